@@ -2,32 +2,114 @@ package main
 
 import (
 	"context"
+	"errors"
 	"flag"
+	"fmt"
+	"io"
 	"log/slog"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 
 	"github.com/arenadata/ad-status-sender/internal/config"
+	"github.com/arenadata/ad-status-sender/internal/rules"
 	"github.com/arenadata/ad-status-sender/internal/runner"
 	sd "github.com/coreos/go-systemd/v22/daemon"
 )
 
+const (
+	defaultConfig = "/etc/ad-status-sender/config.yaml"
+
+	exitOK     = 0
+	exitFailed = 1
+	exitUsage  = 2
+)
+
+const usage = `usage: ad-status-sender [command] [-config path] [flags]
+
+commands:
+  run               run the agent (default)
+  once              scan once, post every status and exit; exits 1 if a post failed
+  check             scan once and print every rule, target, status and component
+                    without posting anything (-dry-run is accepted and changes nothing)
+  validate [-rules path]
+                    check config and rules (rules_path or -rules, and rules_dir) strictly and
+                    exit 1 on problems, each reported as file:line:column: field: message
+  show-config       print every config field with its effective value and source,
+                    secrets redacted
+
+Every config field can be overridden with a flag named like the field
+(-adcm_url, -tls.cert_file) or an AD_STATUS_SENDER_* environment variable
+(AD_STATUS_SENDER_ADCM_URL, AD_STATUS_SENDER_TLS_CERT_FILE).
+Flags win over the environment, the environment over the file.
+`
+
 func main() {
-	var cfgPath string
-	flag.StringVar(&cfgPath, "config",
-		"/etc/ad-status-sender/config.yaml", "path to config")
-	flag.Parse()
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+func run(args []string, stdout, stderr io.Writer) int {
+	cmd := "run"
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		cmd, args = args[0], args[1:]
+	}
+	fs := flag.NewFlagSet(cmd, flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() { fmt.Fprint(fs.Output(), usage) }
+	cfgPath := fs.String("config", defaultConfig, "path to config")
+	fs.Bool("dry-run", false, "check: accepted for compatibility, check never posts")
+	rulesPath := fs.String("rules", "", "validate: rules file to check instead of rules_path")
+	overrides := config.BindFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK
+		}
+		return exitUsage
+	}
+	if fs.NArg() > 0 {
+		fmt.Fprintf(stderr, "unexpected arguments %q\n\n%s", fs.Args(), usage)
+		return exitUsage
+	}
+	// flags that only one command takes
+	owners := map[string]string{"dry-run": "check", "rules": "validate"}
+	misplaced := ""
+	fs.Visit(func(f *flag.Flag) {
+		if owner, ok := owners[f.Name]; ok && owner != cmd {
+			misplaced = fmt.Sprintf("-%s is only accepted by %s", f.Name, owner)
+		}
+	})
+	if misplaced != "" {
+		fmt.Fprintf(stderr, "%s\n\n%s", misplaced, usage)
+		return exitUsage
+	}
+	loader := config.Loader{Path: *cfgPath, Env: os.Environ(), Flags: overrides}
+
+	switch cmd {
+	case "run":
+		return runAgent(loader, stdout)
+	case "once":
+		return runOnce(loader, true, io.Discard, stderr)
+	case "check":
+		return runOnce(loader, false, stdout, stderr)
+	case "validate":
+		return runValidate(loader, *rulesPath, stdout)
+	case "show-config":
+		return showConfig(loader, stdout, stderr)
+	default:
+		fmt.Fprintf(stderr, "unknown command %q\n\n%s", cmd, usage)
+		return exitUsage
+	}
+}
 
-	// pre-load config only to get logging settings
-	cfg, err := config.Load(cfgPath)
+// newLogger builds the logger from the logging settings of the config.
+func newLogger(loader config.Loader, w io.Writer) (*slog.Logger, error) {
+	cfg, _, err := loader.Load()
 	if err != nil {
 		// fallback logger if config can't be read
-		fallback := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{
-			Level: slog.LevelInfo,
-		}))
+		fallback := slog.New(slog.NewTextHandler(w, &slog.HandlerOptions{Level: slog.LevelInfo}))
 		fallback.Error("failed to load config for logger", "err", err)
-		return
+		return nil, err
 	}
 
 	level := config.ParseSlogLevel(cfg.LogLevel)
@@ -35,16 +117,23 @@ func main() {
 	var handler slog.Handler
 	switch cfg.LogFormat {
 	case "json":
-		handler = slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: level})
+		handler = slog.NewJSONHandler(w, &slog.HandlerOptions{Level: level})
 	default:
-		handler = slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: level})
+		handler = slog.NewTextHandler(w, &slog.HandlerOptions{Level: level})
 	}
-	logger := slog.New(handler)
+	return slog.New(handler), nil
+}
 
-	r := runner.NewWithLogger(cfgPath, logger)
+func runAgent(loader config.Loader, logs io.Writer) int {
+	logger, err := newLogger(loader, logs)
+	if err != nil {
+		return exitFailed
+	}
+
+	r := runner.NewWithLoader(loader, logger)
 	if rErr := r.Start(); rErr != nil {
 		logger.Error("start failed", "err", rErr)
-		return
+		return exitFailed
 	}
 	_, _ = sd.SdNotify(false, sd.SdNotifyReady)
 
@@ -53,4 +142,74 @@ func main() {
 
 	<-ctx.Done()
 	r.Stop()
+	return exitOK
+}
+
+// runOnce evaluates the rules once, posts the statuses if post is set and
+// prints the report to out. Logs go to a writer of their own to keep out
+// readable.
+func runOnce(loader config.Loader, post bool, out, logs io.Writer) int {
+	logger, err := newLogger(loader, logs)
+	if err != nil {
+		return exitFailed
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	r := runner.NewWithLoader(loader, logger)
+	var rep runner.Report
+	if post {
+		rep, err = r.RunOnce(ctx)
+	} else {
+		rep, err = r.Evaluate(ctx)
+	}
+	if rep.Targets != nil || rep.Components != nil {
+		printReport(out, rep)
+	}
+	if err != nil {
+		logger.Error("scan failed", "err", err)
+		return exitFailed
+	}
+	return exitOK
+}
+
+// runValidate reports every problem of the config and rules files on out.
+func runValidate(loader config.Loader, rulesPath string, out io.Writer) int {
+	code := exitOK
+	cfg, _, err := loader.Load()
+	if err != nil {
+		fmt.Fprintln(out, err)
+		code = exitFailed
+	} else {
+		fmt.Fprintf(out, "%s: ok\n", loader.Path)
+	}
+	if rulesPath == "" {
+		rulesPath = cfg.RulesPath
+	}
+	if rulesPath == "" && cfg.RulesDir == "" {
+		return code
+	}
+	if _, err = rules.LoadAll(rulesPath, cfg.RulesDir); err != nil {
+		fmt.Fprintln(out, err)
+		return exitFailed
+	}
+	files, _ := rules.Files(rulesPath, cfg.RulesDir)
+	for _, f := range files {
+		fmt.Fprintf(out, "%s: ok\n", f)
+	}
+	return code
+}
+
+// showConfig prints the merged config and where each value came from.
+func showConfig(loader config.Loader, out, errOut io.Writer) int {
+	cfg, src, err := loader.Load()
+	if err != nil {
+		fmt.Fprintln(errOut, err)
+		return exitFailed
+	}
+	if err = config.WriteEffective(out, cfg, src); err != nil {
+		fmt.Fprintln(errOut, err)
+		return exitFailed
+	}
+	return exitOK
 }
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/arenadata/ad-status-sender/internal/runner"
+)
+
+func writeFile(t *testing.T, path, data string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// fixture writes a config and rules file with one TCP rule against a
+// listener of the test and returns the config path and the number of
+// requests ADCM got.
+func fixture(t *testing.T) (string, *atomic.Int32) {
+	t.Helper()
+	var requests atomic.Int32
+	adcm := httptest.NewServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) { requests.Add(1) }))
+	t.Cleanup(adcm.Close)
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = ln.Close() })
+
+	dir := t.TempDir()
+	rulesPath := filepath.Join(dir, "rules.yaml")
+	writeFile(t, rulesPath, fmt.Sprintf("tcp:\n  - name: zk\n    address: %q\n    components: [\"1901\"]\n",
+		ln.Addr().String()))
+	cfgPath := filepath.Join(dir, "config.yaml")
+	writeFile(t, cfgPath, fmt.Sprintf("adcm_url: %q\nhost_id: 7\ntoken: t\nrules_path: %q\n", adcm.URL, rulesPath))
+	return cfgPath, &requests
+}
+
+func TestRun_Usage(t *testing.T) {
+	cfgPath, _ := fixture(t)
+	for _, args := range [][]string{
+		{"bogus"},
+		{"run", "extra"},
+		{"-config", cfgPath, "check"},
+		{"once", "-config", cfgPath, "-dry-run"},
+		{"-dry-run"},
+		{"check", "-rules", "rules.yaml"},
+		{"validate", "-no-such-flag"},
+	} {
+		var stdout, stderr bytes.Buffer
+		if code := run(args, &stdout, &stderr); code != exitUsage {
+			t.Errorf("%q: want exit %d, got %d", args, exitUsage, code)
+		}
+		if stdout.Len() != 0 || !strings.Contains(stderr.String(), "usage:") {
+			t.Errorf("%q: want usage on stderr only, got %q / %q", args, stdout.String(), stderr.String())
+		}
+	}
+	var stdout, stderr bytes.Buffer
+	if code := run([]string{"-h"}, &stdout, &stderr); code != exitOK {
+		t.Errorf("-h: want exit %d, got %d", exitOK, code)
+	}
+}
+
+func TestRun_CheckNeverPosts(t *testing.T) {
+	cfgPath, requests := fixture(t)
+	for _, args := range [][]string{
+		{"check", "-config", cfgPath},
+		{"check", "-config", cfgPath, "-dry-run"},
+	} {
+		var stdout, stderr bytes.Buffer
+		if code := run(args, &stdout, &stderr); code != exitOK {
+			t.Fatalf("%q: want exit %d, got %d: %s", args, exitOK, code, stderr.String())
+		}
+		if !strings.Contains(stdout.String(), "1901") {
+			t.Fatalf("%q: component missing from the report:\n%s", args, stdout.String())
+		}
+	}
+	if n := requests.Load(); n != 0 {
+		t.Fatalf("check contacted ADCM %d times", n)
+	}
+
+	var stdout, stderr bytes.Buffer
+	if code := run([]string{"once", "-config", cfgPath}, &stdout, &stderr); code != exitOK {
+		t.Fatalf("once: want exit %d, got %d: %s", exitOK, code, stderr.String())
+	}
+	if requests.Load() == 0 {
+		t.Fatal("once posted nothing")
+	}
+}
+
+func TestRun_ValidateAndShowConfig(t *testing.T) {
+	cfgPath, _ := fixture(t)
+	var stdout, stderr bytes.Buffer
+	if code := run([]string{"validate", "-config", cfgPath}, &stdout, &stderr); code != exitOK {
+		t.Fatalf("validate: want exit %d, got %d: %s", exitOK, code, stdout.String())
+	}
+	if n := strings.Count(stdout.String(), ": ok\n"); n != 2 {
+		t.Fatalf("validate: want config and rules ok, got:\n%s", stdout.String())
+	}
+
+	stdout.Reset()
+	writeFile(t, filepath.Join(filepath.Dir(cfgPath), "broken.yaml"), "tcp:\n  - address: nowhere\n")
+	args := []string{"validate", "-config", cfgPath, "-rules", filepath.Join(filepath.Dir(cfgPath), "broken.yaml")}
+	if code := run(args, &stdout, &stderr); code != exitFailed {
+		t.Fatalf("validate of a broken rules file: want exit %d, got %d", exitFailed, code)
+	}
+
+	stdout.Reset()
+	if code := run([]string{"show-config", "-config", cfgPath, "-host_id", "9"}, &stdout, &stderr); code != exitOK {
+		t.Fatalf("show-config: want exit %d, got %d: %s", exitOK, code, stderr.String())
+	}
+	for _, pattern := range []string{`(?m)^host_id +9 +flag$`, `(?m)^token +<redacted> +file$`} {
+		if !regexp.MustCompile(pattern).MatchString(stdout.String()) {
+			t.Fatalf("show-config: no line matching %s in:\n%s", pattern, stdout.String())
+		}
+	}
+
+	stderr.Reset()
+	if code := run([]string{"show-config", "-config", "/nonexistent.yaml"}, &stdout, &stderr); code != exitFailed {
+		t.Fatalf("show-config without config: want exit %d, got %d", exitFailed, code)
+	}
+}
+
+func TestPrintReport(t *testing.T) {
+	var out bytes.Buffer
+	printReport(&out, runner.Report{
+		Targets: []runner.TargetStatus{
+			{Kind: "systemd", Rule: "nginx.service", Target: "nginx.service", Status: 1,
+				Components: []string{"501", "502"}},
+			{Kind: "tcp", Rule: "zk", Target: "127.0.0.1:2181", Status: 0, Components: []string{"402"}},
+		},
+		Components: map[string]int{"502": 1, "402": 0, "501": 1},
+	})
+	want := `KIND     RULE           TARGET          STATUS    COMPONENTS
+systemd  nginx.service  nginx.service   1 (down)  501,502
+tcp      zk             127.0.0.1:2181  0 (up)    402
+
+COMPONENT  STATUS
+host       0 (up)
+402        0 (up)
+501        1 (down)
+502        1 (down)
+`
+	if out.String() != want {
+		t.Fatalf("want\n%s\ngot\n%s", want, out.String())
+	}
+}
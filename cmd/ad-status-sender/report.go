@@ -0,0 +1,43 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/arenadata/ad-status-sender/internal/runner"
+)
+
+const tabPadding = 2
+
+// printReport writes one line per check target followed by the statuses
+// that are reported per component.
+func printReport(w io.Writer, rep runner.Report) {
+	tw := tabwriter.NewWriter(w, 0, 0, tabPadding, ' ', 0)
+	fmt.Fprintln(tw, "KIND\tRULE\tTARGET\tSTATUS\tCOMPONENTS")
+	for _, t := range rep.Targets {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n",
+			t.Kind, t.Rule, t.Target, statusText(t.Status), strings.Join(t.Components, ","))
+	}
+	fmt.Fprintln(tw)
+	fmt.Fprintln(tw, "COMPONENT\tSTATUS")
+	fmt.Fprintf(tw, "host\t%s\n", statusText(rep.Host))
+	comps := make([]string, 0, len(rep.Components))
+	for c := range rep.Components {
+		comps = append(comps, c)
+	}
+	slices.Sort(comps)
+	for _, c := range comps {
+		fmt.Fprintf(tw, "%s\t%s\n", c, statusText(rep.Components[c]))
+	}
+	_ = tw.Flush()
+}
+
+func statusText(st int) string {
+	if st == 0 {
+		return "0 (up)"
+	}
+	return fmt.Sprintf("%d (down)", st)
+}
@@ -0,0 +1,232 @@
+package checktest
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/arenadata/ad-status-sender/internal/check"
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/events"
+)
+
+// fakeDaemon is a Docker API whose events stream the test drives.
+type fakeDaemon struct {
+	mu         sync.Mutex
+	containers map[string]types.ContainerJSON // by ID
+	inspects   int
+	msgs       chan events.Message
+	errs       chan error
+}
+
+func newFakeDaemon() *fakeDaemon {
+	return &fakeDaemon{
+		containers: make(map[string]types.ContainerJSON),
+		msgs:       make(chan events.Message),
+		errs:       make(chan error, 1),
+	}
+}
+
+func (f *fakeDaemon) Events(context.Context, types.EventsOptions) (<-chan events.Message, <-chan error) {
+	return f.msgs, f.errs
+}
+
+func (f *fakeDaemon) ContainerList(context.Context, container.ListOptions) ([]types.Container, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var out []types.Container
+	for _, c := range f.containers {
+		state := "exited"
+		if c.State.Running {
+			state = "running"
+		}
+		out = append(out, types.Container{ID: c.ID, Names: []string{c.Name}, Labels: c.Config.Labels, State: state})
+	}
+	return out, nil
+}
+
+func (f *fakeDaemon) ContainerInspect(_ context.Context, ref string) (types.ContainerJSON, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.inspects++
+	for _, c := range f.containers {
+		if c.ID == ref || c.Name == "/"+ref {
+			return c, nil
+		}
+	}
+	return types.ContainerJSON{}, errors.New("no such container: " + ref)
+}
+
+// set adds or replaces a container; health may be empty.
+func (f *fakeDaemon) set(name string, running bool, health string, labels map[string]string) {
+	st := &types.ContainerState{Running: running, StartedAt: time.Now().Format(time.RFC3339Nano)}
+	if health != "" {
+		st.Health = &types.Health{Status: health}
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.containers[name+"-id"] = types.ContainerJSON{
+		ContainerJSONBase: &types.ContainerJSONBase{ID: name + "-id", Name: "/" + name, State: st},
+		Config:            &container.Config{Labels: labels},
+	}
+}
+
+func (f *fakeDaemon) remove(name string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delete(f.containers, name+"-id")
+}
+
+func (f *fakeDaemon) inspected() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.inspects
+}
+
+// emit sends an event about the named container.
+func (f *fakeDaemon) emit(name string, action events.Action) {
+	f.msgs <- events.Message{
+		Type:   events.ContainerEventType,
+		Action: action,
+		Actor:  events.Actor{ID: name + "-id", Attributes: map[string]string{"name": name}},
+	}
+}
+
+// watch runs WatchContainers until the test ends and returns the channels
+// of changed container names and of its result.
+func watch(t *testing.T, chk *check.DockerChecker) (<-chan string, <-chan error) {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	changed := make(chan string, 16)
+	done := make(chan error, 1)
+	go func() {
+		done <- chk.WatchContainers(ctx, func(name string, _ map[string]string) { changed <- name })
+	}()
+	return changed, done
+}
+
+// fromIndex reports whether a check of name is answered without asking
+// the daemon, which only happens while the index is live.
+func fromIndex(chk *check.DockerChecker, f *fakeDaemon, name string) bool {
+	n := f.inspected()
+	chk.AllRunningNames(context.Background(), []string{name}, check.HealthPolicy{})
+	return f.inspected() == n
+}
+
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not met within 1s")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func nextChange(t *testing.T, changed <-chan string) string {
+	t.Helper()
+	select {
+	case name := <-changed:
+		return name
+	case <-time.After(time.Second):
+		t.Fatal("no change reported")
+		return ""
+	}
+}
+
+func TestWatchContainers_KeepsIndex(t *testing.T) {
+	f := newFakeDaemon()
+	f.set("web", true, types.Healthy, map[string]string{"app": "web"})
+	f.set("db", true, "", nil)
+	chk := check.NewDockerCheckerWithClient(f)
+	ctx := context.Background()
+	strict := check.HealthPolicy{RequireHealthy: true}
+
+	changed, _ := watch(t, chk)
+	waitFor(t, func() bool { return fromIndex(chk, f, "web") })
+
+	n := f.inspected()
+	if got := chk.AllRunningNames(ctx, []string{"web", "db"}, strict); got != 0 {
+		t.Fatalf("want web and db running, got %d", got)
+	}
+	if got := chk.AllRunningByLabels(ctx, []string{"app=web"}, strict); got != 0 {
+		t.Fatalf("want app=web running, got %d", got)
+	}
+	if got := chk.AllRunningNames(ctx, []string{"web", "cache"}, check.HealthPolicy{}); got != 1 {
+		t.Fatalf("a container missing from the index must fail, got %d", got)
+	}
+	if f.inspected() != n {
+		t.Fatal("checks asked the daemon while the index is live")
+	}
+
+	// events re-inspect the container they are about
+	f.set("db", false, "", nil)
+	f.emit("db", events.ActionDie)
+	if name := nextChange(t, changed); name != "db" {
+		t.Fatalf("want a change of db, got %s", name)
+	}
+	if got := chk.AllRunningNames(ctx, []string{"db"}, check.HealthPolicy{}); got != 1 {
+		t.Fatalf("want db down after die, got %d", got)
+	}
+
+	f.set("web", true, types.Unhealthy, map[string]string{"app": "web"})
+	f.emit("web", events.Action(string(events.ActionHealthStatus)+": unhealthy"))
+	nextChange(t, changed)
+	if got := chk.AllRunningByLabels(ctx, []string{"app=web"}, strict); got != 1 {
+		t.Fatalf("want app=web unhealthy, got %d", got)
+	}
+
+	// actions that don't change the state are skipped
+	f.emit("web", events.Action("exec_start: sh"))
+	f.remove("db")
+	f.emit("db", events.ActionDestroy)
+	if name := nextChange(t, changed); name != "db" {
+		t.Fatalf("exec_start reported a change of %s", name)
+	}
+	if got := chk.AllRunningNames(ctx, []string{"db"}, check.HealthPolicy{}); got != 1 {
+		t.Fatalf("want a destroyed container gone from the index, got %d", got)
+	}
+}
+
+func TestWatchContainers_FallsBackToPolling(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		err   error
+		wantE string
+	}{
+		{name: "stream error", err: errors.New("unexpected EOF"), wantE: "unexpected EOF"},
+		{name: "stream closed", wantE: "events stream closed"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			f := newFakeDaemon()
+			f.set("web", true, "", nil)
+			chk := check.NewDockerCheckerWithClient(f)
+
+			_, done := watch(t, chk)
+			waitFor(t, func() bool { return fromIndex(chk, f, "web") })
+
+			f.errs <- tc.err
+			select {
+			case err := <-done:
+				if err == nil || !strings.Contains(err.Error(), tc.wantE) {
+					t.Fatalf("want error %q, got %v", tc.wantE, err)
+				}
+			case <-time.After(time.Second):
+				t.Fatal("watch did not stop when the stream broke")
+			}
+
+			if fromIndex(chk, f, "web") {
+				t.Fatal("checks still answered from the index after the stream broke")
+			}
+			if got := chk.AllRunningNames(context.Background(), []string{"web"}, check.HealthPolicy{}); got != 0 {
+				t.Fatalf("want web running by polling, got %d", got)
+			}
+		})
+	}
+}
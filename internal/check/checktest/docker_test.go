@@ -3,6 +3,7 @@ package checktest
 import (
 	"os"
 	"testing"
+	"time"
 
 	"github.com/arenadata/ad-status-sender/internal/check"
 )
@@ -21,6 +22,39 @@ func TestDockerChecker_SkipIfNoDaemon(t *testing.T) {
 		// In CI we avoid actually querying the daemon; the constructor is enough.
 		t.Skip("skip runtime docker queries in CI")
 	}
-	_ = chk.AllRunningNames(t.Context(), []string{"non-existent-container-xyz"})
-	_ = chk.AllRunningByLabels(t.Context(), []string{"this=does-not-exist"})
+	_ = chk.AllRunningNames(t.Context(), []string{"non-existent-container-xyz"}, check.HealthPolicy{})
+	_ = chk.AllRunningByLabels(t.Context(), []string{"this=does-not-exist"}, check.HealthPolicy{RequireHealthy: true})
+}
+
+func TestHealthPolicy_Allows(t *testing.T) {
+	now := time.Unix(1000, 0)
+	recent, old := now.Add(-10*time.Second), now.Add(-5*time.Minute)
+	strict := check.HealthPolicy{RequireHealthy: true}
+	grace := check.HealthPolicy{RequireHealthy: true, AllowStartingFor: time.Minute}
+
+	cases := []struct {
+		name    string
+		hp      check.HealthPolicy
+		running bool
+		health  string
+		started time.Time
+		want    bool
+	}{
+		{"stopped", check.HealthPolicy{}, false, "", old, false},
+		{"legacy ignores health", check.HealthPolicy{}, true, "unhealthy", old, true},
+		{"healthy", strict, true, "healthy", old, true},
+		{"no healthcheck", strict, true, "", old, true},
+		{"none", strict, true, "none", old, true},
+		{"unhealthy", strict, true, "unhealthy", old, false},
+		{"starting without grace", strict, true, "starting", recent, false},
+		{"starting within grace", grace, true, "starting", recent, true},
+		{"starting too long", grace, true, "starting", old, false},
+		{"starting unknown start", grace, true, "starting", time.Time{}, false},
+		{"unhealthy within grace", grace, true, "unhealthy", recent, false},
+	}
+	for _, tc := range cases {
+		if got := tc.hp.Allows(tc.running, tc.health, tc.started, now); got != tc.want {
+			t.Errorf("%s: want %v, got %v", tc.name, tc.want, got)
+		}
+	}
 }
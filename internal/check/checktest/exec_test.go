@@ -0,0 +1,57 @@
+package checktest
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/arenadata/ad-status-sender/internal/check"
+)
+
+func TestExecRunner_ExitCodeOutputEnv(t *testing.T) {
+	res := check.ExecRunner{}.Run(t.Context(), check.ExecSpec{
+		Command: "/bin/sh",
+		Args:    []string{"-c", `echo "$GREETING from $(pwd)"; exit 2`},
+		Env:     []string{"GREETING=hello"},
+		Dir:     "/",
+	})
+	if res.ExitCode != 2 || res.TimedOut || res.Err != nil {
+		t.Fatalf("unexpected result %+v", res)
+	}
+	if res.Output != "hello from /" {
+		t.Fatalf("unexpected output %q", res.Output)
+	}
+
+	res = check.ExecRunner{}.Run(t.Context(), check.ExecSpec{
+		Command:   "/bin/sh",
+		Args:      []string{"-c", "printf 0123456789"},
+		MaxOutput: 4,
+	})
+	if res.ExitCode != 0 || res.Output != "0123..." {
+		t.Fatalf("output not truncated: %+v", res)
+	}
+
+	res = check.ExecRunner{}.Run(t.Context(), check.ExecSpec{Command: "/nonexistent/check"})
+	if res.ExitCode != -1 || res.Err == nil {
+		t.Fatalf("want start failure, got %+v", res)
+	}
+}
+
+func TestExecRunner_TimeoutKillsProcessGroup(t *testing.T) {
+	start := time.Now()
+	res := check.ExecRunner{}.Run(t.Context(), check.ExecSpec{
+		Command: "/bin/sh",
+		// the background sleep keeps stdout open unless the group is killed
+		Args:    []string{"-c", "echo started; sleep 30 & sleep 30"},
+		Timeout: 200 * time.Millisecond,
+	})
+	if !res.TimedOut || res.ExitCode != -1 {
+		t.Fatalf("want timeout, got %+v", res)
+	}
+	if !strings.HasPrefix(res.Output, "started") {
+		t.Fatalf("output before timeout lost: %q", res.Output)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Fatalf("process group not killed, took %v", elapsed)
+	}
+}
@@ -3,39 +3,139 @@ package checktest
 import (
 	"context"
 	"strings"
+	"sync"
+	"time"
+
+	"github.com/arenadata/ad-status-sender/internal/check"
 )
 
+// FakeDocker answers from Names (container -> running) and LabelGroups
+// (joined selectors -> running flags) or LabelMembers (joined selectors ->
+// container names, looked up in Names). Health and Started, keyed by
+// container name, feed the HealthPolicy; containers of LabelGroups have
+// no health state. Now defaults to time.Now. Restarts, also keyed by name,
+// backs ContainerRestarts.
 type FakeDocker struct {
-	Names       map[string]bool
-	LabelGroups map[string][]bool
+	Names        map[string]bool
+	LabelGroups  map[string][]bool
+	LabelMembers map[string][]string
+	Health       map[string]string
+	Started      map[string]time.Time
+	Restarts     map[string]check.Restarts
+	Now          func() time.Time
+
+	mu      sync.Mutex
+	changed func(name string, labels map[string]string)
 }
 
-func (f *FakeDocker) AllRunningNames(_ context.Context, names []string) int {
+func (f *FakeDocker) AllRunningNames(_ context.Context, names []string, hp check.HealthPolicy) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
 	if len(names) == 0 {
 		return 1
 	}
-	for _, n := range names {
-		running, found := f.Names[n]
-		if !found || !running {
-			return 1
-		}
-	}
-	return 0
+	return f.allowNamesLocked(names, hp)
 }
 
-func (f *FakeDocker) AllRunningByLabels(_ context.Context, labels []string) int {
+func (f *FakeDocker) AllRunningByLabels(_ context.Context, labels []string, hp check.HealthPolicy) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
 	if len(labels) == 0 {
 		return 1
 	}
 	key := strings.Join(labels, ",")
+	if members, ok := f.LabelMembers[key]; ok && len(members) > 0 {
+		return f.allowNamesLocked(members, hp)
+	}
 	vals, ok := f.LabelGroups[key]
 	if !ok || len(vals) == 0 {
 		return 1
 	}
 	for _, v := range vals {
-		if !v {
+		if !hp.Allows(v, "", time.Time{}, f.nowLocked()) {
 			return 1
 		}
 	}
 	return 0
 }
+
+func (f *FakeDocker) allowNamesLocked(names []string, hp check.HealthPolicy) int {
+	now := f.nowLocked()
+	for _, n := range names {
+		running, found := f.Names[n]
+		if !found || !hp.Allows(running, f.Health[n], f.Started[n], now) {
+			return 1
+		}
+	}
+	return 0
+}
+
+func (f *FakeDocker) nowLocked() time.Time {
+	if f.Now != nil {
+		return f.Now()
+	}
+	return time.Now()
+}
+
+// ContainerRestarts answers from Restarts for named containers; label
+// selectors match nothing.
+func (f *FakeDocker) ContainerRestarts(_ context.Context, names, _ []string) (map[string]check.Restarts, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	out := make(map[string]check.Restarts, len(names))
+	for _, n := range names {
+		if s, ok := f.Restarts[n]; ok {
+			out[n] = s
+		}
+	}
+	return out, nil
+}
+
+// SetRestarts updates the restart sample of a named container.
+func (f *FakeDocker) SetRestarts(name string, s check.Restarts) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.Restarts == nil {
+		f.Restarts = make(map[string]check.Restarts)
+	}
+	f.Restarts[name] = s
+}
+
+// WatchContainers registers changed as the receiver of Emit and blocks until
+// ctx is done.
+func (f *FakeDocker) WatchContainers(
+	ctx context.Context,
+	changed func(name string, labels map[string]string),
+) error {
+	f.mu.Lock()
+	f.changed = changed
+	f.mu.Unlock()
+	<-ctx.Done()
+	f.mu.Lock()
+	f.changed = nil
+	f.mu.Unlock()
+	return nil
+}
+
+// Watching reports whether a WatchContainers subscriber is registered.
+func (f *FakeDocker) Watching() bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.changed != nil
+}
+
+// Emit sets the running state of a named container and pushes the event to
+// the subscriber.
+func (f *FakeDocker) Emit(name string, labels map[string]string, running bool) {
+	f.mu.Lock()
+	if f.Names == nil {
+		f.Names = make(map[string]bool)
+	}
+	f.Names[name] = running
+	changed := f.changed
+	f.mu.Unlock()
+
+	if changed != nil {
+		changed(name, labels)
+	}
+}
@@ -0,0 +1,71 @@
+package checktest
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/arenadata/ad-status-sender/internal/check"
+)
+
+// FakeExec returns canned results per command and records the highest
+// number of commands running at once. A command listed in Block runs until
+// it receives from its channel.
+type FakeExec struct {
+	Results map[string]check.ExecResult
+	Delay   time.Duration
+	Block   map[string]chan struct{}
+
+	mu      sync.Mutex
+	running int
+	peak    int
+}
+
+func (f *FakeExec) Run(ctx context.Context, spec check.ExecSpec) check.ExecResult {
+	f.mu.Lock()
+	f.running++
+	f.peak = max(f.peak, f.running)
+	f.mu.Unlock()
+	defer func() {
+		f.mu.Lock()
+		f.running--
+		f.mu.Unlock()
+	}()
+
+	if f.Delay > 0 {
+		select {
+		case <-time.After(f.Delay):
+		case <-ctx.Done():
+		}
+	}
+	if block, ok := f.Block[spec.Command]; ok {
+		select {
+		case <-block:
+		case <-ctx.Done():
+		}
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	res, ok := f.Results[spec.Command]
+	if !ok {
+		return check.ExecResult{ExitCode: -1}
+	}
+	return res
+}
+
+// SetResult changes the result of command.
+func (f *FakeExec) SetResult(command string, res check.ExecResult) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.Results == nil {
+		f.Results = make(map[string]check.ExecResult)
+	}
+	f.Results[command] = res
+}
+
+// Peak returns the maximum observed concurrency.
+func (f *FakeExec) Peak() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.peak
+}
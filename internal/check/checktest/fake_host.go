@@ -0,0 +1,30 @@
+package checktest
+
+import (
+	"context"
+	"errors"
+
+	"github.com/arenadata/ad-status-sender/internal/check"
+)
+
+type FakeHost struct {
+	Filesystems map[string]check.FSUsage
+	Pressure    check.Pressure
+	Load        check.LoadAvg
+}
+
+func (f *FakeHost) Filesystem(_ context.Context, path string) (check.FSUsage, error) {
+	u, ok := f.Filesystems[path]
+	if !ok {
+		return check.FSUsage{}, errors.New("no such filesystem: " + path)
+	}
+	return u, nil
+}
+
+func (f *FakeHost) MemoryPressure(_ context.Context) (check.Pressure, error) {
+	return f.Pressure, nil
+}
+
+func (f *FakeHost) LoadAvg(_ context.Context) (check.LoadAvg, error) {
+	return f.Load, nil
+}
@@ -0,0 +1,57 @@
+package checktest
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// FakeProc is one process in a fake procfs tree.
+type FakeProc struct {
+	PID   int
+	Comm  string
+	Argv  []string
+	UID   int
+	State string // "S" if empty
+}
+
+// WriteProcfs lays out <root>/<pid>/{status,cmdline} for procs, plus a few
+// non-process entries that scanners must skip.
+func WriteProcfs(t *testing.T, root string, procs ...FakeProc) {
+	t.Helper()
+	for _, p := range procs {
+		dir := filepath.Join(root, strconv.Itoa(p.PID))
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			t.Fatal(err)
+		}
+		state := p.State
+		if state == "" {
+			state = "S"
+		}
+		uid := strconv.Itoa(p.UID)
+		status := "Name:\t" + p.Comm + "\n" +
+			"State:\t" + state + " (fake)\n" +
+			"Pid:\t" + strconv.Itoa(p.PID) + "\n" +
+			"Uid:\t" + uid + "\t" + uid + "\t" + uid + "\t" + uid + "\n"
+		if err := os.WriteFile(filepath.Join(dir, "status"), []byte(status), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		cmdline := ""
+		if len(p.Argv) > 0 {
+			cmdline = strings.Join(p.Argv, "\x00") + "\x00"
+		}
+		if err := os.WriteFile(filepath.Join(dir, "cmdline"), []byte(cmdline), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, extra := range []string{"sys", "self"} {
+		if err := os.MkdirAll(filepath.Join(root, extra), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(root, "loadavg"), []byte("0.00 0.00 0.00 1/1 1\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -1,13 +1,25 @@
 package checktest
 
-import "context"
+import (
+	"context"
+	"sync"
+
+	"github.com/arenadata/ad-status-sender/internal/check"
+)
 
 type FakeSystemd struct {
-	Units map[string]bool
-	Globs map[string][]string
+	Units    map[string]bool
+	Globs    map[string][]string
+	Restarts map[string]check.Restarts
+
+	mu       sync.Mutex
+	changed  func(unit string, status int)
+	expanded int
 }
 
 func (f *FakeSystemd) SystemdStatus(_ context.Context, unit string) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
 	if ok := f.Units[unit]; ok {
 		if ok {
 			return 0
@@ -16,6 +28,85 @@ func (f *FakeSystemd) SystemdStatus(_ context.Context, unit string) int {
 	}
 	return 1
 }
+
+// UnitRestarts answers from Restarts; units missing there have none.
+func (f *FakeSystemd) UnitRestarts(_ context.Context, unit string) (check.Restarts, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.Restarts[unit], nil
+}
+
+// SetRestarts updates the restart sample of unit.
+func (f *FakeSystemd) SetRestarts(unit string, s check.Restarts) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.Restarts == nil {
+		f.Restarts = make(map[string]check.Restarts)
+	}
+	f.Restarts[unit] = s
+}
+
 func (f *FakeSystemd) ExpandUnitsByGlob(_ context.Context, glob string) []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.expanded++
 	return append([]string(nil), f.Globs[glob]...)
 }
+
+// Expansions reports how many times ExpandUnitsByGlob was called.
+func (f *FakeSystemd) Expansions() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.expanded
+}
+
+// Set changes the unit state without telling the subscriber, like a
+// transition the watch missed.
+func (f *FakeSystemd) Set(unit string, active bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.Units == nil {
+		f.Units = make(map[string]bool)
+	}
+	f.Units[unit] = active
+}
+
+// WatchUnits registers changed as the receiver of Emit and blocks until ctx
+// is done.
+func (f *FakeSystemd) WatchUnits(ctx context.Context, changed func(unit string, status int)) error {
+	f.mu.Lock()
+	f.changed = changed
+	f.mu.Unlock()
+	<-ctx.Done()
+	f.mu.Lock()
+	f.changed = nil
+	f.mu.Unlock()
+	return nil
+}
+
+// Watching reports whether a WatchUnits subscriber is registered.
+func (f *FakeSystemd) Watching() bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.changed != nil
+}
+
+// Emit sets the unit state and pushes the transition to the subscriber.
+func (f *FakeSystemd) Emit(unit string, active bool) {
+	f.mu.Lock()
+	if f.Units == nil {
+		f.Units = make(map[string]bool)
+	}
+	f.Units[unit] = active
+	changed := f.changed
+	f.mu.Unlock()
+
+	if changed == nil {
+		return
+	}
+	st := 1
+	if active {
+		st = 0
+	}
+	changed(unit, st)
+}
@@ -0,0 +1,71 @@
+package checktest
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/arenadata/ad-status-sender/internal/check"
+	"golang.org/x/sys/unix"
+)
+
+func TestHostChecker_FakeProcfs(t *testing.T) {
+	root := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(root, "pressure"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	psi := "some avg10=12.50 avg60=3.00 avg300=1.00 total=100\n" +
+		"full avg10=4.25 avg60=1.50 avg300=0.50 total=50\n"
+	if err := os.WriteFile(filepath.Join(root, "pressure", "memory"), []byte(psi), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "loadavg"), []byte("1.50 0.75 0.25 2/345 6789\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	h := &check.HostChecker{ProcRoot: root}
+	p, err := h.MemoryPressure(t.Context())
+	if err != nil || p.SomeAvg10 != 12.5 || p.SomeAvg60 != 3 || p.FullAvg10 != 4.25 || p.FullAvg60 != 1.5 {
+		t.Fatalf("bad pressure %+v err=%v", p, err)
+	}
+	l, err := h.LoadAvg(t.Context())
+	if err != nil || l.Load1 != 1.5 || l.Load5 != 0.75 || l.Load15 != 0.25 || l.CPUs < 1 {
+		t.Fatalf("bad loadavg %+v err=%v", l, err)
+	}
+
+	u, err := h.Filesystem(t.Context(), root)
+	if err != nil || u.UsedPercent < 0 || u.UsedPercent > 100 {
+		t.Fatalf("bad fs usage %+v err=%v", u, err)
+	}
+	if _, err := h.Filesystem(t.Context(), filepath.Join(root, "missing")); err == nil {
+		t.Fatalf("expected statfs error for missing path")
+	}
+}
+
+func TestHostChecker_FilesystemHangs(t *testing.T) {
+	hung := make(chan struct{})
+	defer close(hung)
+	h := &check.HostChecker{Statfs: func(string, *unix.Statfs_t) error {
+		<-hung
+		return nil
+	}}
+	ctx, cancel := context.WithTimeout(t.Context(), 50*time.Millisecond)
+	defer cancel()
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := h.Filesystem(ctx, "/mnt/nfs")
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("want a deadline error, got %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("a hung statfs held Filesystem past its ctx")
+	}
+}
@@ -0,0 +1,88 @@
+package checktest
+
+import (
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+
+	"github.com/arenadata/ad-status-sender/internal/check"
+	"github.com/arenadata/ad-status-sender/internal/config"
+)
+
+func TestHTTPChecker_Assertions(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/health":
+			if r.Header.Get("X-Probe") != "ad" {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			_, _ = w.Write([]byte(`{"status":"UP","beans":[{"State":"ACTIVE","Live":3}]}`))
+		case "/login":
+			w.WriteHeader(http.StatusUnauthorized)
+		default:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer srv.Close()
+
+	hdr := http.Header{"X-Probe": {"ad"}}
+	health := func(path, value string) check.HTTPSpec {
+		return check.HTTPSpec{URL: srv.URL + "/health", Header: hdr, JSONPath: path, JSONValue: value}
+	}
+	cases := []struct {
+		name string
+		spec check.HTTPSpec
+		ok   bool
+	}{
+		{"2xx", check.HTTPSpec{URL: srv.URL + "/health", Header: hdr}, true},
+		{"missing header", check.HTTPSpec{URL: srv.URL + "/health"}, false},
+		{"head", check.HTTPSpec{Method: http.MethodHead, URL: srv.URL + "/health", Header: hdr}, true},
+		{"5xx", check.HTTPSpec{URL: srv.URL + "/down"}, false},
+		{"expected 401", check.HTTPSpec{URL: srv.URL + "/login", ExpectStatus: []int{200, 401}}, true},
+		{"body regex", check.HTTPSpec{URL: srv.URL + "/health", Header: hdr, Body: regexp.MustCompile(`"UP"`)},
+			true},
+		{"body mismatch", check.HTTPSpec{URL: srv.URL + "/health", Header: hdr, Body: regexp.MustCompile(`DOWN`)},
+			false},
+		{"json value", health("$.beans[0].State", "ACTIVE"), true},
+		{"json number", health("beans.0.Live", "3"), true},
+		{"json exists", health("status", ""), true},
+		{"json missing", health("$.beans[1].State", ""), false},
+		{"json wrong", health("status", "DOWN"), false},
+		{"head with body check", check.HTTPSpec{Method: http.MethodHead, URL: srv.URL + "/health", JSONPath: "status"},
+			false},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if err := (check.HTTPChecker{}).CheckHTTP(t.Context(), tc.spec); (err == nil) != tc.ok {
+				t.Fatalf("want ok=%v, got %v", tc.ok, err)
+			}
+		})
+	}
+}
+
+func TestHTTPChecker_TLSFromConfig(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	spec := check.HTTPSpec{URL: srv.URL, TLS: config.TLS{}.ClientConfig()}
+	if err := (check.HTTPChecker{}).CheckHTTP(t.Context(), spec); err == nil {
+		t.Fatalf("self-signed server accepted without its CA")
+	}
+
+	ca := filepath.Join(t.TempDir(), "ca.pem")
+	block := &pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw}
+	if err := os.WriteFile(ca, pem.EncodeToMemory(block), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	spec.TLS = config.TLS{CAFile: ca}.ClientConfig()
+	if err := (check.HTTPChecker{}).CheckHTTP(t.Context(), spec); err != nil {
+		t.Fatalf("ca_file not applied: %v", err)
+	}
+}
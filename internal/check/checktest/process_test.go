@@ -0,0 +1,59 @@
+package checktest
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+
+	"github.com/arenadata/ad-status-sender/internal/check"
+)
+
+func TestProcessScanner_FakeProcfs(t *testing.T) {
+	root := t.TempDir()
+	WriteProcfs(t, root,
+		FakeProc{PID: 100, Comm: "java", Argv: []string{"/usr/bin/java", "-Dproc_namenode", "Main"}, UID: 1001},
+		FakeProc{PID: 101, Comm: "java", Argv: []string{"/usr/bin/java", "-Dproc_datanode", "Main"}, UID: 1002},
+		FakeProc{PID: 102, Comm: "legacy-collecto", Argv: []string{"/opt/app/legacy-collector", "--daemon"}},
+		FakeProc{PID: 103, Comm: "java", Argv: []string{"/usr/bin/java", "-Dproc_datanode"}, UID: 1002, State: "Z"},
+		FakeProc{PID: 2, Comm: "kthreadd"},
+	)
+	pidfile := filepath.Join(t.TempDir(), "collector.pid")
+	if err := os.WriteFile(pidfile, []byte("102\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	p := &check.ProcessScanner{ProcRoot: root}
+	cases := []struct {
+		name string
+		spec check.ProcessSpec
+		want int
+	}{
+		{"comm", check.ProcessSpec{Name: "java"}, 2},
+		{"argv0 beyond comm limit", check.ProcessSpec{Name: "legacy-collector"}, 1},
+		{"cmdline", check.ProcessSpec{Cmdline: regexp.MustCompile(`-Dproc_datanode\b`)}, 1},
+		{"uid", check.ProcessSpec{Name: "java", User: "1001"}, 1},
+		{"root user by name", check.ProcessSpec{User: "root"}, 2},
+		{"pidfile", check.ProcessSpec{PidFile: pidfile}, 1},
+		{"pidfile and name", check.ProcessSpec{PidFile: pidfile, Name: "java"}, 0},
+		{"missing pidfile", check.ProcessSpec{PidFile: pidfile + ".gone"}, 0},
+		{"kernel thread", check.ProcessSpec{Name: "kthreadd"}, 1},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			n, err := p.CountProcesses(t.Context(), tc.spec)
+			if err != nil || n != tc.want {
+				t.Fatalf("want %d, got %d (err=%v)", tc.want, n, err)
+			}
+		})
+	}
+
+	if _, err := p.CountProcesses(t.Context(), check.ProcessSpec{User: "no-such-user-here"}); err == nil {
+		t.Fatalf("expected error for unknown user")
+	}
+	stale := filepath.Join(t.TempDir(), "bad.pid")
+	_ = os.WriteFile(stale, []byte("not-a-pid"), 0o644)
+	if _, err := p.CountProcesses(t.Context(), check.ProcessSpec{PidFile: stale}); err == nil {
+		t.Fatalf("expected error for malformed pidfile")
+	}
+}
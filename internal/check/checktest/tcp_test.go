@@ -0,0 +1,112 @@
+package checktest
+
+import (
+	"context"
+	"net"
+	"path/filepath"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/arenadata/ad-status-sender/internal/check"
+)
+
+// serve answers every connection on ln with reply after reading one line.
+func serve(t *testing.T, ln net.Listener, reply string) {
+	t.Helper()
+	t.Cleanup(func() { _ = ln.Close() })
+	go func() {
+		for {
+			c, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer c.Close()
+				_ = c.SetDeadline(time.Now().Add(time.Second))
+				buf := make([]byte, 64)
+				_, _ = c.Read(buf)
+				_, _ = c.Write([]byte(reply))
+			}()
+		}
+	}()
+}
+
+func TestTCPProber_Probe(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	serve(t, ln, "imok")
+	addr := ln.Addr().String()
+	p := check.TCPProber{}
+	ctx := context.Background()
+
+	ruok := func(prefix string, re *regexp.Regexp) check.ProbeSpec {
+		spec := check.ProbeSpec{Network: "tcp", Address: addr, Send: []byte("ruok\n"), Expect: re}
+		if prefix != "" {
+			spec.ExpectPrefix = []byte(prefix)
+		}
+		return spec
+	}
+	cases := []struct {
+		name string
+		spec check.ProbeSpec
+		ok   bool
+	}{
+		{"connect", check.ProbeSpec{Network: "tcp", Address: addr}, true},
+		{"prefix", ruok("imok", nil), true},
+		{"regex", ruok("", regexp.MustCompile(`^im(ok|ro)$`)), true},
+		{"wrong prefix", ruok("nope", nil), false},
+		{"regex mismatch", ruok("", regexp.MustCompile(`^x`)), false},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if err := p.Probe(ctx, tc.spec); (err == nil) != tc.ok {
+				t.Fatalf("want ok=%v, got %v", tc.ok, err)
+			}
+		})
+	}
+
+	_ = ln.Close()
+	closed := check.ProbeSpec{Network: "tcp", Address: addr, Timeout: 200 * time.Millisecond}
+	if err := p.Probe(ctx, closed); err == nil {
+		t.Fatalf("closed port reported healthy")
+	}
+}
+
+func TestTCPProber_UnixSocketAndTimeout(t *testing.T) {
+	sock := filepath.Join(t.TempDir(), "app.sock")
+	ln, err := net.Listen("unix", sock)
+	if err != nil {
+		t.Fatal(err)
+	}
+	serve(t, ln, "PONG")
+	p := check.TCPProber{}
+	ctx := context.Background()
+
+	ping := check.ProbeSpec{Network: "unix", Address: sock, Send: []byte("PING\n"), ExpectPrefix: []byte("PONG")}
+	if err := p.Probe(ctx, ping); err != nil {
+		t.Fatalf("unix probe: %v", err)
+	}
+
+	// a listener that never answers must fail within the timeout
+	silent, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = silent.Close() })
+	start := time.Now()
+	err = p.Probe(ctx, check.ProbeSpec{
+		Network:      "tcp",
+		Address:      silent.Addr().String(),
+		Timeout:      100 * time.Millisecond,
+		ExpectPrefix: []byte("hello"),
+	})
+	if err == nil {
+		t.Fatalf("silent server reported healthy")
+	}
+	if d := time.Since(start); d > time.Second {
+		t.Fatalf("probe ignored timeout: %v", d)
+	}
+}
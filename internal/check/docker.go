@@ -2,13 +2,55 @@ package check
 
 import (
 	"context"
+	"time"
 
+	"github.com/docker/docker/api/types"
 	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/events"
 	"github.com/docker/docker/api/types/filters"
 	"github.com/docker/docker/client"
 )
 
-type DockerChecker struct{ cli *client.Client }
+// HealthPolicy decides how a container's HEALTHCHECK state is counted. The
+// zero value only requires the container to be running.
+type HealthPolicy struct {
+	// RequireHealthy fails running containers whose health is "unhealthy" or
+	// "starting". Containers without a HEALTHCHECK only need to run.
+	RequireHealthy bool
+	// AllowStartingFor tolerates "starting" for this long after the
+	// container started.
+	AllowStartingFor time.Duration
+}
+
+// Allows reports whether a container in the given state counts as OK.
+func (p HealthPolicy) Allows(running bool, health string, startedAt, now time.Time) bool {
+	if !running {
+		return false
+	}
+	if !p.RequireHealthy {
+		return true
+	}
+	switch health {
+	case types.Unhealthy:
+		return false
+	case types.Starting:
+		return p.AllowStartingFor > 0 && !startedAt.IsZero() && now.Sub(startedAt) < p.AllowStartingFor
+	default:
+		return true
+	}
+}
+
+type DockerChecker struct {
+	cli   DockerClient
+	index containerIndex
+}
+
+// DockerClient is the part of the Docker API client the checker uses.
+type DockerClient interface {
+	Events(ctx context.Context, options types.EventsOptions) (<-chan events.Message, <-chan error)
+	ContainerList(ctx context.Context, options container.ListOptions) ([]types.Container, error)
+	ContainerInspect(ctx context.Context, containerID string) (types.ContainerJSON, error)
+}
 
 func NewDockerChecker() (*DockerChecker, error) {
 	cli, err := client.NewClientWithOpts(
@@ -18,29 +60,47 @@ func NewDockerChecker() (*DockerChecker, error) {
 	if err != nil {
 		return nil, err
 	}
-	return &DockerChecker{cli: cli}, nil
+	return NewDockerCheckerWithClient(cli), nil
+}
+
+// NewDockerCheckerWithClient returns a checker talking to the daemon through cli.
+func NewDockerCheckerWithClient(cli DockerClient) *DockerChecker {
+	return &DockerChecker{cli: cli}
 }
 
 func (d *DockerChecker) AllRunningNames(
 	ctx context.Context,
 	names []string,
+	hp HealthPolicy,
 ) int {
+	if found, live := d.index.byName(names); live {
+		if len(found) != len(names) {
+			return 1
+		}
+		return allRunning(found, hp)
+	}
+	found := make([]containerState, 0, len(names))
 	for _, n := range names {
-		inspect, err := d.cli.ContainerInspect(ctx, n)
-		if err != nil || inspect.State == nil || !inspect.State.Running {
+		st, ok := d.inspect(ctx, n)
+		if !ok {
 			return 1
 		}
+		found = append(found, st)
 	}
-	return 0
+	return allRunning(found, hp)
 }
 
 func (d *DockerChecker) AllRunningByLabels(
 	ctx context.Context,
 	labels []string,
+	hp HealthPolicy,
 ) int {
 	if len(labels) == 0 {
 		return 1
 	}
+	if found, live := d.index.byLabels(labels); live {
+		return allRunning(found, hp)
+	}
 	f := filters.NewArgs()
 	for _, kv := range labels {
 		if kv == "" {
@@ -55,8 +115,73 @@ func (d *DockerChecker) AllRunningByLabels(
 	if err != nil || len(list) == 0 {
 		return 1
 	}
+	found := make([]containerState, 0, len(list))
+	for _, c := range list {
+		st := summaryState(c)
+		if hp.RequireHealthy && st.running {
+			// the list only carries a human-readable status; inspect for
+			// the health state and start time
+			var ok bool
+			if st, ok = d.inspect(ctx, c.ID); !ok {
+				return 1
+			}
+		}
+		found = append(found, st)
+	}
+	return allRunning(found, hp)
+}
+
+// ContainerRestarts inspects the selected containers for their RestartCount
+// and start time. It always asks the daemon: the event index doesn't track
+// restart counters.
+func (d *DockerChecker) ContainerRestarts(
+	ctx context.Context,
+	names, labels []string,
+) (map[string]Restarts, error) {
+	refs := names
+	if len(refs) == 0 {
+		f := filters.NewArgs()
+		for _, kv := range labels {
+			if kv != "" {
+				f.Add("label", kv)
+			}
+		}
+		list, err := d.cli.ContainerList(ctx, container.ListOptions{All: true, Filters: f})
+		if err != nil {
+			return nil, err
+		}
+		for _, c := range list {
+			refs = append(refs, c.ID)
+		}
+	}
+	out := make(map[string]Restarts, len(refs))
+	for _, ref := range refs {
+		resp, err := d.cli.ContainerInspect(ctx, ref)
+		if err != nil || resp.ContainerJSONBase == nil {
+			continue
+		}
+		st := inspectState(resp)
+		out[st.name] = Restarts{Count: resp.RestartCount, StartedAt: st.startedAt}
+	}
+	return out, nil
+}
+
+// inspect reads the current state of one container.
+func (d *DockerChecker) inspect(ctx context.Context, ref string) (containerState, bool) {
+	resp, err := d.cli.ContainerInspect(ctx, ref)
+	if err != nil || resp.ContainerJSONBase == nil {
+		return containerState{}, false
+	}
+	return inspectState(resp), true
+}
+
+func allRunning(list []containerState, hp HealthPolicy) int {
+	if len(list) == 0 {
+		return 1
+	}
+	now := time.Now()
 	for _, c := range list {
-		if c.State != "running" {
+		if !hp.Allows(c.running, c.health, c.startedAt, now) {
 			return 1
 		}
 	}
@@ -0,0 +1,208 @@
+package check
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/events"
+	"github.com/docker/docker/api/types/filters"
+)
+
+// containerState is what the event index keeps per container.
+type containerState struct {
+	id        string
+	name      string
+	labels    map[string]string
+	running   bool
+	health    string
+	startedAt time.Time
+}
+
+// containerIndex mirrors container states from the Docker events stream.
+// It is only consulted while live; otherwise the checker polls the daemon.
+type containerIndex struct {
+	mu   sync.RWMutex
+	live bool
+	byID map[string]containerState
+}
+
+func (x *containerIndex) reset(list []containerState, live bool) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	x.live = live
+	x.byID = make(map[string]containerState, len(list))
+	for _, c := range list {
+		x.byID[c.id] = c
+	}
+}
+
+func (x *containerIndex) put(c containerState) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	x.byID[c.id] = c
+}
+
+func (x *containerIndex) remove(id string) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	delete(x.byID, id)
+}
+
+// byName returns the containers for names; ok is false if the index is not live.
+func (x *containerIndex) byName(names []string) ([]containerState, bool) {
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	if !x.live {
+		return nil, false
+	}
+	out := make([]containerState, 0, len(names))
+	for _, n := range names {
+		for _, c := range x.byID {
+			if c.name == n || c.id == n {
+				out = append(out, c)
+				break
+			}
+		}
+	}
+	return out, true
+}
+
+// byLabels returns containers carrying every "k=v" (or bare "k") selector.
+func (x *containerIndex) byLabels(selectors []string) ([]containerState, bool) {
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	if !x.live {
+		return nil, false
+	}
+	var out []containerState
+	for _, c := range x.byID {
+		if MatchLabels(c.labels, selectors) {
+			out = append(out, c)
+		}
+	}
+	return out, true
+}
+
+// MatchLabels reports whether labels satisfy every "k=v" or "k" selector.
+func MatchLabels(labels map[string]string, selectors []string) bool {
+	for _, sel := range selectors {
+		if sel == "" {
+			continue
+		}
+		k, v, hasValue := strings.Cut(sel, "=")
+		got, ok := labels[k]
+		if !ok || (hasValue && got != v) {
+			return false
+		}
+	}
+	return true
+}
+
+// WatchContainers consumes the Docker events stream and keeps the container
+// index used by AllRunningNames and AllRunningByLabels up to date. It returns
+// when ctx is done or the stream breaks (e.g. dockerd restarted); the index
+// is then dropped so checks fall back to polling.
+func (d *DockerChecker) WatchContainers(
+	ctx context.Context,
+	changed func(name string, labels map[string]string),
+) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	f := filters.NewArgs(filters.Arg("type", string(events.ContainerEventType)))
+	msgs, errs := d.cli.Events(ctx, types.EventsOptions{Filters: f})
+
+	list, err := d.cli.ContainerList(ctx, container.ListOptions{All: true})
+	if err != nil {
+		return err
+	}
+	states := make([]containerState, 0, len(list))
+	for _, c := range list {
+		// inspect for the health state, which the list doesn't carry
+		st, ok := d.inspect(ctx, c.ID)
+		if !ok {
+			st = summaryState(c)
+		}
+		states = append(states, st)
+	}
+	d.index.reset(states, true)
+	defer d.index.reset(nil, false)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case err := <-errs:
+			if err == nil {
+				err = errors.New("docker: events stream closed")
+			}
+			return err
+		case m := <-msgs:
+			if !watchedAction(m.Action) {
+				continue
+			}
+			if st, ok := d.refresh(ctx, m.Actor.ID); ok {
+				changed(st.name, st.labels)
+				continue
+			}
+			changed(strings.TrimPrefix(m.Actor.Attributes["name"], "/"), m.Actor.Attributes)
+		}
+	}
+}
+
+// refresh re-inspects one container and stores the result in the index.
+func (d *DockerChecker) refresh(ctx context.Context, id string) (containerState, bool) {
+	st, ok := d.inspect(ctx, id)
+	if !ok {
+		d.index.remove(id)
+		return containerState{}, false
+	}
+	d.index.put(st)
+	return st, true
+}
+
+func inspectState(resp types.ContainerJSON) containerState {
+	st := containerState{id: resp.ID, name: strings.TrimPrefix(resp.Name, "/")}
+	if resp.State != nil {
+		st.running = resp.State.Running
+		if resp.State.Health != nil {
+			st.health = resp.State.Health.Status
+		}
+		st.startedAt, _ = time.Parse(time.RFC3339Nano, resp.State.StartedAt)
+	}
+	if resp.Config != nil {
+		st.labels = resp.Config.Labels
+	}
+	return st
+}
+
+func summaryState(c types.Container) containerState {
+	st := containerState{id: c.ID, labels: c.Labels, running: c.State == "running"}
+	if len(c.Names) > 0 {
+		st.name = strings.TrimPrefix(c.Names[0], "/")
+	}
+	return st
+}
+
+func watchedAction(a events.Action) bool {
+	switch {
+	case a == events.ActionStart,
+		a == events.ActionRestart,
+		a == events.ActionStop,
+		a == events.ActionDie,
+		a == events.ActionOOM,
+		a == events.ActionPause,
+		a == events.ActionUnPause,
+		a == events.ActionRename,
+		a == events.ActionDestroy,
+		strings.HasPrefix(string(a), string(events.ActionHealthStatus)):
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,113 @@
+package check
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"syscall"
+	"time"
+)
+
+const (
+	DefaultExecTimeout = 10 * time.Second
+	DefaultExecOutput  = 4096
+
+	// execWaitDelay bounds how long Wait keeps reading pipes inherited by
+	// grandchildren after the process group was killed.
+	execWaitDelay = time.Second
+)
+
+// ExecSpec describes one command run by an exec check.
+type ExecSpec struct {
+	Command   string
+	Args      []string
+	Env       []string // "K=V", appended to the agent's environment
+	Dir       string
+	Timeout   time.Duration
+	MaxOutput int
+}
+
+// ExecResult is the outcome of a command. ExitCode is -1 if the command could
+// not be started or was killed.
+type ExecResult struct {
+	ExitCode int
+	Output   string
+	TimedOut bool
+	Err      error
+}
+
+// ExecRunner runs commands in their own process group and kills the whole
+// group on timeout, so forked helpers don't outlive the check.
+type ExecRunner struct{}
+
+func (ExecRunner) Run(ctx context.Context, spec ExecSpec) ExecResult {
+	timeout := spec.Timeout
+	if timeout <= 0 {
+		timeout = DefaultExecTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, spec.Command, spec.Args...) //nolint:gosec // commands come from rules.yaml
+	cmd.Dir = spec.Dir
+	cmd.Env = append(os.Environ(), spec.Env...)
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Cancel = func() error {
+		return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	}
+	cmd.WaitDelay = execWaitDelay
+
+	maxOut := spec.MaxOutput
+	if maxOut <= 0 {
+		maxOut = DefaultExecOutput
+	}
+	out := &limitedBuffer{max: maxOut}
+	cmd.Stdout = out
+
+	err := cmd.Run()
+	res := ExecResult{ExitCode: -1, Output: out.String(), Err: err}
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		res.TimedOut = true
+		return res
+	}
+	var exitErr *exec.ExitError
+	switch {
+	case err == nil:
+		res.ExitCode = 0
+	case errors.As(err, &exitErr) && exitErr.Exited():
+		res.ExitCode = exitErr.ExitCode()
+		res.Err = nil
+	}
+	return res
+}
+
+// limitedBuffer keeps the first max bytes written and discards the rest.
+type limitedBuffer struct {
+	buf       bytes.Buffer
+	max       int
+	truncated bool
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if room := b.max - b.buf.Len(); room > 0 {
+		if len(p) > room {
+			b.buf.Write(p[:room])
+			b.truncated = true
+		} else {
+			b.buf.Write(p)
+		}
+	} else if len(p) > 0 {
+		b.truncated = true
+	}
+	return len(p), nil
+}
+
+func (b *limitedBuffer) String() string {
+	s := string(bytes.TrimSpace(b.buf.Bytes()))
+	if b.truncated {
+		s += "..."
+	}
+	return s
+}
@@ -0,0 +1,157 @@
+package check
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+const (
+	DefaultStatfsTimeout = 5 * time.Second
+	defaultProcRoot      = "/proc"
+	percent              = 100
+	loadFields           = 3
+)
+
+// FSUsage describes one mounted filesystem, in df(1) terms.
+type FSUsage struct {
+	UsedPercent       float64
+	InodesUsedPercent float64
+	ReadOnly          bool
+}
+
+// Pressure holds the memory PSI averages (percent of time stalled).
+type Pressure struct {
+	SomeAvg10 float64
+	SomeAvg60 float64
+	FullAvg10 float64
+	FullAvg60 float64
+}
+
+type LoadAvg struct {
+	Load1  float64
+	Load5  float64
+	Load15 float64
+	CPUs   int
+}
+
+// HostChecker reads host-level facts from statfs(2) and procfs. ProcRoot can
+// point at a fake procfs tree and Statfs can replace unix.Statfs in tests.
+type HostChecker struct {
+	ProcRoot string
+	Statfs   func(path string, st *unix.Statfs_t) error
+}
+
+func NewHostChecker() *HostChecker {
+	return &HostChecker{ProcRoot: defaultProcRoot}
+}
+
+// Filesystem gives up after DefaultStatfsTimeout or when ctx is done: statfs
+// on a hung NFS mount never returns. The call is left behind in that case.
+func (h *HostChecker) Filesystem(ctx context.Context, path string) (FSUsage, error) {
+	ctx, cancel := context.WithTimeout(ctx, DefaultStatfsTimeout)
+	defer cancel()
+	statfs := h.Statfs
+	if statfs == nil {
+		statfs = unix.Statfs
+	}
+	type result struct {
+		st  unix.Statfs_t
+		err error
+	}
+	done := make(chan result, 1)
+	go func() {
+		var res result
+		res.err = statfs(path, &res.st)
+		done <- res
+	}()
+	var st unix.Statfs_t
+	select {
+	case res := <-done:
+		if res.err != nil {
+			return FSUsage{}, res.err
+		}
+		st = res.st
+	case <-ctx.Done():
+		return FSUsage{}, ctx.Err()
+	}
+	var u FSUsage
+	used := st.Blocks - st.Bfree
+	if total := used + st.Bavail; total > 0 {
+		u.UsedPercent = float64(used) * percent / float64(total)
+	}
+	if st.Files > 0 {
+		u.InodesUsedPercent = float64(st.Files-st.Ffree) * percent / float64(st.Files)
+	}
+	u.ReadOnly = st.Flags&unix.ST_RDONLY != 0
+	return u, nil
+}
+
+// MemoryPressure parses <proc>/pressure/memory (Linux 4.20+ with PSI).
+func (h *HostChecker) MemoryPressure(_ context.Context) (Pressure, error) {
+	f, err := os.Open(filepath.Join(h.procRoot(), "pressure", "memory"))
+	if err != nil {
+		return Pressure{}, err
+	}
+	defer f.Close()
+
+	var p Pressure
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		fields := strings.Fields(sc.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		avg10, avg60 := psiField(fields, "avg10="), psiField(fields, "avg60=")
+		switch fields[0] {
+		case "some":
+			p.SomeAvg10, p.SomeAvg60 = avg10, avg60
+		case "full":
+			p.FullAvg10, p.FullAvg60 = avg10, avg60
+		}
+	}
+	return p, sc.Err()
+}
+
+func (h *HostChecker) LoadAvg(_ context.Context) (LoadAvg, error) {
+	b, err := os.ReadFile(filepath.Join(h.procRoot(), "loadavg"))
+	if err != nil {
+		return LoadAvg{}, err
+	}
+	fields := strings.Fields(string(b))
+	if len(fields) < loadFields {
+		return LoadAvg{}, fmt.Errorf("loadavg: unexpected content %q", string(b))
+	}
+	var vals [loadFields]float64
+	for i := range vals {
+		if vals[i], err = strconv.ParseFloat(fields[i], 64); err != nil {
+			return LoadAvg{}, fmt.Errorf("loadavg: %w", err)
+		}
+	}
+	return LoadAvg{Load1: vals[0], Load5: vals[1], Load15: vals[2], CPUs: runtime.NumCPU()}, nil
+}
+
+func (h *HostChecker) procRoot() string {
+	if h.ProcRoot == "" {
+		return defaultProcRoot
+	}
+	return h.ProcRoot
+}
+
+func psiField(fields []string, prefix string) float64 {
+	for _, f := range fields {
+		if v, ok := strings.CutPrefix(f, prefix); ok {
+			x, _ := strconv.ParseFloat(v, 64)
+			return x
+		}
+	}
+	return 0
+}
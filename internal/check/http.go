@@ -0,0 +1,151 @@
+package check
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	DefaultHTTPTimeout = 5 * time.Second
+	httpMaxBody        = 64 << 10
+)
+
+// HTTPSpec describes a request made by an http check. With no ExpectStatus,
+// any 2xx answer passes. Body and JSONPath assertions need a GET.
+type HTTPSpec struct {
+	Method       string
+	URL          string
+	Header       http.Header
+	Timeout      time.Duration
+	TLS          *tls.Config
+	ExpectStatus []int
+	Body         *regexp.Regexp
+	JSONPath     string // dotted path, e.g. "$.beans[0].State"
+	JSONValue    string // expected value at JSONPath; empty means it must exist
+}
+
+// HTTPChecker makes one request per check, without keeping connections
+// alive between scans.
+type HTTPChecker struct{}
+
+// CheckHTTP returns nil when the answer passes every assertion in spec.
+func (HTTPChecker) CheckHTTP(ctx context.Context, spec HTTPSpec) error {
+	timeout := spec.Timeout
+	if timeout <= 0 {
+		timeout = DefaultHTTPTimeout
+	}
+	method := spec.Method
+	if method == "" {
+		method = http.MethodGet
+	}
+	if method == http.MethodHead && (spec.Body != nil || spec.JSONPath != "") {
+		return fmt.Errorf("body assertions need GET, not %s", method)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, spec.URL, nil)
+	if err != nil {
+		return err
+	}
+	for k, vs := range spec.Header {
+		req.Header[k] = vs
+	}
+	if h := spec.Header.Get("Host"); h != "" {
+		req.Host = h
+	}
+	tr := &http.Transport{TLSClientConfig: spec.TLS, DisableKeepAlives: true}
+	defer tr.CloseIdleConnections()
+	c := &http.Client{Timeout: timeout, Transport: tr}
+
+	resp, err := c.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, httpMaxBody))
+	if err != nil {
+		return fmt.Errorf("read body: %w", err)
+	}
+	if !statusExpected(resp.StatusCode, spec.ExpectStatus) {
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	if spec.Body != nil && !spec.Body.Match(body) {
+		return fmt.Errorf("body does not match %q", spec.Body.String())
+	}
+	if spec.JSONPath != "" {
+		return assertJSON(body, spec.JSONPath, spec.JSONValue)
+	}
+	return nil
+}
+
+func statusExpected(code int, expect []int) bool {
+	if len(expect) == 0 {
+		return code >= http.StatusOK && code < http.StatusMultipleChoices
+	}
+	return slices.Contains(expect, code)
+}
+
+func assertJSON(body []byte, path, want string) error {
+	var doc any
+	if err := json.Unmarshal(body, &doc); err != nil {
+		return fmt.Errorf("body is not JSON: %w", err)
+	}
+	v, ok := lookupJSON(doc, path)
+	if !ok {
+		return fmt.Errorf("%s not found", path)
+	}
+	if want == "" {
+		return nil
+	}
+	if got := jsonString(v); got != want {
+		return fmt.Errorf("%s is %q, want %q", path, got, want)
+	}
+	return nil
+}
+
+// lookupJSON walks a decoded JSON document along a dotted path with optional
+// [n] indexes; a leading "$" is ignored.
+func lookupJSON(doc any, path string) (any, bool) {
+	path = strings.TrimPrefix(path, "$")
+	path = strings.NewReplacer("[", ".", "]", "").Replace(path)
+	cur := doc
+	for _, key := range strings.Split(path, ".") {
+		if key == "" {
+			continue
+		}
+		switch node := cur.(type) {
+		case map[string]any:
+			next, ok := node[key]
+			if !ok {
+				return nil, false
+			}
+			cur = next
+		case []any:
+			i, err := strconv.Atoi(key)
+			if err != nil || i < 0 || i >= len(node) {
+				return nil, false
+			}
+			cur = node[i]
+		default:
+			return nil, false
+		}
+	}
+	return cur, true
+}
+
+// jsonString renders scalars the way they are written in rules: strings
+// bare, numbers and booleans as in JSON.
+func jsonString(v any) string {
+	if s, ok := v.(string); ok {
+		return s
+	}
+	data, _ := json.Marshal(v)
+	return string(data)
+}
@@ -1,13 +1,77 @@
 package check
 
-import "context"
+import (
+	"context"
+	"time"
+)
 
 type Systemd interface {
 	SystemdStatus(ctx context.Context, unit string) int
 	ExpandUnitsByGlob(ctx context.Context, glob string) []string
 }
 
+// SystemdWatcher is implemented by Systemd backends that can push unit state
+// transitions instead of being polled. WatchUnits blocks until ctx is done or
+// the subscription breaks; changed receives the unit name and its new status.
+type SystemdWatcher interface {
+	WatchUnits(ctx context.Context, changed func(unit string, status int)) error
+}
+
+// Docker reports 0 when every selected container is running and passes hp.
 type Docker interface {
-	AllRunningNames(ctx context.Context, names []string) int
-	AllRunningByLabels(ctx context.Context, labels []string) int
+	AllRunningNames(ctx context.Context, names []string, hp HealthPolicy) int
+	AllRunningByLabels(ctx context.Context, labels []string, hp HealthPolicy) int
+}
+
+// DockerWatcher is implemented by Docker backends that follow the daemon's
+// events stream. WatchContainers blocks until ctx is done or the stream
+// breaks; changed receives the name and labels of every container whose
+// state may have changed.
+type DockerWatcher interface {
+	WatchContainers(ctx context.Context, changed func(name string, labels map[string]string)) error
+}
+
+// Host reports host-level facts used to compute the heartbeat status.
+type Host interface {
+	Filesystem(ctx context.Context, path string) (FSUsage, error)
+	MemoryPressure(ctx context.Context) (Pressure, error)
+	LoadAvg(ctx context.Context) (LoadAvg, error)
+}
+
+// Exec runs the command of an exec check.
+type Exec interface {
+	Run(ctx context.Context, spec ExecSpec) ExecResult
+}
+
+// Prober checks that a local address accepts connections.
+type Prober interface {
+	Probe(ctx context.Context, spec ProbeSpec) error
+}
+
+// HTTP checks a local HTTP(S) endpoint.
+type HTTP interface {
+	CheckHTTP(ctx context.Context, spec HTTPSpec) error
+}
+
+// Processes counts running processes matching a spec.
+type Processes interface {
+	CountProcesses(ctx context.Context, spec ProcessSpec) (int, error)
+}
+
+// Restarts is what restart-loop detection samples for one target.
+type Restarts struct {
+	Count     int       // restarts counted by the service manager
+	StartedAt time.Time // when the current instance became active
+}
+
+// SystemdRestarts is implemented by Systemd backends that expose a service's
+// NRestarts and ActiveEnterTimestamp.
+type SystemdRestarts interface {
+	UnitRestarts(ctx context.Context, unit string) (Restarts, error)
+}
+
+// DockerRestarts is implemented by Docker backends that expose RestartCount
+// and StartedAt. The result is keyed by container name.
+type DockerRestarts interface {
+	ContainerRestarts(ctx context.Context, names, labels []string) (map[string]Restarts, error)
 }
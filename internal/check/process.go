@@ -0,0 +1,167 @@
+package check
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/user"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// ProcessSpec selects processes; every non-empty field must match. Name is
+// compared with the kernel's comm and with the basename of argv[0] (comm is
+// cut at 15 characters), Cmdline against the space-joined argv. User is a
+// login name or numeric UID matched against the real UID. With PidFile only
+// the process it names is considered.
+type ProcessSpec struct {
+	Name    string
+	Cmdline *regexp.Regexp
+	User    string
+	PidFile string
+}
+
+// ProcessScanner counts live processes by reading procfs directly. ProcRoot
+// can point at a fake procfs tree in tests.
+type ProcessScanner struct {
+	ProcRoot string
+}
+
+func NewProcessScanner() *ProcessScanner {
+	return &ProcessScanner{ProcRoot: defaultProcRoot}
+}
+
+// CountProcesses returns how many live (non-zombie) processes match spec.
+func (p *ProcessScanner) CountProcesses(ctx context.Context, spec ProcessSpec) (int, error) {
+	uid, err := lookupUID(spec.User)
+	if err != nil {
+		return 0, err
+	}
+	pids, err := p.candidates(spec.PidFile)
+	if err != nil {
+		return 0, err
+	}
+	n := 0
+	for _, pid := range pids {
+		if ctx.Err() != nil {
+			return 0, ctx.Err()
+		}
+		proc, ok := p.read(pid)
+		if ok && proc.matches(spec, uid) {
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (p *ProcessScanner) candidates(pidFile string) ([]string, error) {
+	if pidFile != "" {
+		b, err := os.ReadFile(pidFile)
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		pid := strings.TrimSpace(string(b))
+		if _, convErr := strconv.Atoi(pid); convErr != nil {
+			return nil, fmt.Errorf("pidfile %s: bad pid %q", pidFile, pid)
+		}
+		return []string{pid}, nil
+	}
+	entries, err := os.ReadDir(p.procRoot())
+	if err != nil {
+		return nil, err
+	}
+	pids := make([]string, 0, len(entries))
+	for _, e := range entries {
+		if _, convErr := strconv.Atoi(e.Name()); convErr == nil && e.IsDir() {
+			pids = append(pids, e.Name())
+		}
+	}
+	return pids, nil
+}
+
+type procInfo struct {
+	comm    string
+	argv0   string
+	cmdline string
+	uid     string
+	zombie  bool
+}
+
+// read loads one process; ok is false if it exited in the meantime.
+func (p *ProcessScanner) read(pid string) (procInfo, bool) {
+	dir := filepath.Join(p.procRoot(), pid)
+	f, err := os.Open(filepath.Join(dir, "status"))
+	if err != nil {
+		return procInfo{}, false
+	}
+	defer f.Close()
+
+	var info procInfo
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		key, val, _ := strings.Cut(sc.Text(), ":")
+		val = strings.TrimSpace(val)
+		switch key {
+		case "Name":
+			info.comm = val
+		case "State":
+			info.zombie = strings.HasPrefix(val, "Z") || strings.HasPrefix(val, "X")
+		case "Uid":
+			if fields := strings.Fields(val); len(fields) > 0 {
+				info.uid = fields[0]
+			}
+		}
+	}
+	if raw, rdErr := os.ReadFile(filepath.Join(dir, "cmdline")); rdErr == nil {
+		args := strings.Split(string(bytes.TrimRight(raw, "\x00")), "\x00")
+		if args[0] != "" {
+			info.argv0 = filepath.Base(args[0])
+		}
+		info.cmdline = strings.Join(args, " ")
+	}
+	return info, true
+}
+
+func (info procInfo) matches(spec ProcessSpec, uid string) bool {
+	if info.zombie {
+		return false
+	}
+	if spec.Name != "" && info.comm != spec.Name && info.argv0 != spec.Name {
+		return false
+	}
+	if spec.Cmdline != nil && !spec.Cmdline.MatchString(info.cmdline) {
+		return false
+	}
+	return uid == "" || info.uid == uid
+}
+
+func (p *ProcessScanner) procRoot() string {
+	if p.ProcRoot == "" {
+		return defaultProcRoot
+	}
+	return p.ProcRoot
+}
+
+// lookupUID resolves a login name to its UID; numeric values pass through.
+func lookupUID(name string) (string, error) {
+	if name == "" {
+		return "", nil
+	}
+	if _, err := strconv.Atoi(name); err == nil {
+		return name, nil
+	}
+	u, err := user.Lookup(name)
+	if err != nil {
+		return "", err
+	}
+	return u.Uid, nil
+}
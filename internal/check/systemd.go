@@ -12,6 +12,9 @@ import (
 const (
 	SystemctlTimeout   = 5 * time.Second
 	UnexpectedExitCode = 255
+
+	watchBuffer         = 256
+	watchHealthInterval = 10 * time.Second
 )
 
 type SystemdClient struct {
@@ -48,7 +51,80 @@ func (c *SystemdClient) SystemdStatus(ctx context.Context, unit string) int {
 		}
 		return UnexpectedExitCode
 	}
-	if st, ok := props["ActiveState"].(string); ok && st == "active" {
+	st, _ := props["ActiveState"].(string)
+	return activeStatus(st)
+}
+
+// WatchUnits subscribes to PropertiesChanged signals of systemd units and
+// reports every ActiveState transition.
+func (c *SystemdClient) WatchUnits(ctx context.Context, changed func(unit string, status int)) error {
+	if c == nil || c.conn == nil {
+		return errors.New("systemd: no dbus connection")
+	}
+	if err := c.conn.Subscribe(); err != nil {
+		return err
+	}
+	defer func() { _ = c.conn.Unsubscribe() }()
+
+	updates := make(chan *sd_dbus.PropertiesUpdate, watchBuffer)
+	errs := make(chan error, 1)
+	c.conn.SetPropertiesSubscriber(updates, errs)
+	defer c.conn.SetPropertiesSubscriber(nil, nil)
+
+	health := time.NewTicker(watchHealthInterval)
+	defer health.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case err := <-errs:
+			// updates were dropped; the caller has to reconcile by polling
+			return err
+		case <-health.C:
+			if !c.conn.Connected() {
+				return errors.New("systemd: dbus connection lost")
+			}
+		case u := <-updates:
+			v, ok := u.Changed["ActiveState"]
+			if !ok {
+				continue
+			}
+			if st, isStr := v.Value().(string); isStr {
+				changed(u.UnitName, activeStatus(st))
+			}
+		}
+	}
+}
+
+// UnitRestarts reads NRestarts (service units only) and ActiveEnterTimestamp.
+func (c *SystemdClient) UnitRestarts(ctx context.Context, unit string) (Restarts, error) {
+	if c == nil || c.conn == nil {
+		return Restarts{}, errors.New("systemd: no dbus connection")
+	}
+	ctx, cancel := context.WithTimeout(ctx, SystemctlTimeout)
+	defer cancel()
+
+	n, err := c.conn.GetServicePropertyContext(ctx, unit, "NRestarts")
+	if err != nil {
+		return Restarts{}, err
+	}
+	var out Restarts
+	if v, ok := n.Value.Value().(uint32); ok {
+		out.Count = int(v)
+	}
+	ts, err := c.conn.GetUnitPropertyContext(ctx, unit, "ActiveEnterTimestamp")
+	if err != nil {
+		return Restarts{}, err
+	}
+	if usec, ok := ts.Value.Value().(uint64); ok && usec > 0 {
+		out.StartedAt = time.UnixMicro(int64(usec)) //nolint:gosec // microseconds since epoch fit in int64
+	}
+	return out, nil
+}
+
+func activeStatus(activeState string) int {
+	if activeState == "active" {
 		return 0
 	}
 	return 1
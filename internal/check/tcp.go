@@ -0,0 +1,95 @@
+package check
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"regexp"
+	"time"
+)
+
+const (
+	DefaultProbeTimeout = 3 * time.Second
+	probeMaxRead        = 4096
+)
+
+// ProbeSpec describes a connect check. Network is "tcp" or "unix". When
+// ExpectPrefix or Expect is set, the response (after Send) must match it.
+type ProbeSpec struct {
+	Network      string
+	Address      string
+	Timeout      time.Duration
+	Send         []byte
+	ExpectPrefix []byte
+	Expect       *regexp.Regexp
+}
+
+// TCPProber dials TCP addresses and Unix sockets.
+type TCPProber struct{}
+
+// Probe returns nil when the address accepts a connection within the timeout
+// and the response, if one is expected, matches.
+func (TCPProber) Probe(ctx context.Context, spec ProbeSpec) error {
+	timeout := spec.Timeout
+	if timeout <= 0 {
+		timeout = DefaultProbeTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, spec.Network, spec.Address)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+
+	if len(spec.Send) > 0 {
+		if _, wErr := conn.Write(spec.Send); wErr != nil {
+			return fmt.Errorf("send: %w", wErr)
+		}
+	}
+	if spec.ExpectPrefix == nil && spec.Expect == nil {
+		return nil
+	}
+	return expectResponse(conn, spec)
+}
+
+// expectResponse reads until the response matches or can no longer match.
+func expectResponse(conn net.Conn, spec ProbeSpec) error {
+	var got []byte
+	chunk := make([]byte, probeMaxRead)
+	for len(got) < probeMaxRead {
+		n, err := conn.Read(chunk)
+		got = append(got, chunk[:n]...)
+		if matchResponse(got, spec) {
+			return nil
+		}
+		if spec.ExpectPrefix != nil && len(got) >= len(spec.ExpectPrefix) {
+			break
+		}
+		if err != nil {
+			if !errors.Is(err, io.EOF) {
+				return fmt.Errorf("read: %w (got %q)", err, got)
+			}
+			break
+		}
+	}
+	return fmt.Errorf("unexpected response %q", got)
+}
+
+func matchResponse(got []byte, spec ProbeSpec) bool {
+	if spec.ExpectPrefix != nil && !bytes.HasPrefix(got, spec.ExpectPrefix) {
+		return false
+	}
+	if spec.Expect != nil && !spec.Expect.Match(got) {
+		return false
+	}
+	return true
+}
@@ -5,11 +5,8 @@ import (
 	"log/slog"
 	"os"
 	"path/filepath"
-	"runtime"
 	"strings"
 	"time"
-
-	"github.com/goccy/go-yaml"
 )
 
 type TLS struct {
@@ -20,20 +17,111 @@ type TLS struct {
 	InsecureSkipVerify bool   `yaml:"insecure_skip_verify"`
 }
 
+// Events enables push-based monitoring; polling every interval is then only
+// used as a periodic reconciliation every ReconcileInterval.
+type Events struct {
+	Systemd           bool   `yaml:"systemd"`
+	Docker            bool   `yaml:"docker"`
+	ReconcileInterval string `yaml:"reconcile_interval"`
+}
+
+// Spool keeps the latest undelivered status per key on disk and replays it
+// once ADCM is reachable again. Disabled when Dir is empty.
+type Spool struct {
+	Dir        string `yaml:"dir"`
+	MaxEntries int    `yaml:"max_entries"`
+	MaxAge     string `yaml:"max_age"`
+}
+
+// Retry controls re-sending of failed posts and the circuit breaker that
+// stops posting while ADCM keeps failing. BreakerThreshold < 0 disables it.
+type Retry struct {
+	MaxAttempts      int    `yaml:"max_attempts"`
+	InitialBackoff   string `yaml:"initial_backoff"`
+	MaxBackoff       string `yaml:"max_backoff"`
+	BreakerThreshold int    `yaml:"breaker_threshold"`
+	BreakerCooldown  string `yaml:"breaker_cooldown"`
+}
+
+// Batch groups the posts of one cycle into a single request to Path.
+// Shape is "list" (array of items) or "map" (host status plus a component
+// map); Path may contain {host_id}.
+type Batch struct {
+	Enabled bool   `yaml:"enabled"`
+	Path    string `yaml:"path"`
+	Shape   string `yaml:"shape"`
+	Window  string `yaml:"window"`
+	MaxSize int    `yaml:"max_size"`
+}
+
+// Server is the agent's own HTTP endpoint serving /healthz, /readyz, /status
+// and /metrics. Listen is "host:port" or "unix:/path/to.sock"; empty
+// disables it.
+type Server struct {
+	Listen string `yaml:"listen"`
+}
+
+// Metrics writes the /metrics output to Textfile after every cycle, for the
+// node_exporter textfile collector on hosts without a listener.
+type Metrics struct {
+	Textfile string `yaml:"textfile"`
+}
+
+// Tracing exports OpenTelemetry spans over OTLP/HTTP to Endpoint
+// (e.g. http://collector:4318). Disabled when Endpoint is empty; SampleRatio
+// outside (0, 1] means 1.
+type Tracing struct {
+	Endpoint    string            `yaml:"endpoint"`
+	Headers     map[string]string `yaml:"headers"`
+	SampleRatio float64           `yaml:"sample_ratio"`
+	ServiceName string            `yaml:"service_name"`
+}
+
+// RulesReload guards rules reloads: one that would remove more than
+// MaxDropPercent of the loaded rules is refused. 0 disables the guard.
+type RulesReload struct {
+	MaxDropPercent float64 `yaml:"max_drop_percent"`
+}
+
+// ComponentDiscovery asks ADCM for the components of the host at startup
+// and every Refresh, so that rules can name them by service and component
+// (component_refs) instead of by ID. Path may contain {host_id}. The last
+// answer is kept while ADCM is unreachable and, with CacheFile, across
+// restarts.
+type ComponentDiscovery struct {
+	Enabled   bool   `yaml:"enabled"`
+	Path      string `yaml:"path"`
+	Refresh   string `yaml:"refresh"`
+	CacheFile string `yaml:"cache_file"`
+}
+
 type Config struct {
-	ADCMURL        string `yaml:"adcm_url"`
-	HostID         int    `yaml:"host_id"`
-	Token          string `yaml:"token"`
-	TokenFile      string `yaml:"token_file"`
-	RulesPath      string `yaml:"rules_path"`
-	Interval       string `yaml:"interval"`
-	HTTPTimeout    string `yaml:"http_timeout"`
-	Concurrency    int    `yaml:"concurrency"`
-	LogBodies      bool   `yaml:"log_bodies"`
-	ForceSendAfter string `yaml:"force_send_after"`
-	LogLevel       string `yaml:"log_level"`
-	LogFormat      string `yaml:"log_format"` // "text" or "json"
-	TLS            TLS    `yaml:"tls"`
+	ADCMURL         string             `yaml:"adcm_url"`
+	HostID          int                `yaml:"host_id"`
+	Token           string             `yaml:"token"`
+	TokenFile       string             `yaml:"token_file"`
+	RulesPath       string             `yaml:"rules_path"`
+	RulesDir        string             `yaml:"rules_dir"` // drop-in *.yaml files merged after rules_path
+	RulesReload     RulesReload        `yaml:"rules_reload"`
+	Discovery       ComponentDiscovery `yaml:"component_discovery"`
+	WatchConfig     bool               `yaml:"watch_config"` // reload this file when it changes, not only on SIGHUP
+	Interval        string             `yaml:"interval"`
+	HTTPTimeout     string             `yaml:"http_timeout"`
+	Concurrency     int                `yaml:"concurrency"`
+	ExecConcurrency int                `yaml:"exec_concurrency"`
+	LogBodies       bool               `yaml:"log_bodies"`
+	ForceSendAfter  string             `yaml:"force_send_after"`
+	LogLevel        string             `yaml:"log_level"`
+	LogFormat       string             `yaml:"log_format"` // "text" or "json"
+	TLS             TLS                `yaml:"tls"`
+	Events          Events             `yaml:"events"`
+	Spool           Spool              `yaml:"spool"`
+	Retry           Retry              `yaml:"retry"`
+	Batch           Batch              `yaml:"batch"`
+	SilenceFile     string             `yaml:"silence_file"`
+	Server          Server             `yaml:"server"`
+	Metrics         Metrics            `yaml:"metrics"`
+	Tracing         Tracing            `yaml:"tracing"`
 }
 
 func MustDuration(s string, def time.Duration) time.Duration {
@@ -47,22 +135,12 @@ func MustDuration(s string, def time.Duration) time.Duration {
 	return d
 }
 
+// Load reads the config strictly: unknown fields are rejected and the result
+// is validated (see Config.Validate). Environment and flag overrides are
+// applied by Loader.
 func Load(path string) (Config, error) {
-	data, readErr := os.ReadFile(path)
-	if readErr != nil {
-		return Config{}, readErr
-	}
-	var c Config
-	if unErr := yaml.Unmarshal(data, &c); unErr != nil {
-		return Config{}, unErr
-	}
-	if c.ADCMURL == "" || c.HostID == 0 || c.RulesPath == "" {
-		return Config{}, errors.New("adcm_url, host_id, rules_path are required")
-	}
-	if c.Concurrency <= 0 {
-		c.Concurrency = runtime.NumCPU()
-	}
-	return c, nil
+	c, _, err := Loader{Path: path}.Load()
+	return c, err
 }
 
 func LoadToken(c *Config) (string, error) {
@@ -3,6 +3,7 @@ package config
 import (
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 )
@@ -82,3 +83,46 @@ rules_path: "/tmp/x.yaml"
 		t.Fatalf("bad values: %+v", cfg)
 	}
 }
+
+func TestLoad_Invalid(t *testing.T) {
+	dir := t.TempDir()
+	cert := filepath.Join(dir, "client.crt")
+	if err := os.WriteFile(cert, []byte("x"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	yml := []byte(`
+adcm_url: "adcm.local"
+host_id: 42
+rules_path: "/tmp/x.yaml"
+interval: "5 minutes"
+tls:
+  cert_file: "` + cert + `"
+log_level: "verbose"
+`)
+	fn := filepath.Join(dir, "cfg.yaml")
+	if err := os.WriteFile(fn, yml, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	_, err := Load(fn)
+	if err == nil {
+		t.Fatal("want validation error")
+	}
+	for _, want := range []string{
+		fn + `:2:11: adcm_url: want an http:// or https:// URL, got "adcm.local"`,
+		fn + `:5:11: interval: invalid duration "5 minutes"`,
+		fn + ":7:3: tls: cert_file and key_file must be set together",
+		fn + `:8:12: log_level: must be one of`,
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Fatalf("missing %q in:\n%v", want, err)
+		}
+	}
+
+	yml = []byte("adcm_url: http://a\nhost_id: 1\nrules_path: r\nintreval: 1s\n")
+	if err = os.WriteFile(fn, yml, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = Load(fn); err == nil || !strings.HasPrefix(err.Error(), fn+":4:1: ") {
+		t.Fatalf("want unknown field at 4:1, got %v", err)
+	}
+}
@@ -0,0 +1,87 @@
+package config
+
+import (
+	"runtime"
+	"strings"
+	"time"
+)
+
+// Built-in defaults of the fields left empty (or zero) in every layer.
+const (
+	DefaultInterval          = 5 * time.Second
+	DefaultHTTPTimeout       = 5 * time.Second
+	DefaultForceSendAfter    = 120 * time.Second
+	DefaultExecConcurrency   = 4
+	DefaultLogLevel          = "info"
+	DefaultLogFormat         = "text"
+	DefaultReconcileInterval = 60 * time.Second
+	DefaultDiscoveryPath     = "/api/v1/host/{host_id}/component/"
+	DefaultDiscoveryRefresh  = 5 * time.Minute
+	DefaultSpoolEntries      = 10000
+	DefaultSpoolAge          = 24 * time.Hour
+	DefaultRetryAttempts     = 3
+	DefaultRetryBackoff      = 200 * time.Millisecond
+	DefaultRetryMaxBackoff   = 5 * time.Second
+	DefaultBreakerThreshold  = 5
+	DefaultBreakerCooldown   = 30 * time.Second
+	DefaultBatchPath         = "/status/api/v1/host/{host_id}/batch/"
+	DefaultBatchShape        = "list"
+	DefaultBatchWindow       = 200 * time.Millisecond
+	DefaultBatchSize         = 500
+)
+
+// applyDefaults fills the fields the agent would otherwise default when it
+// uses them, so that the loaded config shows the values in effect.
+func (c *Config) applyDefaults() {
+	orDuration(&c.Interval, DefaultInterval)
+	orDuration(&c.HTTPTimeout, DefaultHTTPTimeout)
+	orDuration(&c.ForceSendAfter, DefaultForceSendAfter)
+	orInt(&c.Concurrency, runtime.NumCPU())
+	orInt(&c.ExecConcurrency, DefaultExecConcurrency)
+	orString(&c.LogLevel, DefaultLogLevel)
+	orString(&c.LogFormat, DefaultLogFormat)
+	orDuration(&c.Events.ReconcileInterval, DefaultReconcileInterval)
+	orString(&c.Discovery.Path, DefaultDiscoveryPath)
+	orDuration(&c.Discovery.Refresh, DefaultDiscoveryRefresh)
+	orInt(&c.Spool.MaxEntries, DefaultSpoolEntries)
+	orDuration(&c.Spool.MaxAge, DefaultSpoolAge)
+	orInt(&c.Retry.MaxAttempts, DefaultRetryAttempts)
+	orDuration(&c.Retry.InitialBackoff, DefaultRetryBackoff)
+	orDuration(&c.Retry.MaxBackoff, DefaultRetryMaxBackoff)
+	if c.Retry.BreakerThreshold == 0 {
+		c.Retry.BreakerThreshold = DefaultBreakerThreshold
+	}
+	orDuration(&c.Retry.BreakerCooldown, DefaultBreakerCooldown)
+	orString(&c.Batch.Path, DefaultBatchPath)
+	orString(&c.Batch.Shape, DefaultBatchShape)
+	orDuration(&c.Batch.Window, DefaultBatchWindow)
+	orInt(&c.Batch.MaxSize, DefaultBatchSize)
+}
+
+func orString(s *string, def string) {
+	if *s == "" {
+		*s = def
+	}
+}
+
+// orInt also replaces negative values, which the agent treats as unset.
+func orInt(n *int, def int) {
+	if *n <= 0 {
+		*n = def
+	}
+}
+
+// orDuration writes def the way a user would, "2m" rather than "2m0s".
+func orDuration(s *string, def time.Duration) {
+	if *s != "" {
+		return
+	}
+	v := def.String()
+	if strings.HasSuffix(v, "m0s") {
+		v = strings.TrimSuffix(v, "0s")
+	}
+	if strings.HasSuffix(v, "h0m") {
+		v = strings.TrimSuffix(v, "0m")
+	}
+	*s = v
+}
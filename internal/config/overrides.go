@@ -0,0 +1,295 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/arenadata/ad-status-sender/internal/schema"
+	"github.com/goccy/go-yaml"
+)
+
+// EnvPrefix starts the environment variables overriding config fields:
+// tls.cert_file is set by AD_STATUS_SENDER_TLS_CERT_FILE.
+const EnvPrefix = "AD_STATUS_SENDER_"
+
+const (
+	redacted   = "<redacted>"
+	tabPadding = 2
+)
+
+// Source tells which layer an effective config value came from.
+type Source string
+
+const (
+	SourceDefault Source = "default"
+	SourceFile    Source = "file"
+	SourceEnv     Source = "env"
+	SourceFlag    Source = "flag"
+)
+
+// Sources maps every field ("adcm_url", "tls.cert_file") to the layer its
+// value came from.
+type Sources map[string]Source
+
+// Loader builds the effective config from the built-in defaults, the file at
+// Path, AD_STATUS_SENDER_* variables in Env and Flags, each layer overriding
+// the ones before it.
+type Loader struct {
+	Path string
+	// Env holds "NAME=value" pairs, usually os.Environ(); other variables
+	// than AD_STATUS_SENDER_* are ignored.
+	Env []string
+	// Flags maps fields to values, as filled by BindFlags.
+	Flags map[string]string
+}
+
+// Load reads, merges and validates the config. Problems with overridden
+// values name the variable or flag instead of a position in the file.
+func (l Loader) Load() (Config, Sources, error) {
+	data, err := os.ReadFile(l.Path)
+	if err != nil {
+		return Config{}, nil, err
+	}
+	var c Config
+	if err = schema.Decode(l.Path, data, &c); err != nil {
+		return Config{}, nil, err
+	}
+	src := fileSources(data)
+
+	env, err := envValues(l.Env)
+	if err != nil {
+		return Config{}, nil, err
+	}
+	for _, layer := range []struct {
+		src    Source
+		values map[string]string
+	}{{SourceEnv, env}, {SourceFlag, l.Flags}} {
+		for key, v := range layer.values {
+			if err = set(&c, key, v); err != nil {
+				return Config{}, nil, fmt.Errorf("%s: %w", origin(layer.src, key), err)
+			}
+			src[key] = layer.src
+		}
+	}
+
+	if err = c.Validate(l.Path, data); err != nil {
+		relocate(err, src)
+		return Config{}, nil, err
+	}
+	c.applyDefaults()
+	return c, src, nil
+}
+
+// BindFlags registers one flag per config field on fs, named like the field
+// ("-adcm_url", "-tls.cert_file"). The returned map receives the values
+// given on the command line and is meant for Loader.Flags.
+func BindFlags(fs *flag.FlagSet) map[string]string {
+	values := make(map[string]string)
+	for _, f := range fields() {
+		fs.Func(f.key, fmt.Sprintf("override %s (env %s)", f.key, envName(f.key)), func(v string) error {
+			if err := set(&Config{}, f.key, v); err != nil {
+				return err
+			}
+			values[f.key] = v
+			return nil
+		})
+	}
+	return values
+}
+
+// WriteEffective prints every field of c with its value and source. The
+// token and tracing header values are redacted.
+func WriteEffective(w io.Writer, c Config, src Sources) error {
+	tw := tabwriter.NewWriter(w, 0, 0, tabPadding, ' ', 0)
+	fmt.Fprintln(tw, "FIELD\tVALUE\tSOURCE")
+	v := reflect.ValueOf(c)
+	for _, f := range fields() {
+		s := src[f.key]
+		if s == "" {
+			s = SourceDefault
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", f.key, format(f.key, v.FieldByIndex(f.index)), s)
+	}
+	return tw.Flush()
+}
+
+type field struct {
+	key   string
+	index []int
+}
+
+// fields lists the settable fields of Config, descending into sections.
+func fields() []field {
+	var out []field
+	var walk func(t reflect.Type, prefix string, index []int)
+	walk = func(t reflect.Type, prefix string, index []int) {
+		for i := range t.NumField() {
+			sf := t.Field(i)
+			tag, _, _ := strings.Cut(sf.Tag.Get("yaml"), ",")
+			if tag == "" || tag == "-" {
+				continue
+			}
+			idx := append(slices.Clone(index), i)
+			if sf.Type.Kind() == reflect.Struct {
+				walk(sf.Type, prefix+tag+".", idx)
+				continue
+			}
+			out = append(out, field{key: prefix + tag, index: idx})
+		}
+	}
+	walk(reflect.TypeOf(Config{}), "", nil)
+	return out
+}
+
+func lookup(key string) (field, bool) {
+	for _, f := range fields() {
+		if f.key == key {
+			return f, true
+		}
+	}
+	return field{}, false
+}
+
+// set parses v into the field key of c. Maps take "k=v,k2=v2".
+func set(c *Config, key, v string) error {
+	f, ok := lookup(key)
+	if !ok {
+		return fmt.Errorf("unknown field %q", key)
+	}
+	dst := reflect.ValueOf(c).Elem().FieldByIndex(f.index)
+	switch dst.Kind() { //nolint:exhaustive // Config only has these kinds
+	case reflect.String:
+		dst.SetString(v)
+	case reflect.Int:
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return fmt.Errorf("want an integer, got %q", v)
+		}
+		dst.SetInt(int64(n))
+	case reflect.Bool:
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return fmt.Errorf("want true or false, got %q", v)
+		}
+		dst.SetBool(b)
+	case reflect.Float64:
+		x, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return fmt.Errorf("want a number, got %q", v)
+		}
+		dst.SetFloat(x)
+	case reflect.Map:
+		m := make(map[string]string)
+		for _, pair := range strings.Split(v, ",") {
+			k, val, found := strings.Cut(pair, "=")
+			if !found || strings.TrimSpace(k) == "" {
+				return fmt.Errorf("want key=value[,key=value], got %q", v)
+			}
+			m[strings.TrimSpace(k)] = val
+		}
+		dst.Set(reflect.ValueOf(m))
+	default:
+		return fmt.Errorf("field %q can't be overridden", key)
+	}
+	return nil
+}
+
+func envName(key string) string {
+	return EnvPrefix + strings.ToUpper(strings.ReplaceAll(key, ".", "_"))
+}
+
+// envValues picks the AD_STATUS_SENDER_* variables out of env. Unknown
+// names are errors, so that a typo doesn't go unnoticed.
+func envValues(env []string) (map[string]string, error) {
+	byName := make(map[string]string)
+	for _, f := range fields() {
+		byName[envName(f.key)] = f.key
+	}
+	values := make(map[string]string)
+	var errs []error
+	for _, kv := range env {
+		name, v, _ := strings.Cut(kv, "=")
+		if !strings.HasPrefix(name, EnvPrefix) {
+			continue
+		}
+		key, ok := byName[name]
+		if !ok {
+			errs = append(errs, fmt.Errorf("unknown environment variable %s", name))
+			continue
+		}
+		values[key] = v
+	}
+	return values, errors.Join(errs...)
+}
+
+// fileSources marks the fields present in the YAML source.
+func fileSources(data []byte) Sources {
+	src := make(Sources)
+	var doc map[string]any
+	if yaml.Unmarshal(data, &doc) != nil {
+		return src
+	}
+	var walk func(m map[string]any, prefix string)
+	walk = func(m map[string]any, prefix string) {
+		for k, v := range m {
+			src[prefix+k] = SourceFile
+			if sub, ok := v.(map[string]any); ok {
+				walk(sub, prefix+k+".")
+			}
+		}
+	}
+	walk(doc, "")
+	return src
+}
+
+func origin(s Source, key string) string {
+	if s == SourceEnv {
+		return "env " + envName(key)
+	}
+	return "flag -" + key
+}
+
+// relocate points validation errors about overridden fields to their
+// variable or flag.
+func relocate(err error, src Sources) {
+	list := []error{err}
+	var joined interface{ Unwrap() []error }
+	if errors.As(err, &joined) {
+		list = joined.Unwrap()
+	}
+	for _, e := range list {
+		var verr *schema.ValidationError
+		if !errors.As(e, &verr) {
+			continue
+		}
+		if s := src[verr.Path]; s == SourceEnv || s == SourceFlag {
+			verr.File, verr.Line, verr.Column = origin(s, verr.Path), 0, 0
+		}
+	}
+}
+
+func format(key string, v reflect.Value) string {
+	if v.Kind() != reflect.Map {
+		if key == "token" && v.String() != "" {
+			return redacted
+		}
+		return fmt.Sprint(v.Interface())
+	}
+	keys := make([]string, 0, v.Len())
+	for _, k := range v.MapKeys() {
+		keys = append(keys, k.String())
+	}
+	slices.Sort(keys)
+	for i, k := range keys {
+		keys[i] = k + "=" + redacted
+	}
+	return strings.Join(keys, ",")
+}
@@ -0,0 +1,160 @@
+package config
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeConfig(t *testing.T, yml string) string {
+	t.Helper()
+	fn := filepath.Join(t.TempDir(), "cfg.yaml")
+	if err := os.WriteFile(fn, []byte(yml), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return fn
+}
+
+func TestLoader_Precedence(t *testing.T) {
+	fn := writeConfig(t, `
+adcm_url: "http://file"
+host_id: 1
+rules_path: "/tmp/x.yaml"
+interval: "5s"
+tls:
+  server_name: "adcm.file"
+`)
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	flags := BindFlags(fs)
+	args := []string{"-interval", "30s", "-tracing.headers", "Authorization=Bearer x, X-Org=1"}
+	if err := fs.Parse(args); err != nil {
+		t.Fatal(err)
+	}
+	env := []string{
+		"PATH=/usr/bin",
+		"AD_STATUS_SENDER_HOST_ID=42",
+		"AD_STATUS_SENDER_INTERVAL=10s",
+		"AD_STATUS_SENDER_TLS_INSECURE_SKIP_VERIFY=true",
+	}
+	c, src, err := Loader{Path: fn, Env: env, Flags: flags}.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.ADCMURL != "http://file" || c.HostID != 42 || c.Interval != "30s" || !c.TLS.InsecureSkipVerify ||
+		c.TLS.ServerName != "adcm.file" || c.Tracing.Headers["X-Org"] != "1" {
+		t.Fatalf("bad merge: %+v", c)
+	}
+	want := Sources{
+		"adcm_url":                 SourceFile,
+		"host_id":                  SourceEnv,
+		"interval":                 SourceFlag,
+		"tls.server_name":          SourceFile,
+		"tls.insecure_skip_verify": SourceEnv,
+		"tracing.headers":          SourceFlag,
+	}
+	for key, s := range want {
+		if src[key] != s {
+			t.Fatalf("%s: want source %s, got %q", key, s, src[key])
+		}
+	}
+	if s := src["http_timeout"]; s != "" {
+		t.Fatalf("http_timeout: want no source (default), got %s", s)
+	}
+}
+
+func TestLoader_Errors(t *testing.T) {
+	fn := writeConfig(t, "adcm_url: \"http://file\"\nhost_id: 1\nrules_path: \"/tmp/x.yaml\"\n")
+	for env, want := range map[string]string{
+		"AD_STATUS_SENDER_HOSTID=1":      "unknown environment variable AD_STATUS_SENDER_HOSTID",
+		"AD_STATUS_SENDER_HOST_ID=many":  `env AD_STATUS_SENDER_HOST_ID: want an integer, got "many"`,
+		"AD_STATUS_SENDER_ADCM_URL=adcm": `env AD_STATUS_SENDER_ADCM_URL: adcm_url: want an http:// or https:// URL`,
+	} {
+		_, _, err := Loader{Path: fn, Env: []string{env}}.Load()
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Fatalf("%s: want %q, got %v", env, want, err)
+		}
+	}
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.SetOutput(&bytes.Buffer{})
+	BindFlags(fs)
+	if err := fs.Parse([]string{"-log_bodies", "maybe"}); err == nil {
+		t.Fatal("want a parse error for a non-boolean flag value")
+	}
+}
+
+func TestLoader_AppliesDefaults(t *testing.T) {
+	fn := writeConfig(t, "adcm_url: \"http://file\"\nhost_id: 1\nrules_path: \"/tmp/x.yaml\"\n"+
+		"retry:\n  max_attempts: 0\n  breaker_threshold: -1\n")
+	c, src, err := Loader{Path: fn}.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	if err = WriteEffective(&out, c, src); err != nil {
+		t.Fatal(err)
+	}
+	rows := make(map[string]string)
+	for _, line := range strings.Split(out.String(), "\n") {
+		if f := strings.Fields(line); len(f) > 0 {
+			rows[f[0]] = strings.Join(f[1:], " ")
+		}
+	}
+	for key, want := range map[string]string{
+		"interval":                    "5s default",
+		"http_timeout":                "5s default",
+		"force_send_after":            "2m default",
+		"log_level":                   "info default",
+		"log_format":                  "text default",
+		"exec_concurrency":            "4 default",
+		"component_discovery.refresh": "5m default",
+		"spool.max_age":               "24h default",
+		"retry.initial_backoff":       "200ms default",
+		"retry.max_attempts":          "3 file",
+		"retry.breaker_threshold":     "-1 file", // disables the breaker, kept
+		"batch.shape":                 "list default",
+	} {
+		if rows[key] != want {
+			t.Errorf("%s: want %q, got %q", key, want, rows[key])
+		}
+	}
+	// the defaults are the ones the agent falls back to
+	if MustDuration(c.ForceSendAfter, 0) != DefaultForceSendAfter ||
+		MustDuration(c.Spool.MaxAge, 0) != DefaultSpoolAge {
+		t.Fatalf("defaults written as %q and %q", c.ForceSendAfter, c.Spool.MaxAge)
+	}
+}
+
+func TestWriteEffective_Redacts(t *testing.T) {
+	c := Config{
+		ADCMURL: "http://adcm",
+		Token:   "s3cret",
+		Tracing: Tracing{Headers: map[string]string{"Authorization": "Bearer s3cret"}},
+	}
+	var out bytes.Buffer
+	if err := WriteEffective(&out, c, Sources{"adcm_url": SourceFile, "token": SourceEnv}); err != nil {
+		t.Fatal(err)
+	}
+	s := out.String()
+	if strings.Contains(s, "s3cret") {
+		t.Fatalf("secret leaked:\n%s", s)
+	}
+	lines := make(map[string]string)
+	for _, l := range strings.Split(s, "\n") {
+		if f := strings.Fields(l); len(f) > 0 {
+			lines[f[0]] = strings.Join(f, " ")
+		}
+	}
+	for key, want := range map[string]string{
+		"adcm_url":        "adcm_url http://adcm file",
+		"token":           "token <redacted> env",
+		"tracing.headers": "tracing.headers Authorization=<redacted> default",
+		"http_timeout":    "http_timeout default",
+	} {
+		if lines[key] != want {
+			t.Fatalf("want %q, got %q in:\n%s", want, lines[key], s)
+		}
+	}
+}
@@ -0,0 +1,38 @@
+package config
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"os"
+	"strings"
+)
+
+// ClientConfig builds a client TLS config: system roots plus CAFile, an
+// optional client certificate, ServerName override and InsecureSkipVerify.
+// Unreadable files are ignored, leaving the defaults in place.
+func (t TLS) ClientConfig() *tls.Config {
+	tlsConf := &tls.Config{MinVersion: tls.VersionTLS12}
+	roots, sysErr := x509.SystemCertPool()
+	if sysErr != nil || roots == nil {
+		roots = x509.NewCertPool()
+	}
+	if strings.TrimSpace(t.CAFile) != "" {
+		if pem, rdErr := os.ReadFile(t.CAFile); rdErr == nil {
+			_ = roots.AppendCertsFromPEM(pem)
+		}
+	}
+	tlsConf.RootCAs = roots
+
+	if t.CertFile != "" && t.KeyFile != "" {
+		if cert, ckErr := tls.LoadX509KeyPair(t.CertFile, t.KeyFile); ckErr == nil {
+			tlsConf.Certificates = []tls.Certificate{cert}
+		}
+	}
+	if t.ServerName != "" {
+		tlsConf.ServerName = t.ServerName
+	}
+	if t.InsecureSkipVerify {
+		tlsConf.InsecureSkipVerify = true
+	}
+	return tlsConf
+}
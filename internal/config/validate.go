@@ -0,0 +1,98 @@
+package config
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/arenadata/ad-status-sender/internal/schema"
+)
+
+const percentMax = 100
+
+// Validate checks the settings that decoding alone doesn't: required fields,
+// durations, enums and referenced files. data is the YAML source of c and
+// is only used to report line and column.
+func (c Config) Validate(file string, data []byte) error {
+	chk := schema.NewChecker(file, data)
+	chk.Required("adcm_url", c.ADCMURL)
+	chk.URL("adcm_url", c.ADCMURL)
+	if c.HostID <= 0 {
+		chk.Addf("host_id", "must be a positive ADCM host ID")
+	}
+	if c.RulesPath == "" && c.RulesDir == "" {
+		chk.Addf("rules_path", "rules_path or rules_dir is required")
+	}
+	chk.File("rules_dir", c.RulesDir)
+	if p := c.RulesReload.MaxDropPercent; p < 0 || p > percentMax {
+		chk.Addf("rules_reload.max_drop_percent", "must be between 0 and 100")
+	}
+	chk.Duration("component_discovery.refresh", c.Discovery.Refresh)
+	if c.Discovery.CacheFile != "" {
+		chk.File("component_discovery.cache_file", filepath.Dir(c.Discovery.CacheFile))
+	}
+	chk.File("token_file", c.TokenFile)
+
+	chk.Duration("interval", c.Interval)
+	if d, err := time.ParseDuration(c.Interval); err == nil && d == 0 {
+		chk.Addf("interval", "must be greater than 0")
+	}
+	chk.Duration("http_timeout", c.HTTPTimeout)
+	chk.Duration("force_send_after", c.ForceSendAfter)
+	if c.Concurrency < 0 {
+		chk.Addf("concurrency", "must not be negative")
+	}
+	if c.ExecConcurrency < 0 {
+		chk.Addf("exec_concurrency", "must not be negative")
+	}
+	chk.OneOf("log_level", strings.ToLower(c.LogLevel), "debug", "info", "warn", "warning", "error")
+	chk.OneOf("log_format", c.LogFormat, "text", "json")
+
+	c.TLS.Check(chk, "tls")
+	chk.Duration("events.reconcile_interval", c.Events.ReconcileInterval)
+	chk.Duration("spool.max_age", c.Spool.MaxAge)
+	chk.Duration("retry.initial_backoff", c.Retry.InitialBackoff)
+	chk.Duration("retry.max_backoff", c.Retry.MaxBackoff)
+	chk.Duration("retry.breaker_cooldown", c.Retry.BreakerCooldown)
+	chk.Duration("batch.window", c.Batch.Window)
+	chk.OneOf("batch.shape", c.Batch.Shape, "list", "map")
+	c.Server.check(chk)
+	if c.Metrics.Textfile != "" {
+		chk.File("metrics.textfile", filepath.Dir(c.Metrics.Textfile))
+	}
+	chk.URL("tracing.endpoint", c.Tracing.Endpoint)
+	if c.Tracing.SampleRatio < 0 || c.Tracing.SampleRatio > 1 {
+		chk.Addf("tracing.sample_ratio", "must be between 0 and 1")
+	}
+	return chk.Err()
+}
+
+// Check reports a certificate without its key (or the other way round) and
+// files that don't exist. path is where t sits in the document.
+func (t TLS) Check(chk *schema.Checker, path string) {
+	if (t.CertFile == "") != (t.KeyFile == "") {
+		chk.Addf(path, "cert_file and key_file must be set together")
+	}
+	chk.File(path+".ca_file", t.CAFile)
+	chk.File(path+".cert_file", t.CertFile)
+	chk.File(path+".key_file", t.KeyFile)
+}
+
+func (s Server) check(chk *schema.Checker) {
+	if s.Listen == "" {
+		return
+	}
+	if sock, ok := strings.CutPrefix(s.Listen, "unix:"); ok {
+		if sock == "" {
+			chk.Addf("server.listen", "unix socket path is empty")
+		} else if _, err := os.Stat(filepath.Dir(sock)); err != nil {
+			chk.Addf("server.listen", "%v", err)
+		}
+		return
+	}
+	if _, _, err := net.SplitHostPort(s.Listen); err != nil {
+		chk.Addf("server.listen", "want host:port or unix:/path, got %q", s.Listen)
+	}
+}
@@ -0,0 +1,47 @@
+package config
+
+import (
+	"path/filepath"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+const watchDebounce = 150 * time.Millisecond
+
+// Watch calls changed whenever the file at path is written, created or
+// renamed into place, once the burst of events has settled. The directory
+// is watched rather than the file, so that editors and configuration
+// management replacing the file are noticed. Watch returns when stop is
+// closed.
+func Watch(stop <-chan struct{}, path string, changed func()) error {
+	w, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	defer w.Close()
+	if err = w.Add(filepath.Dir(path)); err != nil {
+		return err
+	}
+
+	debounce := time.NewTimer(0)
+	if !debounce.Stop() {
+		<-debounce.C
+	}
+	for {
+		select {
+		case <-stop:
+			return nil
+		case ev := <-w.Events:
+			if filepath.Clean(ev.Name) != filepath.Clean(path) {
+				continue
+			}
+			if ev.Has(fsnotify.Write) || ev.Has(fsnotify.Create) || ev.Has(fsnotify.Rename) {
+				debounce.Reset(watchDebounce)
+			}
+		case <-debounce.C:
+			changed()
+		case <-w.Errors:
+		}
+	}
+}
@@ -0,0 +1,162 @@
+package maintenance
+
+import (
+	"fmt"
+	"math/bits"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const cronFields = 5
+
+// Schedule is a parsed five-field cron expression: minute, hour, day of
+// month, month and day of week. Fields accept *, lists, ranges, steps and
+// three-letter month and weekday names (0 and 7 are both Sunday).
+type Schedule struct {
+	minute, hour, dom, month, dow uint64
+	domAny, dowAny                bool
+}
+
+type cronField struct {
+	min, max int
+	names    []string
+}
+
+func cronLayout() [cronFields]cronField {
+	return [cronFields]cronField{
+		{min: 0, max: 59},
+		{min: 0, max: 23},
+		{min: 1, max: 31},
+		{min: 1, max: 12, names: []string{"", "JAN", "FEB", "MAR", "APR", "MAY", "JUN",
+			"JUL", "AUG", "SEP", "OCT", "NOV", "DEC"}},
+		{min: 0, max: 7, names: []string{"SUN", "MON", "TUE", "WED", "THU", "FRI", "SAT"}},
+	}
+}
+
+// ParseCron parses expr, e.g. "0 2 * * SUN" or "*/15 9-17 * * MON-FRI".
+func ParseCron(expr string) (Schedule, error) {
+	fields := strings.Fields(expr)
+	if len(fields) != cronFields {
+		return Schedule{}, fmt.Errorf("cron %q: want %d fields, got %d", expr, cronFields, len(fields))
+	}
+	var masks [cronFields]uint64
+	layout := cronLayout()
+	for i, f := range fields {
+		m, err := parseField(f, layout[i])
+		if err != nil {
+			return Schedule{}, fmt.Errorf("cron %q: %w", expr, err)
+		}
+		masks[i] = m
+	}
+	dow := masks[4]
+	if dow&(1<<7) != 0 {
+		dow |= 1 // 7 is Sunday too
+	}
+	return Schedule{
+		minute: masks[0],
+		hour:   masks[1],
+		dom:    masks[2],
+		month:  masks[3],
+		dow:    dow,
+		domAny: fields[2] == "*",
+		dowAny: fields[4] == "*",
+	}, nil
+}
+
+// Matches reports whether t falls on a scheduled minute. As in cron, a
+// restricted day of month and day of week match if either does.
+func (s Schedule) Matches(t time.Time) bool {
+	return s.minute&(1<<t.Minute()) != 0 && s.hourMatches(t)
+}
+
+// hourMatches reports whether the hour of t has scheduled minutes.
+func (s Schedule) hourMatches(t time.Time) bool {
+	if s.hour&(1<<t.Hour()) == 0 || s.month&(1<<int(t.Month())) == 0 {
+		return false
+	}
+	dom := s.dom&(1<<t.Day()) != 0
+	dow := s.dow&(1<<int(t.Weekday())) != 0
+	switch {
+	case s.domAny && s.dowAny:
+		return true
+	case s.domAny:
+		return dow
+	case s.dowAny:
+		return dom
+	default:
+		return dom || dow
+	}
+}
+
+// Last returns the latest scheduled minute in (now-lookback, now]. It goes
+// back an hour at a time and looks up the minute in the hours that match.
+func (s Schedule) Last(now time.Time, lookback time.Duration) (time.Time, bool) {
+	end := now.Add(-lookback)
+	for t := now.Truncate(time.Minute); t.After(end); {
+		m := t.Minute()
+		if s.hourMatches(t) {
+			if earlier := s.minute & (1<<(m+1) - 1); earlier != 0 {
+				start := t.Add(-time.Duration(m-(bits.Len64(earlier)-1)) * time.Minute)
+				if !start.After(end) {
+					break
+				}
+				return start, true
+			}
+		}
+		// the last minute of the previous hour
+		t = t.Add(-time.Duration(m+1) * time.Minute)
+	}
+	return time.Time{}, false
+}
+
+func parseField(f string, lay cronField) (uint64, error) {
+	var mask uint64
+	for _, part := range strings.Split(f, ",") {
+		rng, stepStr, hasStep := strings.Cut(part, "/")
+		step := 1
+		if hasStep {
+			n, err := strconv.Atoi(stepStr)
+			if err != nil || n < 1 {
+				return 0, fmt.Errorf("bad step %q", part)
+			}
+			step = n
+		}
+		lo, hi := lay.min, lay.max
+		if rng != "*" {
+			a, b, isRange := strings.Cut(rng, "-")
+			var err error
+			if lo, err = cronValue(a, lay); err != nil {
+				return 0, err
+			}
+			hi = lo
+			if isRange {
+				if hi, err = cronValue(b, lay); err != nil {
+					return 0, err
+				}
+			} else if hasStep {
+				hi = lay.max
+			}
+			if hi < lo {
+				return 0, fmt.Errorf("bad range %q", rng)
+			}
+		}
+		for v := lo; v <= hi; v += step {
+			mask |= 1 << v
+		}
+	}
+	return mask, nil
+}
+
+func cronValue(s string, lay cronField) (int, error) {
+	for i, name := range lay.names {
+		if name != "" && strings.EqualFold(s, name) {
+			return i, nil
+		}
+	}
+	v, err := strconv.Atoi(s)
+	if err != nil || v < lay.min || v > lay.max {
+		return 0, fmt.Errorf("value %q out of range %d-%d", s, lay.min, lay.max)
+	}
+	return v, nil
+}
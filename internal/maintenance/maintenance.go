@@ -0,0 +1,100 @@
+// Package maintenance decides which components are in a planned maintenance
+// window or silenced on demand, and what to report for them meanwhile.
+package maintenance
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/goccy/go-yaml"
+)
+
+// Window is one maintenance period: either recurring (Schedule + Duration)
+// or absolute (From..To). Status is reported for covered components while
+// it is active; nil means they are not posted at all.
+type Window struct {
+	Name       string
+	Components []string // empty covers every component
+	Schedule   *Schedule
+	Location   *time.Location
+	Duration   time.Duration
+	From, To   time.Time
+	Status     *int
+}
+
+// Active reports whether the window covers now and, if so, when it ends.
+func (w Window) Active(now time.Time) (time.Time, bool) {
+	if w.Schedule != nil {
+		loc := w.Location
+		if loc == nil {
+			loc = time.Local
+		}
+		start, ok := w.Schedule.Last(now.In(loc), w.Duration)
+		if !ok {
+			return time.Time{}, false
+		}
+		return start.Add(w.Duration), true
+	}
+	if (w.From.IsZero() || !now.Before(w.From)) && now.Before(w.To) {
+		return w.To, true
+	}
+	return time.Time{}, false
+}
+
+// Covers reports whether comp is affected by the window.
+func (w Window) Covers(comp string) bool {
+	return len(w.Components) == 0 || slices.Contains(w.Components, comp)
+}
+
+// Silence is an on-demand entry of the silence file.
+type Silence struct {
+	Components []string  `yaml:"components"`
+	Until      time.Time `yaml:"until"`
+	Status     *int      `yaml:"status"`
+	Reason     string    `yaml:"reason"`
+}
+
+// LoadSilences reads the silence file, a YAML (or JSON) list of Silence
+// entries. A missing file means no silences.
+func LoadSilences(path string) ([]Silence, error) {
+	if path == "" {
+		return nil, nil
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var list []Silence
+	if unErr := yaml.Unmarshal(data, &list); unErr != nil {
+		return nil, fmt.Errorf("silence file %s: %w", path, unErr)
+	}
+	return list, nil
+}
+
+// Name identifies the silence by its content, so that it keeps its name when
+// other entries of the file are added or removed: the components ("*" for
+// every component), the end and the reason.
+func (s Silence) Name() string {
+	comps := "*"
+	if len(s.Components) > 0 {
+		comps = strings.Join(s.Components, ",")
+	}
+	name := "silence " + comps + " until " + s.Until.Format(time.RFC3339)
+	if s.Reason != "" {
+		name += ": " + s.Reason
+	}
+	return name
+}
+
+// Window turns a silence into a window ending at Until.
+func (s Silence) Window(name string) Window {
+	return Window{Name: name, Components: s.Components, To: s.Until, Status: s.Status}
+}
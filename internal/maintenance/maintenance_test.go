@@ -0,0 +1,154 @@
+package maintenance
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestParseCron(t *testing.T) {
+	at := func(s string) time.Time {
+		t.Helper()
+		v, err := time.Parse("2006-01-02 15:04", s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return v
+	}
+	cases := []struct {
+		expr string
+		t    string
+		want bool
+	}{
+		{"0 2 * * SUN", "2026-11-01 02:00", true}, // a Sunday
+		{"0 2 * * SUN", "2026-11-02 02:00", false},
+		{"0 2 * * 7", "2026-11-01 02:00", true},
+		{"*/15 9-17 * * MON-FRI", "2026-11-02 09:45", true},
+		{"*/15 9-17 * * MON-FRI", "2026-11-02 09:50", false},
+		{"*/15 9-17 * * MON-FRI", "2026-11-02 18:00", false},
+		{"30 3 1 JAN,jul *", "2026-07-01 03:30", true},
+		{"30 3 1 JAN,jul *", "2026-08-01 03:30", false},
+		{"0 0 13 * FRI", "2026-11-13 00:00", true}, // dom or dow
+		{"0 0 13 * FRI", "2026-11-06 00:00", true},
+		{"0 0 13 * FRI", "2026-11-07 00:00", false},
+		{"5/20 * * * *", "2026-11-07 00:45", true},
+	}
+	for _, tc := range cases {
+		s, err := ParseCron(tc.expr)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.expr, err)
+		}
+		if got := s.Matches(at(tc.t)); got != tc.want {
+			t.Errorf("%q at %s: want %v, got %v", tc.expr, tc.t, tc.want, got)
+		}
+	}
+	for _, bad := range []string{"", "* * * *", "60 * * * *", "* * * * FUNDAY", "*/0 * * * *", "5-1 * * * *"} {
+		if _, err := ParseCron(bad); err == nil {
+			t.Errorf("%q accepted", bad)
+		}
+	}
+}
+
+func TestScheduleLast(t *testing.T) {
+	// lastByMinute is the plain walk Last has to agree with
+	lastByMinute := func(s Schedule, now time.Time, lookback time.Duration) (time.Time, bool) {
+		for t := now.Truncate(time.Minute); t.After(now.Add(-lookback)); t = t.Add(-time.Minute) {
+			if s.Matches(t) {
+				return t, true
+			}
+		}
+		return time.Time{}, false
+	}
+	locs := []*time.Location{time.UTC, time.FixedZone("IST", 5*3600+1800)}
+	if ny, err := time.LoadLocation("America/New_York"); err == nil {
+		locs = append(locs, ny)
+	}
+	exprs := []string{
+		"0 2 * * SUN", "*/15 9-17 * * MON-FRI", "30 3 1 JAN,jul *", "0 0 13 * FRI", "59 23 * * *", "0 0 1 1 *",
+	}
+	lookbacks := []time.Duration{time.Minute, 2 * time.Hour, 7 * 24 * time.Hour, 400 * 24 * time.Hour}
+	base := time.Date(2026, 11, 1, 5, 17, 30, 0, time.UTC) // around the end of US summer time
+	for _, expr := range exprs {
+		s, err := ParseCron(expr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, loc := range locs {
+			for _, lb := range lookbacks {
+				for _, off := range []time.Duration{0, -3 * time.Hour, 41 * time.Minute, 200 * 24 * time.Hour} {
+					now := base.Add(off).In(loc)
+					want, wantOK := lastByMinute(s, now, lb)
+					got, ok := s.Last(now, lb)
+					if ok != wantOK || !got.Equal(want) {
+						t.Errorf("%q at %v back %v: want %v %v, got %v %v", expr, now, lb, want, wantOK, got, ok)
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestWindowActive(t *testing.T) {
+	sched, err := ParseCron("0 2 * * *")
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := Window{Schedule: &sched, Duration: 2 * time.Hour, Location: time.UTC}
+	day := time.Date(2026, 11, 1, 0, 0, 0, 0, time.UTC)
+	if _, ok := w.Active(day.Add(time.Hour + 59*time.Minute)); ok {
+		t.Fatalf("active before start")
+	}
+	if until, ok := w.Active(day.Add(3 * time.Hour)); !ok || !until.Equal(day.Add(4*time.Hour)) {
+		t.Fatalf("want active until 04:00, got %v %v", until, ok)
+	}
+	if _, ok := w.Active(day.Add(4 * time.Hour)); ok {
+		t.Fatalf("active after end")
+	}
+
+	abs := Window{From: day, To: day.Add(time.Hour), Components: []string{"1"}}
+	if _, ok := abs.Active(day.Add(30 * time.Minute)); !ok {
+		t.Fatalf("absolute window not active inside range")
+	}
+	if _, ok := abs.Active(day.Add(-time.Minute)); ok {
+		t.Fatalf("absolute window active before from")
+	}
+	if !abs.Covers("1") || abs.Covers("2") || !(Window{}).Covers("2") {
+		t.Fatalf("covers")
+	}
+}
+
+func TestLoadSilences(t *testing.T) {
+	dir := t.TempDir()
+	if list, err := LoadSilences(filepath.Join(dir, "missing.yaml")); err != nil || list != nil {
+		t.Fatalf("missing file: %v %v", list, err)
+	}
+	fn := filepath.Join(dir, "silence.yaml")
+	data := `
+- components: ["101", "102"]
+  until: 2026-11-01T23:00:00Z
+  reason: "disk swap"
+- until: 2026-11-02T01:00:00Z
+  status: 0
+`
+	if err := os.WriteFile(fn, []byte(data), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	list, err := LoadSilences(fn)
+	if err != nil || len(list) != 2 {
+		t.Fatalf("load: %v %+v", err, list)
+	}
+	if list[0].Status != nil || list[0].Reason != "disk swap" || len(list[0].Components) != 2 {
+		t.Fatalf("unexpected first entry %+v", list[0])
+	}
+	until := time.Date(2026, 11, 2, 1, 0, 0, 0, time.UTC)
+	if list[1].Status == nil || *list[1].Status != 0 || !list[1].Until.Equal(until) {
+		t.Fatalf("unexpected second entry %+v", list[1])
+	}
+	if err := os.WriteFile(fn, []byte("{not: [yaml"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadSilences(fn); err == nil {
+		t.Fatalf("broken file accepted")
+	}
+}
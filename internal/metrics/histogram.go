@@ -0,0 +1,111 @@
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// DefaultBuckets are latency buckets in seconds, from 5ms to 10s.
+func DefaultBuckets() []float64 {
+	return []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+}
+
+// HistogramVec is a histogram family with a fixed set of label names.
+type HistogramVec struct {
+	name, help string
+	labels     []string
+	buckets    []float64
+
+	mu     sync.RWMutex
+	series map[string]*Histogram
+}
+
+// Histogram counts observations into cumulative buckets.
+type Histogram struct {
+	values []string
+
+	mu     sync.Mutex
+	counts []uint64 // per bucket, not cumulative; the last one is +Inf
+	sum    float64
+	count  uint64
+}
+
+// Histogram registers a histogram family with the given upper bounds.
+func (r *Registry) Histogram(name, help string, buckets []float64, labels ...string) *HistogramVec {
+	b := slices.Clone(buckets)
+	slices.Sort(b)
+	h := &HistogramVec{name: name, help: help, labels: labels, buckets: b, series: make(map[string]*Histogram)}
+	r.add(h)
+	return h
+}
+
+// With returns the histogram for the given label values.
+func (h *HistogramVec) With(values ...string) *Histogram {
+	if len(values) != len(h.labels) {
+		panic(fmt.Sprintf("metrics: %s wants %d label values, got %d", h.name, len(h.labels), len(values)))
+	}
+	key := strings.Join(values, labelSep)
+	h.mu.RLock()
+	s, ok := h.series[key]
+	h.mu.RUnlock()
+	if ok {
+		return s
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if s, ok = h.series[key]; !ok {
+		s = &Histogram{values: slices.Clone(values), counts: make([]uint64, len(h.buckets)+1)}
+		h.series[key] = s
+	}
+	return s
+}
+
+// observe adds one observation; it goes into the first bucket with v <= le.
+func (s *Histogram) observe(buckets []float64, v float64) {
+	i, _ := slices.BinarySearch(buckets, v)
+	s.mu.Lock()
+	s.counts[i]++
+	s.sum += v
+	s.count++
+	s.mu.Unlock()
+}
+
+// Observe records v in the histogram of the given label values.
+func (h *HistogramVec) Observe(v float64, values ...string) {
+	h.With(values...).observe(h.buckets, v)
+}
+
+func (h *HistogramVec) write(w *bufio.Writer) {
+	h.mu.RLock()
+	keys := make([]string, 0, len(h.series))
+	for k := range h.series {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	list := make([]*Histogram, 0, len(keys))
+	for _, k := range keys {
+		list = append(list, h.series[k])
+	}
+	h.mu.RUnlock()
+
+	header(w, h.name, h.help, "histogram")
+	labels := append(slices.Clone(h.labels), "le")
+	for _, s := range list {
+		s.mu.Lock()
+		counts := slices.Clone(s.counts)
+		sum, count := s.sum, s.count
+		s.mu.Unlock()
+
+		var cum uint64
+		for i, le := range append(slices.Clone(h.buckets), math.Inf(1)) {
+			cum += counts[i]
+			sample(w, h.name+"_bucket", labels, append(slices.Clone(s.values), formatFloat(le)), float64(cum))
+		}
+		sample(w, h.name+"_sum", h.labels, s.values, sum)
+		sample(w, h.name+"_count", h.labels, s.values, float64(count))
+	}
+}
@@ -0,0 +1,249 @@
+// Package metrics is a small registry of counters, gauges and histograms
+// written in the Prometheus text exposition format (version 0.0.4).
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// ContentType is the media type of WriteText output.
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+const (
+	labelSep     = "\xff"
+	textfileMode = 0o644
+)
+
+// Registry holds metric families in registration order.
+type Registry struct {
+	mu       sync.Mutex
+	families []family
+}
+
+type family interface {
+	write(w *bufio.Writer)
+}
+
+func NewRegistry() *Registry { return &Registry{} }
+
+// WriteText writes every family in the text exposition format.
+func (r *Registry) WriteText(w io.Writer) error {
+	r.mu.Lock()
+	fams := slices.Clone(r.families)
+	r.mu.Unlock()
+
+	bw := bufio.NewWriter(w)
+	for _, f := range fams {
+		f.write(bw)
+	}
+	return bw.Flush()
+}
+
+// WriteFile writes the registry to path for the node_exporter textfile
+// collector. The file is replaced atomically so a scrape never sees a
+// partial write.
+func (r *Registry) WriteFile(path string) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if err = r.WriteText(tmp); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err = tmp.Chmod(textfileMode); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// Value is a float64 that can be updated concurrently.
+type Value struct{ bits atomic.Uint64 }
+
+func (v *Value) Set(x float64) { v.bits.Store(math.Float64bits(x)) }
+
+func (v *Value) Add(x float64) {
+	for {
+		old := v.bits.Load()
+		if v.bits.CompareAndSwap(old, math.Float64bits(math.Float64frombits(old)+x)) {
+			return
+		}
+	}
+}
+
+func (v *Value) Inc() { v.Add(1) }
+
+func (v *Value) Get() float64 { return math.Float64frombits(v.bits.Load()) }
+
+// Vec is a counter or gauge family with a fixed set of label names.
+type Vec struct {
+	name, help, typ string
+	labels          []string
+
+	mu     sync.RWMutex
+	series map[string]*series
+}
+
+type series struct {
+	values []string
+	v      Value
+}
+
+// Counter registers a monotonically increasing family.
+func (r *Registry) Counter(name, help string, labels ...string) *Vec {
+	return r.vec(name, help, "counter", labels)
+}
+
+// Gauge registers a family whose values go up and down.
+func (r *Registry) Gauge(name, help string, labels ...string) *Vec {
+	return r.vec(name, help, "gauge", labels)
+}
+
+// With returns the series for the given label values, creating it at 0.
+func (v *Vec) With(values ...string) *Value {
+	if len(values) != len(v.labels) {
+		panic(fmt.Sprintf("metrics: %s wants %d label values, got %d", v.name, len(v.labels), len(values)))
+	}
+	key := strings.Join(values, labelSep)
+	v.mu.RLock()
+	s, ok := v.series[key]
+	v.mu.RUnlock()
+	if ok {
+		return &s.v
+	}
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if s, ok = v.series[key]; !ok {
+		s = &series{values: slices.Clone(values)}
+		v.series[key] = s
+	}
+	return &s.v
+}
+
+// Reset drops every series, e.g. before a gauge family is refilled.
+func (v *Vec) Reset() {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.series = make(map[string]*series)
+}
+
+func (v *Vec) write(w *bufio.Writer) {
+	v.mu.RLock()
+	keys := make([]string, 0, len(v.series))
+	for k := range v.series {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	list := make([]*series, 0, len(keys))
+	for _, k := range keys {
+		list = append(list, v.series[k])
+	}
+	v.mu.RUnlock()
+
+	header(w, v.name, v.help, v.typ)
+	for _, s := range list {
+		sample(w, v.name, v.labels, s.values, s.v.Get())
+	}
+}
+
+// Func is a family whose samples are produced on every scrape.
+type Func struct {
+	name, help, typ string
+	labels          []string
+	collect         func(emit func(value float64, labelValues ...string))
+}
+
+// GaugeFunc registers a gauge family computed at scrape time; collect calls
+// emit once per series.
+func (r *Registry) GaugeFunc(
+	name, help string,
+	labels []string,
+	collect func(emit func(value float64, labelValues ...string)),
+) {
+	r.add(&Func{name: name, help: help, typ: "gauge", labels: labels, collect: collect})
+}
+
+func (f *Func) write(w *bufio.Writer) {
+	header(w, f.name, f.help, f.typ)
+	f.collect(func(value float64, values ...string) {
+		sample(w, f.name, f.labels, values, value)
+	})
+}
+
+func (r *Registry) add(f family) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.families = append(r.families, f)
+}
+
+func (r *Registry) vec(name, help, typ string, labels []string) *Vec {
+	v := &Vec{name: name, help: help, typ: typ, labels: labels, series: make(map[string]*series)}
+	r.add(v)
+	return v
+}
+
+func header(w *bufio.Writer, name, help, typ string) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, escapeHelp(help), name, typ)
+}
+
+func sample(w *bufio.Writer, name string, labels, values []string, v float64) {
+	w.WriteString(name)
+	writeLabels(w, labels, values)
+	w.WriteByte(' ')
+	w.WriteString(formatFloat(v))
+	w.WriteByte('\n')
+}
+
+func writeLabels(w *bufio.Writer, labels, values []string) {
+	if len(labels) == 0 {
+		return
+	}
+	w.WriteByte('{')
+	for i, l := range labels {
+		if i > 0 {
+			w.WriteByte(',')
+		}
+		val := ""
+		if i < len(values) {
+			val = values[i]
+		}
+		fmt.Fprintf(w, "%s=\"%s\"", l, escapeLabel(val))
+	}
+	w.WriteByte('}')
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	default:
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	}
+}
+
+func escapeHelp(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "\n", `\n`).Replace(s)
+}
+
+func escapeLabel(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`).Replace(s)
+}
@@ -0,0 +1,75 @@
+package metrics
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRegistry_WriteText(t *testing.T) {
+	r := NewRegistry()
+	req := r.Counter("requests_total", "Requests by code.", "code")
+	req.With("200").Inc()
+	req.With("200").Add(2)
+	req.With("500").Inc()
+	up := r.Gauge("up", "Whether it is up.")
+	up.With().Set(1)
+	r.GaugeFunc("info", "Build info.", []string{"version"}, func(emit func(float64, ...string)) {
+		emit(1, `v1 "quoted"\`)
+	})
+
+	var b strings.Builder
+	if err := r.WriteText(&b); err != nil {
+		t.Fatal(err)
+	}
+	want := `# HELP requests_total Requests by code.
+# TYPE requests_total counter
+requests_total{code="200"} 3
+requests_total{code="500"} 1
+# HELP up Whether it is up.
+# TYPE up gauge
+up 1
+# HELP info Build info.
+# TYPE info gauge
+info{version="v1 \"quoted\"\\"} 1
+`
+	if b.String() != want {
+		t.Fatalf("got:\n%s\nwant:\n%s", b.String(), want)
+	}
+
+	req.Reset()
+	b.Reset()
+	_ = r.WriteText(&b)
+	if strings.Contains(b.String(), "requests_total{") {
+		t.Fatalf("series survived Reset:\n%s", b.String())
+	}
+}
+
+func TestHistogram_CumulativeBuckets(t *testing.T) {
+	r := NewRegistry()
+	h := r.Histogram("latency_seconds", "Latency.", []float64{1, 0.1}, "op")
+	for _, v := range []float64{0.05, 0.1, 0.5, 3} {
+		h.Observe(v, "get")
+	}
+
+	path := filepath.Join(t.TempDir(), "out.prom")
+	if err := r.WriteFile(path); err != nil {
+		t.Fatal(err)
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `# HELP latency_seconds Latency.
+# TYPE latency_seconds histogram
+latency_seconds_bucket{op="get",le="0.1"} 2
+latency_seconds_bucket{op="get",le="1"} 3
+latency_seconds_bucket{op="get",le="+Inf"} 4
+latency_seconds_sum{op="get"} 3.65
+latency_seconds_count{op="get"} 4
+`
+	if string(b) != want {
+		t.Fatalf("got:\n%s\nwant:\n%s", b, want)
+	}
+}
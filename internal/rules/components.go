@@ -0,0 +1,124 @@
+package rules
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/arenadata/ad-status-sender/internal/schema"
+)
+
+// ComponentRef names a component of the host by its service and component
+// names instead of its ADCM ID, which changes whenever the cluster is
+// recreated. Refs are turned into IDs by Resolve.
+type ComponentRef struct {
+	Service   string `json:"service"   yaml:"service"`
+	Component string `json:"component" yaml:"component"`
+}
+
+// ParseComponentRef reads a ref written as "service/component".
+func ParseComponentRef(s string) (ComponentRef, bool) {
+	svc, comp, ok := strings.Cut(s, "/")
+	if !ok || strings.TrimSpace(svc) == "" || strings.TrimSpace(comp) == "" {
+		return ComponentRef{}, false
+	}
+	return ComponentRef{Service: strings.TrimSpace(svc), Component: strings.TrimSpace(comp)}, true
+}
+
+func (c ComponentRef) String() string { return c.Service + "/" + c.Component }
+
+// Resolve returns a copy of r with the component_refs of every rule looked
+// up with ids and added to its components, and aggregation policies keyed
+// "service/component" re-keyed by ID. It also returns the refs ids has no
+// entry for, sorted. A rule whose refs are all unknown still runs but
+// feeds no component; a maintenance window in that state is dropped rather
+// than left to cover every component.
+func (r Rules) Resolve(ids map[ComponentRef]string) (Rules, []ComponentRef) {
+	missing := make(map[ComponentRef]bool)
+	out := r
+	out.Systemd = resolveRules(r.Systemd, ids, missing)
+	out.Docker = resolveRules(r.Docker, ids, missing)
+	out.Exec = resolveRules(r.Exec, ids, missing)
+	out.TCP = resolveRules(r.TCP, ids, missing)
+	out.HTTP = resolveRules(r.HTTP, ids, missing)
+	out.Process = resolveRules(r.Process, ids, missing)
+	out.Maintenance = slices.DeleteFunc(resolveRules(r.Maintenance, ids, missing), func(mw MaintenanceWindow) bool {
+		return len(mw.ComponentRefs) > 0 && len(mw.Components) == 0
+	})
+
+	if len(r.Aggregation.Components) > 0 {
+		out.Aggregation.Components = make(map[string]Policy, len(r.Aggregation.Components))
+		for comp, p := range r.Aggregation.Components {
+			ref, isRef := ParseComponentRef(comp)
+			if !isRef {
+				out.Aggregation.Components[comp] = p
+				continue
+			}
+			if id, ok := ids[ref]; ok {
+				out.Aggregation.Components[id] = p
+			} else {
+				missing[ref] = true
+			}
+		}
+	}
+
+	return out, slices.SortedFunc(maps.Keys(missing), func(a, b ComponentRef) int {
+		return strings.Compare(a.String(), b.String())
+	})
+}
+
+// referrer is a rule feeding components given by ID and by ref.
+type referrer[T any] interface {
+	*T
+	components() (*[]string, []ComponentRef)
+}
+
+func resolveRules[T any, P referrer[T]](rules []T, ids map[ComponentRef]string, missing map[ComponentRef]bool) []T {
+	out := slices.Clone(rules)
+	for i := range out {
+		comps, refs := P(&out[i]).components()
+		if len(refs) == 0 {
+			continue
+		}
+		resolved := slices.Clone(*comps)
+		for _, ref := range refs {
+			id, ok := ids[ref]
+			switch {
+			case !ok:
+				missing[ref] = true
+			case !slices.Contains(resolved, id):
+				resolved = append(resolved, id)
+			}
+		}
+		*comps = resolved
+	}
+	return out
+}
+
+// checkComponents requires component IDs, refs or both, and complete refs.
+func checkComponents(chk *schema.Checker, path string, ids []string, refs []ComponentRef) {
+	if len(ids) > 0 || len(refs) == 0 {
+		chk.Components(path+".components", ids)
+	}
+	checkRefs(chk, path, refs)
+}
+
+func checkRefs(chk *schema.Checker, path string, refs []ComponentRef) {
+	for i, ref := range refs {
+		if strings.TrimSpace(ref.Service) == "" || strings.TrimSpace(ref.Component) == "" {
+			chk.Addf(fmt.Sprintf("%s.component_refs[%d]", path, i), "service and component are required")
+		}
+	}
+}
+
+func (r *RuleSystemd) components() (*[]string, []ComponentRef) { return &r.Components, r.ComponentRefs }
+func (r *RuleDocker) components() (*[]string, []ComponentRef)  { return &r.Components, r.ComponentRefs }
+func (r *RuleExec) components() (*[]string, []ComponentRef)    { return &r.Components, r.ComponentRefs }
+func (r *RuleTCP) components() (*[]string, []ComponentRef)     { return &r.Components, r.ComponentRefs }
+func (r *RuleHTTP) components() (*[]string, []ComponentRef)    { return &r.Components, r.ComponentRefs }
+func (r *RuleProcess) components() (*[]string, []ComponentRef) { return &r.Components, r.ComponentRefs }
+
+func (mw *MaintenanceWindow) components() (*[]string, []ComponentRef) {
+	return &mw.Components, mw.ComponentRefs
+}
@@ -0,0 +1,75 @@
+package rules
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/goccy/go-yaml"
+)
+
+// Threshold is either a number of consecutive results (fail_after: 3) or
+// how long a new status has to persist (fail_after: 30s). The zero value
+// applies changes immediately.
+type Threshold struct {
+	Count    int
+	Duration time.Duration
+}
+
+func (t *Threshold) UnmarshalYAML(b []byte) error {
+	var s string
+	if err := yaml.Unmarshal(b, &s); err != nil {
+		return fmt.Errorf("threshold: %w", err)
+	}
+	s = strings.TrimSpace(s)
+	if s == "" {
+		*t = Threshold{}
+		return nil
+	}
+	if n, err := strconv.Atoi(s); err == nil {
+		if n < 0 {
+			return fmt.Errorf("threshold must be >= 0, got %d", n)
+		}
+		*t = Threshold{Count: n}
+		return nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return fmt.Errorf("threshold %q is neither a count nor a duration", s)
+	}
+	if d < 0 {
+		return fmt.Errorf("threshold must be >= 0, got %s", s)
+	}
+	*t = Threshold{Duration: d}
+	return nil
+}
+
+// MarshalJSON writes the threshold the way it is configured: a count, a
+// duration string or 0.
+func (t Threshold) MarshalJSON() ([]byte, error) {
+	if t.Duration > 0 {
+		return []byte(strconv.Quote(t.Duration.String())), nil
+	}
+	return []byte(strconv.Itoa(t.Count)), nil
+}
+
+// Reached reports whether a change seen n times in a row, first at since,
+// may be applied at now.
+func (t Threshold) Reached(n int, since, now time.Time) bool {
+	switch {
+	case t.Count > 0:
+		return n >= t.Count
+	case t.Duration > 0:
+		return now.Sub(since) >= t.Duration
+	default:
+		return true
+	}
+}
+
+// Debounce holds back status changes of a rule's targets: a failure is only
+// reported after FailAfter, a recovery after RecoverAfter.
+type Debounce struct {
+	FailAfter    Threshold `json:"fail_after"    yaml:"fail_after"`
+	RecoverAfter Threshold `json:"recover_after" yaml:"recover_after"`
+}
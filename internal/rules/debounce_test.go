@@ -0,0 +1,63 @@
+package rules
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestDebounceThresholds(t *testing.T) {
+	data := []byte(`
+systemd:
+  - unit: "hdfs-datanode.service"
+    components: ["1"]
+    fail_after: 3
+    recover_after: "30s"
+exec:
+  - name: "quorum"
+    command: "/bin/true"
+    components: ["2"]
+    fail_after: 1m
+`)
+	fn := filepath.Join(t.TempDir(), "rules.yaml")
+	if err := os.WriteFile(fn, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	r, err := Load(fn)
+	if err != nil {
+		t.Fatalf("load err: %v", err)
+	}
+	sd := r.Systemd[0].Debounce
+	if sd.FailAfter != (Threshold{Count: 3}) || sd.RecoverAfter != (Threshold{Duration: 30 * time.Second}) {
+		t.Fatalf("unexpected systemd debounce %+v", sd)
+	}
+	ex := r.Exec[0].Debounce
+	if ex.FailAfter != (Threshold{Duration: time.Minute}) || ex.RecoverAfter != (Threshold{}) {
+		t.Fatalf("unexpected exec debounce %+v", ex)
+	}
+
+	for _, bad := range []string{"-1", "soon", "-5s"} {
+		data := []byte("systemd:\n  - unit: a\n    fail_after: \"" + bad + "\"\n")
+		if err := os.WriteFile(fn, data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := Load(fn); err == nil {
+			t.Fatalf("threshold %q accepted", bad)
+		}
+	}
+}
+
+func TestThresholdReached(t *testing.T) {
+	t0 := time.Unix(0, 0)
+	if !(Threshold{}).Reached(1, t0, t0) {
+		t.Fatalf("zero threshold must apply immediately")
+	}
+	if (Threshold{Count: 3}).Reached(2, t0, t0.Add(time.Hour)) || !(Threshold{Count: 3}).Reached(3, t0, t0) {
+		t.Fatalf("count threshold")
+	}
+	d := Threshold{Duration: 30 * time.Second}
+	if d.Reached(5, t0, t0.Add(29*time.Second)) || !d.Reached(1, t0, t0.Add(30*time.Second)) {
+		t.Fatalf("duration threshold")
+	}
+}
@@ -0,0 +1,93 @@
+package rules
+
+import (
+	"fmt"
+	"maps"
+	"reflect"
+	"slices"
+)
+
+const percent = 100
+
+// Change summarizes a reload by rule identity (see LoadAll): which rules
+// are new, gone or differ, and how many there are before and after.
+type Change struct {
+	Added   []string
+	Removed []string
+	Changed []string
+	Before  int
+	After   int
+}
+
+// Diff compares the rules before and after a reload.
+func Diff(before, after Rules) Change {
+	old, cur := before.entries(), after.entries()
+	ch := Change{Before: len(old), After: len(cur)}
+	for _, key := range slices.Sorted(maps.Keys(cur)) {
+		prev, ok := old[key]
+		switch {
+		case !ok:
+			ch.Added = append(ch.Added, key)
+		case !reflect.DeepEqual(prev, cur[key]):
+			ch.Changed = append(ch.Changed, key)
+		}
+	}
+	for _, key := range slices.Sorted(maps.Keys(old)) {
+		if _, ok := cur[key]; !ok {
+			ch.Removed = append(ch.Removed, key)
+		}
+	}
+	return ch
+}
+
+// Empty reports whether the reload changes nothing.
+func (c Change) Empty() bool {
+	return len(c.Added) == 0 && len(c.Removed) == 0 && len(c.Changed) == 0
+}
+
+// DropPercent is the share of the previous rules that the reload removes.
+func (c Change) DropPercent() float64 {
+	if c.Before == 0 {
+		return 0
+	}
+	return float64(len(c.Removed)) * percent / float64(c.Before)
+}
+
+// entries maps every rule of r to its identity. Rules sharing an identity
+// within one file are told apart by a counter.
+func (r Rules) entries() map[string]any {
+	out := make(map[string]any)
+	add := func(key string, rule any) {
+		k := key
+		for n := 2; ; n++ {
+			if _, dup := out[k]; !dup {
+				break
+			}
+			k = fmt.Sprintf("%s #%d", key, n)
+		}
+		out[k] = rule
+	}
+	addRules(add, r.Systemd)
+	addRules(add, r.Docker)
+	addRules(add, r.Exec)
+	addRules(add, r.TCP)
+	addRules(add, r.HTTP)
+	addRules(add, r.Process)
+	addRules(add, r.Maintenance)
+	if r.Host != nil {
+		add("host", *r.Host)
+	}
+	if r.Aggregation.Default != (Policy{}) {
+		add("aggregation default", r.Aggregation.Default)
+	}
+	for comp, p := range r.Aggregation.Components {
+		add("aggregation of component "+comp, p)
+	}
+	return out
+}
+
+func addRules[T any, P sourced[T]](add func(string, any), rules []T) {
+	for i := range rules {
+		add(P(&rules[i]).key(), rules[i])
+	}
+}
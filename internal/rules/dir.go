@@ -0,0 +1,249 @@
+package rules
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+)
+
+// SkippedError lists the files and rules LoadAll left out because they
+// failed to load or clash with a rule of an earlier file. The rules returned
+// along with it are still usable.
+type SkippedError struct {
+	Errs []error
+}
+
+func (e *SkippedError) Error() string { return errors.Join(e.Errs...).Error() }
+
+func (e *SkippedError) Unwrap() []error { return e.Errs }
+
+// Files lists what LoadAll reads: path, if set, then the *.yaml and *.yml
+// files of dir in lexical order.
+func Files(path, dir string) ([]string, error) {
+	var files []string
+	if path != "" {
+		files = append(files, path)
+	}
+	if dir == "" {
+		return files, nil
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range entries {
+		name := filepath.Join(dir, e.Name())
+		if e.Type().IsRegular() && isRulesFile(name) && filepath.Clean(name) != filepath.Clean(path) {
+			files = append(files, name)
+		}
+	}
+	return files, nil
+}
+
+// LoadAll merges path and the drop-in files of dir, tagging every rule with
+// the file it came from. A file that fails to load is skipped, as is a rule
+// whose target (systemd unit, rule name, ...) an earlier file already
+// covers; both are reported in a *SkippedError. If no file loads at all,
+// the error is returned alone.
+func LoadAll(path, dir string) (Rules, error) {
+	files, err := Files(path, dir)
+	if err != nil {
+		return Rules{}, err
+	}
+	m := merger{owners: make(map[string]owner)}
+	loaded := 0
+	for _, file := range files {
+		r, loadErr := Load(file)
+		if loadErr != nil {
+			m.errs = append(m.errs, loadErr)
+			m.out.files = append(m.out.files, loadedFile{name: file})
+			continue
+		}
+		m.merge(r, file)
+		m.out.files = append(m.out.files, loadedFile{name: file, rules: &r})
+		loaded++
+	}
+	switch {
+	case len(m.errs) == 0:
+		return m.out, nil
+	case loaded == 0:
+		return Rules{}, errors.Join(m.errs...)
+	default:
+		return m.out, &SkippedError{Errs: m.errs}
+	}
+}
+
+// KeepBroken merges the files of r, as returned by LoadAll, once more and
+// takes every file that failed to load from prev instead, so that a broken
+// edit leaves the rules of its file as they were. It returns the files
+// whose rules were kept; without any, r is returned as is.
+func (r Rules) KeepBroken(prev Rules) (Rules, []string) {
+	before := make(map[string]*Rules, len(prev.files))
+	for _, f := range prev.files {
+		if f.rules != nil {
+			before[f.name] = f.rules
+		}
+	}
+	var kept []string
+	files := slices.Clone(r.files)
+	for i, f := range files {
+		if f.rules == nil && before[f.name] != nil {
+			files[i].rules = before[f.name]
+			kept = append(kept, f.name)
+		}
+	}
+	if len(kept) == 0 {
+		return r, nil
+	}
+	m := merger{owners: make(map[string]owner)}
+	for _, f := range files {
+		if f.rules != nil {
+			m.merge(*f.rules, f.name)
+		}
+	}
+	m.out.files = files
+	return m.out, kept
+}
+
+func isRulesFile(name string) bool {
+	ext := strings.ToLower(filepath.Ext(name))
+	return (ext == ".yaml" || ext == ".yml") && !strings.HasPrefix(filepath.Base(name), ".")
+}
+
+// loadedFile is what LoadAll read from one file; rules is nil when the
+// file failed to load.
+type loadedFile struct {
+	name  string
+	rules *Rules
+}
+
+type owner struct {
+	file string
+	rule any
+}
+
+type merger struct {
+	out    Rules
+	owners map[string]owner
+	errs   []error
+}
+
+// claim registers rule under key for file. It reports false, and records
+// why, when an earlier file already has key. Rules of one file never clash:
+// a file may check the same target more than once, e.g. for different
+// components.
+func (m *merger) claim(key, file string, rule any) bool {
+	prev, ok := m.owners[key]
+	if !ok {
+		m.owners[key] = owner{file: file, rule: rule}
+		return true
+	}
+	if prev.file == file {
+		return true
+	}
+	what := "conflicts with"
+	if reflect.DeepEqual(prev.rule, rule) {
+		what = "duplicates"
+	}
+	m.errs = append(m.errs, fmt.Errorf("%s: %s %s the one in %s, skipped", file, key, what, prev.file))
+	return false
+}
+
+func (m *merger) merge(r Rules, file string) {
+	m.out.Systemd = mergeRules(m, m.out.Systemd, r.Systemd, file)
+	m.out.Docker = mergeRules(m, m.out.Docker, r.Docker, file)
+	m.out.Exec = mergeRules(m, m.out.Exec, r.Exec, file)
+	m.out.TCP = mergeRules(m, m.out.TCP, r.TCP, file)
+	m.out.HTTP = mergeRules(m, m.out.HTTP, r.HTTP, file)
+	m.out.Process = mergeRules(m, m.out.Process, r.Process, file)
+	m.out.Maintenance = mergeRules(m, m.out.Maintenance, r.Maintenance, file)
+
+	if r.Host != nil && m.claim("host", file, *r.Host) {
+		h := *r.Host
+		h.Source = file
+		m.out.Host = &h
+	}
+	if r.Aggregation.Default != (Policy{}) && m.claim("aggregation default", file, r.Aggregation.Default) {
+		m.out.Aggregation.Default = r.Aggregation.Default
+	}
+	for _, comp := range slices.Sorted(maps.Keys(r.Aggregation.Components)) {
+		p := r.Aggregation.Components[comp]
+		if !m.claim("aggregation of component "+comp, file, p) {
+			continue
+		}
+		if m.out.Aggregation.Components == nil {
+			m.out.Aggregation.Components = make(map[string]Policy)
+		}
+		m.out.Aggregation.Components[comp] = p
+	}
+}
+
+// sourced is a rule that can be told apart from the other rules of its kind
+// and tagged with its file.
+type sourced[T any] interface {
+	*T
+	key() string
+	setSource(file string)
+}
+
+func mergeRules[T any, P sourced[T]](m *merger, dst, src []T, file string) []T {
+	for _, rule := range src {
+		if !m.claim(P(&rule).key(), file, rule) {
+			continue
+		}
+		P(&rule).setSource(file)
+		dst = append(dst, rule)
+	}
+	return dst
+}
+
+// nameOr identifies a rule by its name if it has one, by what it checks
+// otherwise.
+func nameOr(kind, name string, target ...string) string {
+	if name != "" {
+		return fmt.Sprintf("%s rule %q", kind, name)
+	}
+	return fmt.Sprintf("%s rule for %s", kind, strings.Join(slices.DeleteFunc(target, isEmpty), " "))
+}
+
+func isEmpty(s string) bool { return s == "" }
+
+func (r *RuleSystemd) key() string {
+	if r.UnitGlob != "" {
+		return fmt.Sprintf("systemd unit_glob %q", r.UnitGlob)
+	}
+	return fmt.Sprintf("systemd unit %q", r.Unit)
+}
+
+func (r *RuleDocker) key() string {
+	return nameOr("docker", r.Name, strings.Join(r.Containers.Names, ","), strings.Join(r.Containers.Labels, ","))
+}
+
+func (r *RuleExec) key() string {
+	return nameOr("exec", r.Name, r.Command, strings.Join(r.Args, " "))
+}
+
+func (r *RuleTCP) key() string { return nameOr("tcp", r.Name, r.Address, r.Socket) }
+
+func (r *RuleHTTP) key() string { return nameOr("http", r.Name, r.Method, r.URL) }
+
+func (r *RuleProcess) key() string {
+	return nameOr("process", r.Name, r.Process, r.Cmdline, r.User, r.PidFile)
+}
+
+func (mw *MaintenanceWindow) key() string {
+	return nameOr("maintenance", mw.Name, mw.Cron, mw.From, mw.To)
+}
+
+func (r *RuleSystemd) setSource(file string)        { r.Source = file }
+func (r *RuleDocker) setSource(file string)         { r.Source = file }
+func (r *RuleExec) setSource(file string)           { r.Source = file }
+func (r *RuleTCP) setSource(file string)            { r.Source = file }
+func (r *RuleHTTP) setSource(file string)           { r.Source = file }
+func (r *RuleProcess) setSource(file string)        { r.Source = file }
+func (mw *MaintenanceWindow) setSource(file string) { mw.Source = file }
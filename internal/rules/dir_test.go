@@ -0,0 +1,249 @@
+package rules
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func writeRules(t *testing.T, path, data string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLoadAll_MergesDropIns(t *testing.T) {
+	root := t.TempDir()
+	dir := filepath.Join(root, "conf.d")
+	if err := os.Mkdir(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	main := filepath.Join(root, "rules.yaml")
+	writeRules(t, main, `
+systemd:
+  - unit: "nginx.service"
+    components: ["1"]
+aggregation:
+  default: all_ok
+`)
+	writeRules(t, filepath.Join(dir, "20-kafka.yaml"), `
+systemd:
+  - unit: "kafka.service"
+    components: ["2"]
+  - unit: "nginx.service"
+    components: ["1"]
+aggregation:
+  components:
+    "2": any_ok
+`)
+	writeRules(t, filepath.Join(dir, "10-hbase.yml"), `
+systemd:
+  - unit: "hbase-master.service"
+    components: ["3"]
+  - unit: "nginx.service"
+    components: ["9"]
+`)
+	writeRules(t, filepath.Join(dir, "30-broken.yaml"), "systemd:\n  - unit: \"x.service\"\n    componets: [\"4\"]\n")
+	writeRules(t, filepath.Join(dir, "README.md"), "not rules")
+
+	r, err := LoadAll(main, dir)
+	var skipped *SkippedError
+	if !errors.As(err, &skipped) || len(skipped.Errs) != 3 {
+		t.Fatalf("want 3 skipped, got %v", err)
+	}
+	msg := err.Error()
+	for _, want := range []string{
+		filepath.Join(dir, "10-hbase.yml") + `: systemd unit "nginx.service" conflicts with the one in ` + main,
+		filepath.Join(dir, "20-kafka.yaml") + `: systemd unit "nginx.service" duplicates the one in ` + main,
+		filepath.Join(dir, "30-broken.yaml") + ":3:5: ",
+	} {
+		if !strings.Contains(msg, want) {
+			t.Fatalf("missing %q in:\n%s", want, msg)
+		}
+	}
+
+	got := make(map[string]string)
+	for _, rule := range r.Systemd {
+		got[rule.Unit] = rule.Source
+	}
+	want := map[string]string{
+		"nginx.service":        main,
+		"hbase-master.service": filepath.Join(dir, "10-hbase.yml"),
+		"kafka.service":        filepath.Join(dir, "20-kafka.yaml"),
+	}
+	if len(got) != len(want) || len(r.Systemd) != len(want) {
+		t.Fatalf("unexpected rules: %+v", r.Systemd)
+	}
+	for unit, src := range want {
+		if got[unit] != src {
+			t.Fatalf("%s: want source %s, got %q", unit, src, got[unit])
+		}
+	}
+	if r.Aggregation.Default.Mode != PolicyAllOK || r.Aggregation.Components["2"].Mode != PolicyAnyOK {
+		t.Fatalf("aggregation not merged: %+v", r.Aggregation)
+	}
+}
+
+func TestLoadAll_SameFileDuplicates(t *testing.T) {
+	dir := t.TempDir()
+	main := filepath.Join(dir, "rules.yaml")
+	writeRules(t, main, `
+systemd:
+  - unit: "nginx.service"
+    components: ["1"]
+  - unit: "nginx.service"
+    components: ["2"]
+  - unit: "nginx.service"
+    components: ["2"]
+`)
+	dropIn := filepath.Join(dir, "conf.d")
+	if err := os.Mkdir(dropIn, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	writeRules(t, filepath.Join(dropIn, "10-nginx.yaml"), `
+systemd:
+  - unit: "nginx.service"
+    components: ["3"]
+  - unit: "nginx.service"
+    components: ["4"]
+`)
+
+	r, err := LoadAll(main, dropIn)
+	var skipped *SkippedError
+	if !errors.As(err, &skipped) || len(skipped.Errs) != 2 {
+		t.Fatalf("want both rules of the drop-in skipped, got %v", err)
+	}
+	for _, e := range skipped.Errs {
+		if !strings.HasPrefix(e.Error(), filepath.Join(dropIn, "10-nginx.yaml")+":") {
+			t.Fatalf("rules of %s reported: %v", main, e)
+		}
+	}
+	if len(r.Systemd) != 3 {
+		t.Fatalf("want the 3 rules of %s, got %+v", main, r.Systemd)
+	}
+	for _, rule := range r.Systemd {
+		if rule.Source != main {
+			t.Fatalf("rule from %s kept: %+v", rule.Source, rule)
+		}
+	}
+}
+
+func TestKeepBroken(t *testing.T) {
+	dir := t.TempDir()
+	a, b := filepath.Join(dir, "10-a.yaml"), filepath.Join(dir, "20-b.yaml")
+	writeRules(t, a, "systemd:\n  - unit: a.service\n    components: [\"1\"]\n")
+	writeRules(t, b, "systemd:\n  - unit: b.service\n    components: [\"2\"]\n"+
+		"aggregation:\n  components:\n    \"2\": any_ok\n")
+	units := func(r Rules) string {
+		var out []string
+		for _, rule := range r.Systemd {
+			out = append(out, rule.Unit+"@"+filepath.Base(rule.Source))
+		}
+		return strings.Join(out, ",")
+	}
+
+	prev, err := LoadAll("", dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r, kept := prev.KeepBroken(Rules{}); kept != nil || units(r) != units(prev) {
+		t.Fatalf("nothing broken, yet kept %v", kept)
+	}
+
+	writeRules(t, b, "systemd:\n  - unit: c.service\n    componets: [\"3\"]\n")
+	cur, err := LoadAll("", dir)
+	var skipped *SkippedError
+	if !errors.As(err, &skipped) || units(cur) != "a.service@10-a.yaml" {
+		t.Fatalf("want %s skipped, got %v: %s", b, err, units(cur))
+	}
+	r, kept := cur.KeepBroken(prev)
+	if len(kept) != 1 || kept[0] != b {
+		t.Fatalf("want %s kept, got %v", b, kept)
+	}
+	if units(r) != "a.service@10-a.yaml,b.service@20-b.yaml" || r.Aggregation.Components["2"].Mode != PolicyAnyOK {
+		t.Fatalf("rules of %s not kept: %s %+v", b, units(r), r.Aggregation)
+	}
+
+	// still broken on the next load: the same rules stay
+	writeRules(t, a, "systemd:\n  - unit: a.service\n    components: [\"5\"]\n")
+	next, _ := LoadAll("", dir)
+	if again, _ := next.KeepBroken(r); units(again) != units(r) || again.Systemd[0].Components[0] != "5" {
+		t.Fatalf("want the kept rules again with the new a.yaml, got %+v", again.Systemd)
+	}
+
+	// a removed file takes its rules along
+	if err = os.Remove(b); err != nil {
+		t.Fatal(err)
+	}
+	gone, err := LoadAll("", dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r, kept = gone.KeepBroken(r); kept != nil || units(r) != "a.service@10-a.yaml" {
+		t.Fatalf("removed file kept: %v %s", kept, units(r))
+	}
+}
+
+func TestLoadAll_NothingLoads(t *testing.T) {
+	dir := t.TempDir()
+	writeRules(t, filepath.Join(dir, "a.yaml"), "systemd: [")
+	r, err := LoadAll("", dir)
+	var skipped *SkippedError
+	if err == nil || errors.As(err, &skipped) || len(r.Systemd) != 0 {
+		t.Fatalf("want a plain error, got %v (%+v)", err, r)
+	}
+	if r, err = LoadAll("", t.TempDir()); err != nil || len(r.Systemd) != 0 {
+		t.Fatalf("empty dir: want no rules and no error, got %v", err)
+	}
+}
+
+func TestWatch_DropInDir(t *testing.T) {
+	dir := t.TempDir()
+	writeRules(t, filepath.Join(dir, "a.yaml"), "systemd:\n  - unit: a.service\n    components: [\"1\"]\n")
+
+	var mu sync.Mutex
+	var last Rules
+	stop := make(chan struct{})
+	defer close(stop)
+	go func() {
+		_ = Watch(stop, "", dir, func(r Rules, err error) {
+			if err == nil {
+				mu.Lock()
+				last = r
+				mu.Unlock()
+			}
+		})
+	}()
+	units := func() int {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(last.Systemd)
+	}
+	wait := func(n int) {
+		t.Helper()
+		deadline := time.Now().Add(2 * time.Second)
+		for units() != n {
+			if time.Now().After(deadline) {
+				t.Fatalf("want %d rules, got %d", n, units())
+			}
+			time.Sleep(20 * time.Millisecond)
+		}
+	}
+	time.Sleep(100 * time.Millisecond)
+
+	writeRules(t, filepath.Join(dir, "b.yaml"), "systemd:\n  - unit: b.service\n    components: [\"2\"]\n")
+	wait(2)
+	if err := os.Rename(filepath.Join(dir, "b.yaml"), filepath.Join(dir, "b.yaml.off")); err != nil {
+		t.Fatal(err)
+	}
+	wait(1)
+	if err := os.Remove(filepath.Join(dir, "a.yaml")); err != nil {
+		t.Fatal(err)
+	}
+	wait(0)
+}
@@ -0,0 +1,43 @@
+package rules
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/arenadata/ad-status-sender/internal/maintenance"
+)
+
+// Window converts the rule into a maintenance window called name.
+func (mw MaintenanceWindow) Window(name string) (maintenance.Window, error) {
+	w := maintenance.Window{Name: name, Components: mw.Components, Status: mw.Status}
+	switch {
+	case mw.Cron != "":
+		sched, err := maintenance.ParseCron(mw.Cron)
+		if err != nil {
+			return w, err
+		}
+		if w.Duration, err = time.ParseDuration(mw.Duration); err != nil || w.Duration <= 0 {
+			return w, fmt.Errorf("cron window needs a positive duration, got %q", mw.Duration)
+		}
+		if mw.Timezone != "" {
+			if w.Location, err = time.LoadLocation(mw.Timezone); err != nil {
+				return w, err
+			}
+		}
+		w.Schedule = &sched
+	case mw.To != "":
+		var err error
+		if mw.From != "" {
+			if w.From, err = time.Parse(time.RFC3339, mw.From); err != nil {
+				return w, fmt.Errorf("from: %w", err)
+			}
+		}
+		if w.To, err = time.Parse(time.RFC3339, mw.To); err != nil {
+			return w, fmt.Errorf("to: %w", err)
+		}
+	default:
+		return w, errors.New("either cron+duration or to is required")
+	}
+	return w, nil
+}
@@ -0,0 +1,117 @@
+package rules
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/goccy/go-yaml"
+)
+
+const (
+	PolicyAllOK   = "all_ok"
+	PolicyAnyOK   = "any_ok"
+	PolicyQuorum  = "quorum"
+	PolicyPercent = "percent"
+
+	percentMax = 100
+)
+
+// Aggregation declares how the statuses of all checks mapped to a component
+// are reduced to the single status posted for it in a cycle.
+type Aggregation struct {
+	Default    Policy            `json:"default"    yaml:"default"`
+	Components map[string]Policy `json:"components" yaml:"components"`
+}
+
+// PolicyFor returns the policy for compID, falling back to the default.
+func (a Aggregation) PolicyFor(compID string) Policy {
+	if p, ok := a.Components[compID]; ok {
+		return p
+	}
+	return a.Default
+}
+
+// Policy is written in YAML either as a bare mode ("all_ok", "any_ok") or as
+// a single-key mapping ("quorum: 2", "percent: 50").
+type Policy struct {
+	Mode    string  `json:"mode"`
+	Quorum  int     `json:"quorum,omitempty"`
+	Percent float64 `json:"percent,omitempty"`
+
+	problem string // an out-of-range value, reported by Validate with its position
+}
+
+func (p *Policy) UnmarshalYAML(b []byte) error {
+	var mode string
+	if err := yaml.Unmarshal(b, &mode); err == nil {
+		switch mode {
+		case PolicyAllOK, PolicyAnyOK, "":
+			*p = Policy{Mode: mode}
+			return nil
+		default:
+			return fmt.Errorf("unknown aggregation policy %q", mode)
+		}
+	}
+
+	var m map[string]float64
+	if err := yaml.Unmarshal(b, &m); err != nil {
+		return fmt.Errorf("aggregation policy: %w", err)
+	}
+	if len(m) != 1 {
+		return fmt.Errorf("aggregation policy must have exactly one key, got %d", len(m))
+	}
+	for k, v := range m {
+		switch strings.TrimSpace(k) {
+		case PolicyQuorum:
+			*p = Policy{Mode: PolicyQuorum, Quorum: int(v)}
+			switch {
+			case v != math.Trunc(v):
+				p.problem = fmt.Sprintf("must be a whole number, got %v", v)
+			case v < 1:
+				p.problem = fmt.Sprintf("must be >= 1, got %v", v)
+			}
+		case PolicyPercent:
+			*p = Policy{Mode: PolicyPercent, Percent: v}
+			if v <= 0 || v > percentMax {
+				p.problem = fmt.Sprintf("must be in (0, 100], got %v", v)
+			}
+		default:
+			return fmt.Errorf("unknown aggregation policy %q", k)
+		}
+	}
+	return nil
+}
+
+// Reduce folds the statuses reported for one component into one status.
+// When the policy is satisfied it returns 0, otherwise the highest non-zero
+// status seen (at least 1). An empty input is reported as 1.
+func (p Policy) Reduce(statuses []int) int {
+	if len(statuses) == 0 {
+		return 1
+	}
+	ok, worst := 0, 0
+	for _, st := range statuses {
+		if st == 0 {
+			ok++
+			continue
+		}
+		worst = max(worst, st)
+	}
+
+	var pass bool
+	switch p.Mode {
+	case PolicyAnyOK:
+		pass = ok > 0
+	case PolicyQuorum:
+		pass = ok >= p.Quorum
+	case PolicyPercent:
+		pass = float64(ok)*percentMax >= p.Percent*float64(len(statuses))
+	default:
+		pass = ok == len(statuses)
+	}
+	if pass {
+		return 0
+	}
+	return max(worst, 1)
+}
@@ -1,27 +1,51 @@
 package rules
 
 import (
+	"bytes"
 	"os"
 	"path/filepath"
 	"strings"
 	"sync"
 	"time"
 
+	"github.com/arenadata/ad-status-sender/internal/config"
+	"github.com/arenadata/ad-status-sender/internal/schema"
 	"github.com/fsnotify/fsnotify"
-	"github.com/goccy/go-yaml"
 )
 
 const debounceDelay = 150 * time.Millisecond
 
 type Rules struct {
-	Systemd []RuleSystemd `json:"systemd" yaml:"systemd"`
-	Docker  []RuleDocker  `json:"docker"  yaml:"docker"`
+	Systemd     []RuleSystemd       `json:"systemd"     yaml:"systemd"`
+	Docker      []RuleDocker        `json:"docker"      yaml:"docker"`
+	Exec        []RuleExec          `json:"exec"        yaml:"exec"`
+	TCP         []RuleTCP           `json:"tcp"         yaml:"tcp"`
+	HTTP        []RuleHTTP          `json:"http"        yaml:"http"`
+	Process     []RuleProcess       `json:"process"     yaml:"process"`
+	Aggregation Aggregation         `json:"aggregation" yaml:"aggregation"`
+	Host        *RuleHost           `json:"host"        yaml:"host"`
+	Maintenance []MaintenanceWindow `json:"maintenance" yaml:"maintenance"`
+
+	// files are the rules of every file LoadAll read, in order.
+	files []loadedFile
 }
 
 type RuleSystemd struct {
-	Unit       string   `json:"unit"       yaml:"unit"`
-	UnitGlob   string   `json:"unit_glob"  yaml:"unit_glob"`
-	Components []string `json:"components" yaml:"components"`
+	Unit          string         `json:"unit"             yaml:"unit"`
+	UnitGlob      string         `json:"unit_glob"        yaml:"unit_glob"`
+	Components    []string       `json:"components"       yaml:"components"`
+	ComponentRefs []ComponentRef `json:"component_refs"   yaml:"component_refs"`
+	RestartLimit  *RestartLimit  `json:"restart_limit"    yaml:"restart_limit"`
+	Source        string         `json:"source,omitempty" yaml:"-"`
+	Debounce      `json:",inline" yaml:",inline"`
+}
+
+// RestartLimit reports Status (default 1) for a target that is up but was
+// restarted more than Max times within Window (default 10m).
+type RestartLimit struct {
+	Max    int    `json:"max"    yaml:"max"`
+	Window string `json:"window" yaml:"window"`
+	Status int    `json:"status" yaml:"status"`
 }
 
 type DockerSelector struct {
@@ -29,20 +53,157 @@ type DockerSelector struct {
 	Labels []string `json:"labels" yaml:"labels"` // "k=v"
 }
 
+// RuleDocker reports 0 when every selected container is running. With
+// RequireHealthy, containers failing their HEALTHCHECK count as down, and
+// "starting" is tolerated for AllowStartingFor after the container started.
 type RuleDocker struct {
-	Name       string         `json:"name"       yaml:"name"`
-	Components []string       `json:"components" yaml:"components"`
-	Containers DockerSelector `json:"containers" yaml:"containers"`
+	Name             string         `json:"name"               yaml:"name"`
+	Components       []string       `json:"components"         yaml:"components"`
+	ComponentRefs    []ComponentRef `json:"component_refs"     yaml:"component_refs"`
+	Containers       DockerSelector `json:"containers"         yaml:"containers"`
+	RequireHealthy   bool           `json:"require_healthy"    yaml:"require_healthy"`
+	AllowStartingFor string         `json:"allow_starting_for" yaml:"allow_starting_for"`
+	RestartLimit     *RestartLimit  `json:"restart_limit"      yaml:"restart_limit"`
+	Source           string         `json:"source,omitempty"   yaml:"-"`
+	Debounce         `json:",inline" yaml:",inline"`
+}
+
+// RuleExec runs a Nagios-style command. Exit code 0 maps to status 0 and any
+// other code to 1, unless overridden in ExitCodes; a timeout is status 1.
+type RuleExec struct {
+	Name          string            `json:"name"             yaml:"name"`
+	Command       string            `json:"command"          yaml:"command"`
+	Args          []string          `json:"args"             yaml:"args"`
+	Timeout       string            `json:"timeout"          yaml:"timeout"`
+	Env           map[string]string `json:"env"              yaml:"env"`
+	Dir           string            `json:"dir"              yaml:"dir"`
+	ExitCodes     map[int]int       `json:"exit_codes"       yaml:"exit_codes"`
+	Components    []string          `json:"components"       yaml:"components"`
+	ComponentRefs []ComponentRef    `json:"component_refs"   yaml:"component_refs"`
+	Source        string            `json:"source,omitempty" yaml:"-"`
+	Debounce      `json:",inline" yaml:",inline"`
+}
+
+// RuleTCP reports 0 when Address (host:port) or Socket (Unix socket path)
+// accepts a connection within Timeout and, if set, the response to Send
+// starts with ExpectPrefix and matches ExpectRegex.
+type RuleTCP struct {
+	Name          string         `json:"name"             yaml:"name"`
+	Address       string         `json:"address"          yaml:"address"`
+	Socket        string         `json:"socket"           yaml:"socket"`
+	Timeout       string         `json:"timeout"          yaml:"timeout"`
+	Send          string         `json:"send"             yaml:"send"`
+	ExpectPrefix  string         `json:"expect_prefix"    yaml:"expect_prefix"`
+	ExpectRegex   string         `json:"expect_regex"     yaml:"expect_regex"`
+	Components    []string       `json:"components"       yaml:"components"`
+	ComponentRefs []ComponentRef `json:"component_refs"   yaml:"component_refs"`
+	Source        string         `json:"source,omitempty" yaml:"-"`
+	Debounce      `json:",inline" yaml:",inline"`
+}
+
+// RuleHTTP reports 0 when a Method (GET or HEAD) request to URL answers with
+// one of ExpectStatus (any 2xx if empty) within Timeout and the body matches
+// ExpectBody and/or has JSONValue at JSONPath. TLS applies to https URLs.
+type RuleHTTP struct {
+	Name          string            `json:"name"             yaml:"name"`
+	URL           string            `json:"url"              yaml:"url"`
+	Method        string            `json:"method"           yaml:"method"`
+	Timeout       string            `json:"timeout"          yaml:"timeout"`
+	Headers       map[string]string `json:"headers"          yaml:"headers"`
+	ExpectStatus  []int             `json:"expect_status"    yaml:"expect_status"`
+	ExpectBody    string            `json:"expect_body"      yaml:"expect_body"`
+	JSONPath      string            `json:"json_path"        yaml:"json_path"`
+	JSONValue     string            `json:"json_value"       yaml:"json_value"`
+	TLS           config.TLS        `json:"tls"              yaml:"tls"`
+	Components    []string          `json:"components"       yaml:"components"`
+	ComponentRefs []ComponentRef    `json:"component_refs"   yaml:"component_refs"`
+	Source        string            `json:"source,omitempty" yaml:"-"`
+	Debounce      `json:",inline" yaml:",inline"`
+}
+
+// RuleProcess reports 0 when the number of live processes matching every
+// set selector (Process name, Cmdline regex, User, PidFile) is within
+// [Min, Max]. Min defaults to 1; Max 0 means no upper bound.
+type RuleProcess struct {
+	Name          string         `json:"name"             yaml:"name"`
+	Process       string         `json:"process"          yaml:"process"`
+	Cmdline       string         `json:"cmdline"          yaml:"cmdline"`
+	User          string         `json:"user"             yaml:"user"`
+	PidFile       string         `json:"pidfile"          yaml:"pidfile"`
+	Min           *int           `json:"min"              yaml:"min"`
+	Max           int            `json:"max"              yaml:"max"`
+	Components    []string       `json:"components"       yaml:"components"`
+	ComponentRefs []ComponentRef `json:"component_refs"   yaml:"component_refs"`
+	Source        string         `json:"source,omitempty" yaml:"-"`
+	Debounce      `json:",inline" yaml:",inline"`
+}
+
+// MaintenanceWindow covers Components (every component if empty) for
+// Duration each time Cron fires, or once between From and To (RFC 3339).
+// Status is reported meanwhile; without it the components are not posted.
+type MaintenanceWindow struct {
+	Name          string         `json:"name"             yaml:"name"`
+	Components    []string       `json:"components"       yaml:"components"`
+	ComponentRefs []ComponentRef `json:"component_refs"   yaml:"component_refs"`
+	Cron          string         `json:"cron"             yaml:"cron"`
+	Duration      string         `json:"duration"         yaml:"duration"`
+	Timezone      string         `json:"timezone"         yaml:"timezone"`
+	From          string         `json:"from"             yaml:"from"`
+	To            string         `json:"to"               yaml:"to"`
+	Status        *int           `json:"status"           yaml:"status"`
+	Source        string         `json:"source,omitempty" yaml:"-"`
+}
+
+// RuleHost lists host-level checks; the heartbeat is 0 only if all pass.
+// Zero thresholds are not checked.
+type RuleHost struct {
+	Filesystems    []HostFilesystem `json:"filesystems"      yaml:"filesystems"`
+	MemoryPressure HostPressure     `json:"memory_pressure"  yaml:"memory_pressure"`
+	Load           HostLoad         `json:"load"             yaml:"load"`
+	Source         string           `json:"source,omitempty" yaml:"-"`
+}
+
+type HostFilesystem struct {
+	Path             string  `json:"path"               yaml:"path"`
+	MaxUsedPercent   float64 `json:"max_used_percent"   yaml:"max_used_percent"`
+	MaxInodesPercent float64 `json:"max_inodes_percent" yaml:"max_inodes_percent"`
+	FailReadOnly     bool    `json:"fail_read_only"     yaml:"fail_read_only"`
+}
+
+// HostPressure thresholds apply to /proc/pressure/memory averages.
+type HostPressure struct {
+	MaxSomeAvg10 float64 `json:"max_some_avg10" yaml:"max_some_avg10"`
+	MaxSomeAvg60 float64 `json:"max_some_avg60" yaml:"max_some_avg60"`
+	MaxFullAvg10 float64 `json:"max_full_avg10" yaml:"max_full_avg10"`
+	MaxFullAvg60 float64 `json:"max_full_avg60" yaml:"max_full_avg60"`
+}
+
+// HostLoad thresholds apply to the load average, divided by the number of
+// CPUs when PerCPU is set.
+type HostLoad struct {
+	MaxLoad1  float64 `json:"max_load1"  yaml:"max_load1"`
+	MaxLoad5  float64 `json:"max_load5"  yaml:"max_load5"`
+	MaxLoad15 float64 `json:"max_load15" yaml:"max_load15"`
+	PerCPU    bool    `json:"per_cpu"    yaml:"per_cpu"`
 }
 
+// Load reads the rules strictly: unknown fields are rejected and the result
+// is validated (see Rules.Validate). An empty file is an error, as it is
+// most likely caught halfway through being written.
 func Load(path string) (Rules, error) {
 	var r Rules
 	b, err := os.ReadFile(path)
 	if err != nil {
 		return r, err
 	}
-	if unErr := yaml.Unmarshal(b, &r); unErr != nil {
-		return r, unErr
+	if len(bytes.TrimSpace(b)) == 0 {
+		return r, &schema.ValidationError{File: path, Msg: "file is empty"}
+	}
+	if err = schema.Decode(path, b, &r); err != nil {
+		return Rules{}, err
+	}
+	if err = r.Validate(path, b); err != nil {
+		return Rules{}, err
 	}
 	return r, nil
 }
@@ -64,15 +225,26 @@ func (s *Store) Set(r Rules) {
 	s.mu.Unlock()
 }
 
-func Watch(stop <-chan struct{}, path string, apply func(Rules)) error {
+// Watch reloads the rules whenever path or a drop-in file of dir is
+// written, created, renamed or removed, and passes the result of LoadAll to
+// apply. A failed load is passed on as well; the caller decides what to
+// keep.
+func Watch(stop <-chan struct{}, path, dir string, apply func(Rules, error)) error {
 	w, err := fsnotify.NewWatcher()
 	if err != nil {
 		return err
 	}
 	defer w.Close()
 
-	_ = w.Add(path)
-	_ = w.Add(filepath.Dir(path))
+	if path != "" {
+		_ = w.Add(path)
+		_ = w.Add(filepath.Dir(path))
+	}
+	if dir != "" {
+		if err = w.Add(dir); err != nil {
+			return err
+		}
+	}
 
 	debounce := time.NewTimer(0)
 	if !debounce.Stop() {
@@ -85,19 +257,20 @@ func Watch(stop <-chan struct{}, path string, apply func(Rules)) error {
 		case <-stop:
 			return nil
 		case ev := <-w.Events:
-			if !sameFile(path, ev.Name) {
-				continue
-			}
-			if ev.Has(fsnotify.Write) ||
-				ev.Has(fsnotify.Create) ||
-				ev.Has(fsnotify.Rename) {
-				fire()
+			switch {
+			case path != "" && sameFile(path, ev.Name):
+				if ev.Has(fsnotify.Write) ||
+					ev.Has(fsnotify.Create) ||
+					ev.Has(fsnotify.Rename) {
+					fire()
+				}
+			case dir != "" && filepath.Dir(ev.Name) == filepath.Clean(dir) && isRulesFile(ev.Name):
+				if !ev.Has(fsnotify.Chmod) {
+					fire()
+				}
 			}
 		case <-debounce.C:
-			r, loadErr := Load(path)
-			if loadErr == nil {
-				apply(r)
-			}
+			apply(LoadAll(path, dir))
 		case <-w.Errors:
 		}
 	}
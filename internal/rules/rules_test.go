@@ -3,6 +3,8 @@ package rules
 import (
 	"os"
 	"path/filepath"
+	"slices"
+	"strings"
 	"sync/atomic"
 	"testing"
 	"time"
@@ -54,8 +56,10 @@ func TestWatchHotReload(t *testing.T) {
 
 	// start watcher
 	go func() {
-		_ = Watch(stop, fn, func(Rules) {
-			atomic.AddInt32(&applied, 1)
+		_ = Watch(stop, fn, "", func(_ Rules, err error) {
+			if err == nil {
+				atomic.AddInt32(&applied, 1)
+			}
 		})
 	}()
 
@@ -81,3 +85,189 @@ systemd:
 		t.Fatalf("watcher did not apply changes")
 	}
 }
+
+func TestAggregationPolicies(t *testing.T) {
+	data := []byte(`
+aggregation:
+  default: all_ok
+  components:
+    "1": any_ok
+    "2": {quorum: 2}
+    "3": {percent: 50}
+`)
+	fn := filepath.Join(t.TempDir(), "rules.yaml")
+	if err := os.WriteFile(fn, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	r, err := Load(fn)
+	if err != nil {
+		t.Fatalf("load err: %v", err)
+	}
+
+	cases := []struct {
+		comp string
+		in   []int
+		want int
+	}{
+		{"9", []int{0, 0}, 0},
+		{"9", []int{0, 1}, 1},
+		{"1", []int{1, 0, 1}, 0},
+		{"1", []int{1, 255}, 255},
+		{"2", []int{0, 1, 0}, 0},
+		{"2", []int{0}, 1},
+		{"3", []int{0, 1}, 0},
+		{"3", []int{0, 1, 1}, 1},
+		{"9", nil, 1},
+	}
+	for _, c := range cases {
+		if got := r.Aggregation.PolicyFor(c.comp).Reduce(c.in); got != c.want {
+			t.Fatalf("comp %s %v: want %d, got %d", c.comp, c.in, c.want, got)
+		}
+	}
+}
+
+func TestAggregationPolicyInvalid(t *testing.T) {
+	for _, c := range []struct{ body, want string }{
+		{"aggregation: {default: most_ok}", "unknown aggregation policy"},
+		{"aggregation: {default: {quorum: 0}}", ":1:33: aggregation.default.quorum: must be >= 1"},
+		{"aggregation: {default: {percent: 150}}", ":1:34: aggregation.default.percent: must be in (0, 100]"},
+		{"aggregation:\n  components:\n    \"7\": {quorum: 1.5}\n",
+			":3:19: aggregation.components.7.quorum: must be a whole number, got 1.5"},
+	} {
+		fn := filepath.Join(t.TempDir(), "rules.yaml")
+		if err := os.WriteFile(fn, []byte(c.body), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		_, err := Load(fn)
+		if err == nil || !strings.Contains(err.Error(), c.want) {
+			t.Fatalf("%q: want an error with %q, got %v", c.body, c.want, err)
+		}
+	}
+}
+
+func TestLoadInvalid(t *testing.T) {
+	data := []byte(`
+systemd:
+  - unit: "nginx.service"
+    components: []
+docker:
+  - name: "web"
+    components: ["3"]
+    containers:
+      labels: ["com.example.role"]
+    allow_starting_for: "1 minute"
+http:
+  - url: "http://127.0.0.1/health"
+    components: ["4"]
+    tls:
+      key_file: "/nonexistent/client.key"
+exec:
+  - command: "/bin/true"
+    component_refs:
+      - service: "hbase"
+`)
+	fn := filepath.Join(t.TempDir(), "rules.yaml")
+	if err := os.WriteFile(fn, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	_, err := Load(fn)
+	if err == nil {
+		t.Fatal("want validation error")
+	}
+	if strings.Contains(err.Error(), "exec[0].components") {
+		t.Fatalf("component_refs must do instead of components:\n%v", err)
+	}
+	for _, want := range []string{
+		fn + ":4:17: systemd[0].components: at least one component is required",
+		fn + `:9:16: docker[0].containers.labels[0]: want key=value, got "com.example.role"`,
+		fn + `:10:25: docker[0].allow_starting_for: invalid duration "1 minute"`,
+		fn + `:10:25: docker[0].allow_starting_for: only applies with require_healthy: true`,
+		fn + ":15:7: http[0].tls: cert_file and key_file must be set together",
+		fn + ":19:9: exec[0].component_refs[0]: service and component are required",
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Fatalf("missing %q in:\n%v", want, err)
+		}
+	}
+
+	if err = os.WriteFile(fn, []byte("systemd:\n  - unit: a\n    component: [\"1\"]\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = Load(fn); err == nil || !strings.HasPrefix(err.Error(), fn+":3:5: ") {
+		t.Fatalf("want unknown field at 3:5, got %v", err)
+	}
+}
+
+func TestDiff(t *testing.T) {
+	before := Rules{
+		Systemd: []RuleSystemd{
+			{Unit: "a.service", Components: []string{"1"}},
+			{Unit: "b.service", Components: []string{"2"}},
+		},
+		HTTP:        []RuleHTTP{{Name: "api", URL: "http://127.0.0.1/health", Components: []string{"3"}}},
+		Aggregation: Aggregation{Default: Policy{Mode: PolicyAllOK}},
+	}
+	after := Rules{
+		Systemd: []RuleSystemd{
+			{Unit: "a.service", Components: []string{"1"}},
+			{Unit: "c.service", Components: []string{"4"}},
+		},
+		HTTP: []RuleHTTP{{Name: "api", URL: "http://127.0.0.1/ready", Components: []string{"3"}}},
+	}
+	ch := Diff(before, after)
+	if strings.Join(ch.Added, ";") != `systemd unit "c.service"` ||
+		strings.Join(ch.Removed, ";") != `aggregation default;systemd unit "b.service"` ||
+		strings.Join(ch.Changed, ";") != `http rule "api"` {
+		t.Fatalf("unexpected diff: %+v", ch)
+	}
+	if ch.Before != 4 || ch.After != 3 || ch.DropPercent() != 50 || ch.Empty() {
+		t.Fatalf("unexpected summary: %+v, drop %v", ch, ch.DropPercent())
+	}
+	if !Diff(after, after).Empty() || Diff(Rules{}, after).DropPercent() != 0 {
+		t.Fatal("identical rules must not differ")
+	}
+}
+
+func TestLoad_EmptyFile(t *testing.T) {
+	fn := filepath.Join(t.TempDir(), "rules.yaml")
+	if err := os.WriteFile(fn, []byte("\n  \n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(fn); err == nil || !strings.Contains(err.Error(), "file is empty") {
+		t.Fatalf("want an error for an empty file, got %v", err)
+	}
+}
+
+func TestResolve(t *testing.T) {
+	rs := ComponentRef{Service: "hbase", Component: "regionserver"}
+	dn := ComponentRef{Service: "hdfs", Component: "datanode"}
+	r := Rules{
+		Systemd: []RuleSystemd{{Unit: "a", Components: []string{"1"}, ComponentRefs: []ComponentRef{rs, dn}}},
+		Maintenance: []MaintenanceWindow{
+			{Name: "by ref", ComponentRefs: []ComponentRef{rs}},
+			{Name: "lost", ComponentRefs: []ComponentRef{dn}},
+			{Name: "all"},
+		},
+		Aggregation: Aggregation{Components: map[string]Policy{
+			"hbase/regionserver": {Mode: PolicyAnyOK},
+			"1":                  {Mode: PolicyAllOK},
+		}},
+	}
+	got, missing := r.Resolve(map[ComponentRef]string{rs: "202"})
+
+	if !slices.Equal(got.Systemd[0].Components, []string{"1", "202"}) {
+		t.Fatalf("systemd components: %v", got.Systemd[0].Components)
+	}
+	if !slices.Equal(r.Systemd[0].Components, []string{"1"}) {
+		t.Fatalf("input modified: %v", r.Systemd[0].Components)
+	}
+	if len(got.Maintenance) != 2 || got.Maintenance[0].Components[0] != "202" || got.Maintenance[1].Name != "all" {
+		t.Fatalf("a window with unresolved refs must be dropped, not cover everything: %+v", got.Maintenance)
+	}
+	if got.Aggregation.PolicyFor("202").Mode != PolicyAnyOK || got.Aggregation.PolicyFor("1").Mode != PolicyAllOK {
+		t.Fatalf("aggregation: %v", got.Aggregation.Components)
+	}
+	if !slices.Equal(missing, []ComponentRef{dn}) {
+		t.Fatalf("missing: %v", missing)
+	}
+}
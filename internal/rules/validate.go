@@ -0,0 +1,131 @@
+package rules
+
+import (
+	"fmt"
+	"maps"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/arenadata/ad-status-sender/internal/schema"
+)
+
+// Validate checks what decoding alone doesn't: every rule selects
+// something, feeds at least one component, by ID or ref, and has parseable
+// durations and patterns. data is the YAML source of r and is only used to
+// report line and column.
+func (r Rules) Validate(file string, data []byte) error {
+	chk := schema.NewChecker(file, data)
+	for i, rule := range r.Systemd {
+		path := fmt.Sprintf("systemd[%d]", i)
+		if rule.Unit == "" && rule.UnitGlob == "" {
+			chk.Addf(path, "unit or unit_glob is required")
+		}
+		checkComponents(chk, path, rule.Components, rule.ComponentRefs)
+		rule.RestartLimit.check(chk, path+".restart_limit")
+	}
+	for i, rule := range r.Docker {
+		path := fmt.Sprintf("docker[%d]", i)
+		rule.Containers.check(chk, path+".containers")
+		chk.Duration(path+".allow_starting_for", rule.AllowStartingFor)
+		if rule.AllowStartingFor != "" && !rule.RequireHealthy {
+			chk.Addf(path+".allow_starting_for", "only applies with require_healthy: true")
+		}
+		checkComponents(chk, path, rule.Components, rule.ComponentRefs)
+		rule.RestartLimit.check(chk, path+".restart_limit")
+	}
+	for i, rule := range r.Exec {
+		path := fmt.Sprintf("exec[%d]", i)
+		chk.Required(path+".command", rule.Command)
+		chk.Duration(path+".timeout", rule.Timeout)
+		checkComponents(chk, path, rule.Components, rule.ComponentRefs)
+	}
+	for i, rule := range r.TCP {
+		path := fmt.Sprintf("tcp[%d]", i)
+		if (rule.Address == "") == (rule.Socket == "") {
+			chk.Addf(path, "exactly one of address and socket is required")
+		}
+		chk.Duration(path+".timeout", rule.Timeout)
+		chk.Regexp(path+".expect_regex", rule.ExpectRegex)
+		checkComponents(chk, path, rule.Components, rule.ComponentRefs)
+	}
+	for i, rule := range r.HTTP {
+		rule.check(chk, fmt.Sprintf("http[%d]", i))
+	}
+	for i, rule := range r.Process {
+		rule.check(chk, fmt.Sprintf("process[%d]", i))
+	}
+	r.Aggregation.Default.check(chk, "aggregation.default")
+	for _, comp := range slices.Sorted(maps.Keys(r.Aggregation.Components)) {
+		r.Aggregation.Components[comp].check(chk, "aggregation.components."+comp)
+	}
+	for i, mw := range r.Maintenance {
+		path := fmt.Sprintf("maintenance[%d]", i)
+		if _, err := mw.Window(mw.Name); err != nil {
+			chk.Addf(path, "%v", err)
+		}
+		checkRefs(chk, path, mw.ComponentRefs)
+	}
+	return chk.Err()
+}
+
+func (p Policy) check(chk *schema.Checker, path string) {
+	if p.problem != "" {
+		chk.Addf(path+"."+p.Mode, "%s", p.problem)
+	}
+}
+
+func (l *RestartLimit) check(chk *schema.Checker, path string) {
+	if l == nil {
+		return
+	}
+	if l.Max < 0 {
+		chk.Addf(path+".max", "must not be negative")
+	}
+	chk.Duration(path+".window", l.Window)
+}
+
+func (s DockerSelector) check(chk *schema.Checker, path string) {
+	if len(s.Names) == 0 && len(s.Labels) == 0 {
+		chk.Addf(path, "names or labels is required")
+	}
+	for i, l := range s.Labels {
+		if k, _, ok := strings.Cut(l, "="); !ok || strings.TrimSpace(k) == "" {
+			chk.Addf(fmt.Sprintf("%s.labels[%d]", path, i), "want key=value, got %q", l)
+		}
+	}
+}
+
+func (h RuleHTTP) check(chk *schema.Checker, path string) {
+	chk.Required(path+".url", h.URL)
+	chk.URL(path+".url", h.URL)
+	if h.Method != "" && strings.ToUpper(h.Method) != http.MethodGet && strings.ToUpper(h.Method) != http.MethodHead {
+		chk.Addf(path+".method", "must be GET or HEAD, got %q", h.Method)
+	}
+	chk.Duration(path+".timeout", h.Timeout)
+	for i, code := range h.ExpectStatus {
+		if code < 100 || code > 599 {
+			chk.Addf(fmt.Sprintf("%s.expect_status[%d]", path, i), "%d is not an HTTP status code", code)
+		}
+	}
+	chk.Regexp(path+".expect_body", h.ExpectBody)
+	if h.JSONValue != "" && h.JSONPath == "" {
+		chk.Addf(path+".json_value", "needs json_path")
+	}
+	h.TLS.Check(chk, path+".tls")
+	checkComponents(chk, path, h.Components, h.ComponentRefs)
+}
+
+func (p RuleProcess) check(chk *schema.Checker, path string) {
+	if p.Process == "" && p.Cmdline == "" && p.PidFile == "" {
+		chk.Addf(path, "process, cmdline or pidfile is required")
+	}
+	chk.Regexp(path+".cmdline", p.Cmdline)
+	if p.Min != nil && *p.Min < 0 {
+		chk.Addf(path+".min", "must not be negative")
+	}
+	if p.Max < 0 || (p.Max > 0 && p.Min != nil && p.Max < *p.Min) {
+		chk.Addf(path+".max", "must be 0 (unbounded) or at least min")
+	}
+	checkComponents(chk, path, p.Components, p.ComponentRefs)
+}
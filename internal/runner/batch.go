@@ -0,0 +1,183 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/arenadata/ad-status-sender/internal/config"
+)
+
+const (
+	BatchShapeList = "list"
+	BatchShapeMap  = "map"
+)
+
+type batchItem struct {
+	HostID      int    `json:"host_id"`
+	ComponentID string `json:"component_id,omitempty"`
+	Status      int    `json:"status"`
+}
+
+type batchMap struct {
+	HostID     int            `json:"host_id"`
+	Host       *int           `json:"host,omitempty"`
+	Components map[string]int `json:"components"`
+}
+
+// PostBatch sends all updates in one request to the batch endpoint. If the
+// server answers 404/405 the endpoint is remembered as unsupported and
+// updates are posted one by one from then on.
+func (p *httpPoster) PostBatch(ctx context.Context, updates []Update) error {
+	if len(updates) == 0 {
+		return nil
+	}
+	if p.batchPath == "" || p.batchUnsupported.Load() {
+		return p.postEach(ctx, updates)
+	}
+	path := strings.ReplaceAll(p.batchPath, "{host_id}", strconv.Itoa(p.hostID))
+	url := strings.TrimRight(p.adcmURL, "/") + "/" + strings.TrimLeft(path, "/")
+
+	err := p.post(ctx, url, p.batchPayload(updates), "batch post", "count", len(updates))
+	var he *HTTPError
+	if errors.As(err, &he) && (he.Code == http.StatusNotFound || he.Code == http.StatusMethodNotAllowed) {
+		p.batchUnsupported.Store(true)
+		p.log.WarnContext(ctx, "batch endpoint not supported, posting one by one", "url", url, "code", he.Code)
+		return p.postEach(ctx, updates)
+	}
+	return err
+}
+
+func (p *httpPoster) batchPayload(updates []Update) any {
+	if p.batchShape == BatchShapeMap {
+		m := batchMap{HostID: p.hostID, Components: make(map[string]int, len(updates))}
+		for _, u := range updates {
+			if u.IsHost {
+				st := u.Status
+				m.Host = &st
+				continue
+			}
+			m.Components[u.CompID] = u.Status
+		}
+		return m
+	}
+	items := make([]batchItem, 0, len(updates))
+	for _, u := range updates {
+		items = append(items, batchItem{HostID: p.hostID, ComponentID: u.CompID, Status: u.Status})
+	}
+	return items
+}
+
+func (p *httpPoster) postEach(ctx context.Context, updates []Update) error {
+	errs := make([]error, len(updates))
+	failed := false
+	for i, u := range updates {
+		if u.IsHost {
+			errs[i] = p.PostHost(ctx, u.Status)
+		} else {
+			errs[i] = p.PostComponent(ctx, u.CompID, u.Status)
+		}
+		failed = failed || errs[i] != nil
+	}
+	if failed {
+		return &BatchError{Errs: errs}
+	}
+	return nil
+}
+
+type queued struct {
+	key string
+	upd Update
+}
+
+// batcher collects updates for a short window and hands them to flush in
+// one call, or earlier once maxSize updates are waiting.
+type batcher struct {
+	window  time.Duration
+	maxSize int
+	flush   func(ctx context.Context, items []queued)
+
+	mu    sync.Mutex
+	ctx   context.Context
+	buf   []queued
+	timer *time.Timer
+}
+
+func newBatcher(c config.Batch, flush func(context.Context, []queued)) *batcher {
+	b := &batcher{
+		window:  config.MustDuration(c.Window, config.DefaultBatchWindow),
+		maxSize: c.MaxSize,
+		flush:   flush,
+	}
+	if b.maxSize <= 0 {
+		b.maxSize = config.DefaultBatchSize
+	}
+	return b
+}
+
+func (b *batcher) add(ctx context.Context, key string, upd Update) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if len(b.buf) == 0 {
+		b.ctx = ctx
+		b.timer = time.AfterFunc(b.window, b.fire)
+	}
+	b.buf = append(b.buf, queued{key: key, upd: upd})
+	if len(b.buf) >= b.maxSize {
+		b.timer.Stop()
+		b.takeLocked()
+	}
+}
+
+func (b *batcher) fire() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.takeLocked()
+}
+
+func (b *batcher) takeLocked() {
+	if len(b.buf) == 0 {
+		return
+	}
+	items, ctx := b.buf, b.ctx
+	b.buf, b.ctx = nil, nil
+	go b.flush(ctx, items)
+}
+
+func (r *Runner) getBatcher() *batcher {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.batch
+}
+
+// flushBatch posts a batch and records the outcome of every update.
+func (r *Runner) flushBatch(ctx context.Context, items []queued) {
+	cfg, _, _ := r.snapshot()
+	updates := make([]Update, len(items))
+	for i, it := range items {
+		updates[i] = it.upd
+	}
+	err := r.post.PostBatch(ctx, updates)
+	if err != nil {
+		r.log.WarnContext(ctx, "post batch failed", "count", len(items), "err", err)
+	}
+	for i, it := range items {
+		r.delivered(ctx, cfg, it.key, it.upd, itemErr(err, i))
+	}
+}
+
+func batchShape(s string) (string, error) {
+	switch s {
+	case "", BatchShapeList:
+		return BatchShapeList, nil
+	case BatchShapeMap:
+		return BatchShapeMap, nil
+	default:
+		return "", fmt.Errorf("batch.shape: unknown shape %q", s)
+	}
+}
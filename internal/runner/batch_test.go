@@ -0,0 +1,158 @@
+package runner
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/arenadata/ad-status-sender/internal/check/checktest"
+	"github.com/arenadata/ad-status-sender/internal/config"
+	"github.com/arenadata/ad-status-sender/internal/rules"
+)
+
+func TestHTTPPoster_BatchShapes(t *testing.T) {
+	var mu sync.Mutex
+	var paths, bodies []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		paths = append(paths, r.URL.Path)
+		bodies = append(bodies, string(b))
+		mu.Unlock()
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	updates := []Update{{IsHost: true, Status: 0}, {CompID: "501", Status: 1}}
+
+	p := newTestPoster(srv.URL, config.Retry{MaxAttempts: 1})
+	p.batchPath, p.batchShape = config.DefaultBatchPath, BatchShapeList
+	if err := p.PostBatch(context.Background(), updates); err != nil {
+		t.Fatalf("list batch: %v", err)
+	}
+	var items []batchItem
+	if err := json.Unmarshal([]byte(bodies[0]), &items); err != nil || len(items) != 2 ||
+		items[0].ComponentID != "" || items[1].ComponentID != "501" || items[1].Status != 1 {
+		t.Fatalf("bad list body %s: %v", bodies[0], err)
+	}
+	if paths[0] != "/status/api/v1/host/7/batch/" {
+		t.Fatalf("bad batch path %s", paths[0])
+	}
+
+	p.batchShape = BatchShapeMap
+	if err := p.PostBatch(context.Background(), updates); err != nil {
+		t.Fatalf("map batch: %v", err)
+	}
+	var m batchMap
+	if err := json.Unmarshal([]byte(bodies[1]), &m); err != nil ||
+		m.Host == nil || *m.Host != 0 || m.Components["501"] != 1 || m.HostID != 7 {
+		t.Fatalf("bad map body %s: %v", bodies[1], err)
+	}
+}
+
+func TestHTTPPoster_BatchFallsBackOn404(t *testing.T) {
+	var mu sync.Mutex
+	var paths []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		paths = append(paths, r.URL.Path)
+		mu.Unlock()
+		if r.URL.Path == "/batch" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if r.URL.Path == "/status/api/v1/host/7/component/502/" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	p := newTestPoster(srv.URL, config.Retry{MaxAttempts: 1})
+	p.batchPath = "/batch"
+	updates := []Update{{CompID: "501"}, {CompID: "502"}}
+
+	err := p.PostBatch(context.Background(), updates)
+	if itemErr(err, 0) != nil || itemErr(err, 1) == nil {
+		t.Fatalf("want only 502 to fail, got %v", err)
+	}
+	_ = p.PostBatch(context.Background(), updates[:1])
+
+	want := []string{
+		"/batch",
+		"/status/api/v1/host/7/component/501/",
+		"/status/api/v1/host/7/component/502/",
+		"/status/api/v1/host/7/component/501/",
+	}
+	if len(paths) != len(want) {
+		t.Fatalf("want %v, got %v", want, paths)
+	}
+	for i := range want {
+		if paths[i] != want[i] {
+			t.Fatalf("want %v, got %v", want, paths)
+		}
+	}
+}
+
+type batchRecorder struct {
+	testPoster
+	mu      sync.Mutex
+	batches [][]Update
+}
+
+func (p *batchRecorder) PostBatch(_ context.Context, updates []Update) error {
+	p.mu.Lock()
+	p.batches = append(p.batches, updates)
+	p.mu.Unlock()
+	return nil
+}
+
+func (p *batchRecorder) Batches() [][]Update {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([][]Update(nil), p.batches...)
+}
+
+func TestRunner_BatchesOneCycle(t *testing.T) {
+	sd := &checktest.FakeSystemd{Units: map[string]bool{"a.service": true, "b.service": false}}
+	post := &batchRecorder{}
+	clk := &testClock{now: time.Unix(0, 0)}
+
+	cfg := config.Config{ADCMURL: "http://example", HostID: 7, ForceSendAfter: "120s"}
+	r := newTestRunner(t, cfg, sd, &checktest.FakeDocker{}, post, clk)
+	r.mu.Lock()
+	r.batch = newBatcher(config.Batch{Enabled: true, Window: "50ms"}, r.flushBatch)
+	r.mu.Unlock()
+
+	r.ruleStore.Set(rules.Rules{
+		Systemd: []rules.RuleSystemd{
+			{Unit: "a.service", Components: []string{"501"}},
+			{Unit: "b.service", Components: []string{"502"}},
+		},
+	})
+
+	r.scanOnce(context.Background())
+	waitUntil(t, func() bool { return len(post.Batches()) > 0 }, 500*time.Millisecond)
+	time.Sleep(20 * time.Millisecond)
+
+	bb := post.Batches()
+	if len(bb) != 1 || len(bb[0]) != 3 {
+		t.Fatalf("want one batch with host + 2 components, got %+v", bb)
+	}
+	if post.Count() != 0 {
+		t.Fatalf("no single posts expected with batching")
+	}
+
+	// delivered updates are cached like single posts
+	r.scanOnce(context.Background())
+	time.Sleep(100 * time.Millisecond)
+	if got := len(post.Batches()); got != 1 {
+		t.Fatalf("cache not applied to batched updates, got %d batches", got)
+	}
+}
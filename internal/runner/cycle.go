@@ -0,0 +1,147 @@
+package runner
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/arenadata/ad-status-sender/internal/rules"
+)
+
+// result is the outcome of one check target within a scan cycle.
+type result struct {
+	kind   string
+	rule   string // rule that produced target, when it differs from target
+	index  int    // position of that rule among the rules of its kind
+	target string
+	status int
+	raw    int // status before debouncing
+	comps  []string
+	deb    rules.Debounce
+	repeat bool // reported in an earlier cycle already
+}
+
+// cycle collects the results of every check started during one scan so they
+// can be reduced per component once all of them have finished.
+type cycle struct {
+	mu      sync.Mutex
+	pending int           // checks begun but not ended
+	idle    chan struct{} // closed when pending drops to 0 while waited on
+	results []result
+}
+
+// begin registers a check that will report with add and then call end.
+func (c *cycle) begin() {
+	c.mu.Lock()
+	c.pending++
+	c.mu.Unlock()
+}
+
+func (c *cycle) end() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.pending--
+	if c.pending == 0 && c.idle != nil {
+		close(c.idle)
+		c.idle = nil
+	}
+}
+
+func (c *cycle) add(res result) {
+	c.mu.Lock()
+	c.results = append(c.results, res)
+	c.mu.Unlock()
+}
+
+// wait blocks until all checks have ended or ctx is done. Nothing is left
+// behind when ctx wins: checks that never end don't hold a goroutine.
+func (c *cycle) wait(ctx context.Context) bool {
+	c.mu.Lock()
+	if c.pending == 0 {
+		c.mu.Unlock()
+		return true
+	}
+	if c.idle == nil {
+		c.idle = make(chan struct{})
+	}
+	idle := c.idle
+	c.mu.Unlock()
+	select {
+	case <-idle:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// debounce runs the collected results through h; call it after wait.
+func (c *cycle) debounce(h *hysteresis, now time.Time) []result {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return h.apply(c.results, now)
+}
+
+// list returns a copy of the collected results.
+func (c *cycle) list() []result {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]result(nil), c.results...)
+}
+
+// reduce groups the collected statuses by component and applies the
+// aggregation policy of each one. Every component of comps is reported,
+// also one no target fed this cycle (a unit_glob matching nothing): the
+// policy reduces its empty set to down.
+func (c *cycle) reduce(agg rules.Aggregation, comps []string) map[string]int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	byComp := make(map[string][]int)
+	for _, comp := range comps {
+		byComp[comp] = nil
+	}
+	for _, res := range c.results {
+		for _, comp := range res.comps {
+			byComp[comp] = append(byComp[comp], res.status)
+		}
+	}
+	out := make(map[string]int, len(byComp))
+	for comp, sts := range byComp {
+		out[comp] = agg.PolicyFor(comp).Reduce(sts)
+	}
+	return out
+}
+
+// ruleComponents lists the components the check rules of rr feed.
+func ruleComponents(rr rules.Rules) []string {
+	var comps []string
+	for _, r := range rr.Systemd {
+		comps = append(comps, r.Components...)
+	}
+	for _, r := range rr.Docker {
+		comps = append(comps, r.Components...)
+	}
+	for _, r := range rr.Exec {
+		comps = append(comps, r.Components...)
+	}
+	for _, r := range rr.TCP {
+		comps = append(comps, r.Components...)
+	}
+	for _, r := range rr.HTTP {
+		comps = append(comps, r.Components...)
+	}
+	for _, r := range rr.Process {
+		comps = append(comps, r.Components...)
+	}
+	return comps
+}
+
+func sortedKeys(m map[string]int) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
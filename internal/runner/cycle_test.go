@@ -0,0 +1,64 @@
+package runner
+
+import (
+	"context"
+	"maps"
+	"testing"
+	"time"
+
+	"github.com/arenadata/ad-status-sender/internal/rules"
+)
+
+func TestCycle_ReduceReportsComponentsWithoutTargets(t *testing.T) {
+	rr := rules.Rules{
+		Systemd: []rules.RuleSystemd{
+			{UnitGlob: "none@*.service", Components: []string{"1"}},
+			{Unit: "a.service", Components: []string{"2"}},
+		},
+		Aggregation: rules.Aggregation{Components: map[string]rules.Policy{"1": {Mode: rules.PolicyAnyOK}}},
+	}
+	cyc := &cycle{}
+	cyc.add(result{kind: "systemd", target: "a.service", comps: []string{"2"}})
+
+	got := cyc.reduce(rr.Aggregation, ruleComponents(rr))
+	if want := map[string]int{"1": 1, "2": 0}; !maps.Equal(got, want) {
+		t.Fatalf("want %v, got %v", want, got)
+	}
+}
+
+func TestCycle_WaitGivesUpOnCancel(t *testing.T) {
+	cyc := &cycle{}
+	cyc.begin()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if cyc.wait(ctx) {
+		t.Fatal("wait must fail while a check is pending")
+	}
+
+	cyc.end()
+	if !cyc.wait(context.Background()) {
+		t.Fatal("wait must succeed once every check ended")
+	}
+}
+
+func TestHysteresis_RepeatedResultsDontCount(t *testing.T) {
+	var h hysteresis
+	now := time.Unix(0, 0)
+	deb := rules.Debounce{FailAfter: rules.Threshold{Count: 2}}
+	observe := func(status int, repeat bool) int {
+		res := []result{{kind: "exec", target: "zk", status: status, deb: deb, repeat: repeat}}
+		h.apply(res, now)
+		return res[0].status
+	}
+
+	observe(0, false)
+	if got := observe(1, false); got != 0 {
+		t.Fatalf("first failure must be held, got %d", got)
+	}
+	if got := observe(1, true); got != 0 {
+		t.Fatalf("a repeat of the same run counted as a second failure, got %d", got)
+	}
+	if got := observe(1, false); got != 1 {
+		t.Fatalf("second failed run must be reported, got %d", got)
+	}
+}
@@ -0,0 +1,83 @@
+package runner
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/arenadata/ad-status-sender/internal/rules"
+)
+
+// hysteresis holds back status changes of single check targets until they
+// persist for the rule's fail_after / recover_after. Like the send cache it
+// survives between cycles, but it works on raw results, before they are
+// reduced per component.
+type hysteresis struct {
+	mu      sync.Mutex
+	targets map[string]*debounceState
+}
+
+type debounceState struct {
+	reported int
+	pending  int
+	seen     int
+	since    time.Time
+}
+
+// apply replaces the status of every result with the debounced one and
+// returns the results whose change is being held back. Targets that did not
+// report in this cycle are forgotten.
+func (h *hysteresis) apply(results []result, now time.Time) []result {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.targets == nil {
+		h.targets = make(map[string]*debounceState)
+	}
+	var held []result
+	live := make(map[string]bool, len(results))
+	for i, res := range results {
+		key := res.kind + "|" + res.target + "|" + strings.Join(res.comps, ",")
+		live[key] = true
+		st, ok := h.targets[key]
+		if !ok {
+			// a new target counts as up, so a failure at start waits for
+			// fail_after like any other
+			st = &debounceState{}
+			h.targets[key] = st
+		}
+		results[i].status = st.observe(res.status, res.deb, now, !res.repeat)
+		if results[i].status != res.status {
+			held = append(held, res)
+		}
+	}
+	for key := range h.targets {
+		if !live[key] {
+			delete(h.targets, key)
+		}
+	}
+	return held
+}
+
+// observe feeds one raw status and returns the one to report. A status
+// that is not fresh was observed before and only lets time pass.
+func (s *debounceState) observe(status int, deb rules.Debounce, now time.Time, fresh bool) int {
+	if status == s.reported {
+		s.seen = 0
+		return s.reported
+	}
+	if s.seen == 0 || status != s.pending {
+		s.pending, s.seen, s.since = status, 0, now
+		fresh = true
+	}
+	if fresh {
+		s.seen++
+	}
+	th := deb.FailAfter
+	if status == 0 {
+		th = deb.RecoverAfter
+	}
+	if th.Reached(s.seen, s.since, now) {
+		s.reported, s.seen = status, 0
+	}
+	return s.reported
+}
@@ -0,0 +1,126 @@
+package runner_test
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/arenadata/ad-status-sender/internal/check/checktest"
+	"github.com/arenadata/ad-status-sender/internal/runner"
+	"github.com/arenadata/ad-status-sender/internal/runner/runnertest"
+)
+
+// lastStatuses returns the number of posts and the last status posted for
+// every component.
+func lastStatuses(post *runnertest.FakePoster) (int, map[string]int) {
+	sent := post.Snapshot()
+	out := make(map[string]int)
+	for _, s := range sent {
+		if !s.IsHost {
+			out[s.CompID] = s.Status
+		}
+	}
+	return len(sent), out
+}
+
+func TestRunner_HysteresisWithFakeClock(t *testing.T) {
+	dir := t.TempDir()
+	rulesPath := filepath.Join(dir, "rules.yaml")
+	writeFile(t, rulesPath, `
+systemd:
+  - unit: "hdfs-datanode.service"
+    components: ["1401"]
+    fail_after: 3
+    recover_after: "30s"
+  - unit: "yarn-nodemanager.service"
+    components: ["1402"]
+  - unit: "hbase-regionserver.service"
+    components: ["1403"]
+    fail_after: 2
+`)
+	// force_send_after below the tick makes every scan post the host and
+	// all three components, which tells the test that the scan is over
+	cfgPath := filepath.Join(dir, "config.yaml")
+	writeFile(t, cfgPath, `
+adcm_url: "http://adcm.invalid"
+host_id: 7
+token: "t"
+rules_path: "`+rulesPath+`"
+interval: "10s"
+force_send_after: "1s"
+concurrency: 2
+`)
+	const postsPerScan = 4
+
+	sd := &checktest.FakeSystemd{}
+	sd.Set("hdfs-datanode.service", true)
+	sd.Set("yarn-nodemanager.service", true)
+	sd.Set("hbase-regionserver.service", false)
+	post := &runnertest.FakePoster{}
+	clk := runnertest.NewFakeClock(time.Unix(1_000_000, 0))
+
+	r := runner.NewWithDeps(cfgPath, nil, sd, &checktest.FakeDocker{}, post, clk)
+	if err := r.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer r.Stop()
+
+	scans := 1
+	expect := func(want1401, want1402, want1403 int) {
+		t.Helper()
+		deadline := time.Now().Add(2 * time.Second)
+		for {
+			n, got := lastStatuses(post)
+			if n == scans*postsPerScan {
+				if got["1401"] != want1401 || got["1402"] != want1402 || got["1403"] != want1403 {
+					t.Fatalf("scan %d: want 1401=%d 1402=%d 1403=%d, got %v",
+						scans, want1401, want1402, want1403, got)
+				}
+				return
+			}
+			if n > scans*postsPerScan || time.Now().After(deadline) {
+				t.Fatalf("scan %d: want %d posts, got %d", scans, scans*postsPerScan, n)
+			}
+			time.Sleep(5 * time.Millisecond)
+		}
+	}
+	tick := func(d time.Duration) {
+		clk.Advance(d)
+		clk.Tick()
+		scans++
+	}
+
+	expect(0, 0, 0) // 1403 is down from the start, but fail_after holds it
+	sd.Set("hdfs-datanode.service", false)
+	sd.Set("yarn-nodemanager.service", false)
+	tick(10 * time.Second)
+	expect(0, 1, 1) // no thresholds: 1402 flips right away
+	tick(10 * time.Second)
+	expect(0, 1, 1) // 2 of 3 failures: held
+	tick(10 * time.Second)
+	expect(1, 1, 1)
+
+	// a recovery needs 30s of being up
+	sd.Set("hdfs-datanode.service", true)
+	tick(10 * time.Second)
+	expect(1, 1, 1)
+	tick(20 * time.Second)
+	expect(1, 1, 1)
+	tick(15 * time.Second)
+	expect(0, 1, 1)
+
+	// a failure streak interrupted by a success starts over
+	sd.Set("hdfs-datanode.service", false)
+	tick(10 * time.Second)
+	expect(0, 1, 1)
+	sd.Set("hdfs-datanode.service", true)
+	tick(10 * time.Second)
+	expect(0, 1, 1)
+	sd.Set("hdfs-datanode.service", false)
+	tick(10 * time.Second)
+	expect(0, 1, 1)
+	tick(10 * time.Second)
+	expect(0, 1, 1)
+	tick(10 * time.Second)
+	expect(1, 1, 1)
+}
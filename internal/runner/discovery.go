@@ -0,0 +1,337 @@
+package runner
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/arenadata/ad-status-sender/internal/config"
+	"github.com/arenadata/ad-status-sender/internal/rules"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+const (
+	maxDiscoveryPages = 100
+	cacheFilePerm     = 0o600
+)
+
+// componentIDs is the last known mapping of component refs to the IDs ADCM
+// has for them on this host. Its mutex also orders installs of resolved
+// rules, so that a rules reload and a refresh can't undo each other.
+type componentIDs struct {
+	mu    sync.Mutex
+	ids   map[rules.ComponentRef]string
+	known bool
+}
+
+// discoveryCache is what component_discovery.cache_file holds.
+type discoveryCache struct {
+	HostID     int              `json:"host_id"`
+	Components []discoveredComp `json:"components"`
+}
+
+type discoveredComp struct {
+	Service   string `json:"service"`
+	Component string `json:"component"`
+	ID        string `json:"id"`
+}
+
+// adcmComponent is one item of the ADCM answer. The service name is read
+// from service_name or from a nested service object, whichever is set.
+type adcmComponent struct {
+	ID          json.Number `json:"id"`
+	Name        string      `json:"name"`
+	ServiceName string      `json:"service_name"`
+	Service     struct {
+		Name string `json:"name"`
+	} `json:"service"`
+}
+
+// adcmPage is a paginated ADCM answer; unpaginated ones are a bare list.
+type adcmPage struct {
+	Results []adcmComponent `json:"results"`
+	Next    string          `json:"next"`
+}
+
+// discoverComponents sets the component IDs up before the first scan: from
+// the cache file, then from ADCM. Failing both, rules go on with the IDs
+// they give and their refs stay unresolved until a refresh succeeds.
+func (r *Runner) discoverComponents(ctx context.Context) {
+	cfg, _, _ := r.snapshot()
+	if !cfg.Discovery.Enabled {
+		return
+	}
+	r.loadComponentCache(cfg)
+	_ = r.refreshComponents(ctx)
+}
+
+// startComponentDiscovery refreshes the component IDs every
+// component_discovery.refresh until ctx is done.
+func (r *Runner) startComponentDiscovery(ctx context.Context, cfg config.Config) {
+	if !cfg.Discovery.Enabled {
+		return
+	}
+	t := r.clk.NewTicker(config.MustDuration(cfg.Discovery.Refresh, config.DefaultDiscoveryRefresh))
+	go func() {
+		defer t.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-t.C():
+				_ = r.refreshComponents(ctx)
+			}
+		}
+	}()
+}
+
+// refreshComponents asks ADCM for the components of the host. On failure
+// the last known IDs stay in use. A changed answer re-resolves the rules
+// and is written to the cache file.
+func (r *Runner) refreshComponents(ctx context.Context) error {
+	cfg, tok, _ := r.snapshot()
+	ids, err := r.fetchComponents(ctx, cfg, tok)
+	if err != nil {
+		r.met.discovered(false)
+		r.log.WarnContext(ctx, "component discovery failed, keeping last known IDs", "err", err)
+		return err
+	}
+	r.met.discovered(true)
+	if cur, _, _ := r.snapshot(); cur.HostID != cfg.HostID || cur.ADCMURL != cfg.ADCMURL {
+		return nil // an answer about the previous host; a refresh for the new one is on its way
+	}
+
+	r.comps.mu.Lock()
+	changed := !r.comps.known || !maps.Equal(r.comps.ids, ids)
+	r.comps.ids, r.comps.known = ids, true
+	r.comps.mu.Unlock()
+	if !changed {
+		return nil
+	}
+	r.log.InfoContext(ctx, "components discovered", "host", cfg.HostID, "components", len(ids))
+	if cfg.Discovery.CacheFile != "" {
+		if saveErr := saveComponentCache(cfg.Discovery.CacheFile, cfg.HostID, ids); saveErr != nil {
+			r.log.WarnContext(ctx, "component cache write failed", "file", cfg.Discovery.CacheFile, "err", saveErr)
+		}
+	}
+	r.installRules()
+	return nil
+}
+
+// forgetComponents drops the known component IDs, as if none had been
+// discovered yet.
+func (r *Runner) forgetComponents() {
+	r.comps.mu.Lock()
+	r.comps.ids, r.comps.known = nil, false
+	r.comps.mu.Unlock()
+}
+
+// installRules resolves the loaded rules with the known component IDs and
+// makes the result the rules the checks run.
+func (r *Runner) installRules() {
+	r.comps.mu.Lock()
+	defer r.comps.mu.Unlock()
+	rr, missing := r.loaded.Get().Resolve(r.comps.ids)
+	r.ruleStore.Set(rr)
+	if len(missing) == 0 {
+		return
+	}
+	refs := make([]string, len(missing))
+	for i, ref := range missing {
+		refs[i] = ref.String()
+	}
+	cfg, _, _ := r.snapshot()
+	switch {
+	case !cfg.Discovery.Enabled:
+		r.log.Warn("component refs need component_discovery, their rules feed no component", "refs", refs)
+	case !r.comps.known:
+		r.log.Warn("component IDs not discovered yet, rules using refs feed no component", "refs", refs)
+	default:
+		r.log.Warn("component refs not found on this host, their rules feed no component", "refs", refs)
+	}
+}
+
+// componentRefs returns the known mapping keyed "service/component".
+func (r *Runner) componentRefs() map[string]string {
+	r.comps.mu.Lock()
+	defer r.comps.mu.Unlock()
+	if len(r.comps.ids) == 0 {
+		return nil
+	}
+	out := make(map[string]string, len(r.comps.ids))
+	for ref, id := range r.comps.ids {
+		out[ref.String()] = id
+	}
+	return out
+}
+
+// loadComponentCache takes the IDs from the cache file unless some are
+// known already. A cache written for another host is ignored.
+func (r *Runner) loadComponentCache(cfg config.Config) {
+	if cfg.Discovery.CacheFile == "" {
+		return
+	}
+	data, err := os.ReadFile(cfg.Discovery.CacheFile)
+	if errors.Is(err, os.ErrNotExist) {
+		return
+	}
+	var c discoveryCache
+	if err == nil {
+		err = json.Unmarshal(data, &c)
+	}
+	if err != nil {
+		r.log.Warn("component cache unreadable", "file", cfg.Discovery.CacheFile, "err", err)
+		return
+	}
+	if c.HostID != cfg.HostID {
+		return
+	}
+	ids := make(map[rules.ComponentRef]string, len(c.Components))
+	for _, comp := range c.Components {
+		ids[rules.ComponentRef{Service: comp.Service, Component: comp.Component}] = comp.ID
+	}
+	r.comps.mu.Lock()
+	if !r.comps.known {
+		r.comps.ids, r.comps.known = ids, true
+	}
+	r.comps.mu.Unlock()
+	r.log.Info("component IDs loaded from cache", "file", cfg.Discovery.CacheFile, "components", len(ids))
+}
+
+func saveComponentCache(path string, hostID int, ids map[rules.ComponentRef]string) error {
+	c := discoveryCache{HostID: hostID, Components: make([]discoveredComp, 0, len(ids))}
+	for ref, id := range ids {
+		c.Components = append(c.Components, discoveredComp{Service: ref.Service, Component: ref.Component, ID: id})
+	}
+	data, err := json.Marshal(c)
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err = os.WriteFile(tmp, data, cacheFilePerm); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// fetchComponents reads the components of the host from ADCM, following
+// the pages of a paginated answer. A next page must be on adcm_url too, as
+// every request carries the token.
+func (r *Runner) fetchComponents(
+	ctx context.Context,
+	cfg config.Config,
+	token string,
+) (ids map[rules.ComponentRef]string, err error) {
+	ctx, span := r.tracer.Start(ctx, "discover components")
+	defer func() { endSpan(span, err) }()
+
+	path := cfg.Discovery.Path
+	if path == "" {
+		path = config.DefaultDiscoveryPath
+	}
+	base, err := url.Parse(cfg.ADCMURL)
+	if err != nil {
+		return nil, err
+	}
+	next := strings.TrimRight(cfg.ADCMURL, "/") + strings.ReplaceAll(path, "{host_id}", strconv.Itoa(cfg.HostID))
+	ids = make(map[rules.ComponentRef]string)
+	for page := 0; next != ""; page++ {
+		if page == maxDiscoveryPages {
+			return nil, fmt.Errorf("more than %d pages of components", maxDiscoveryPages)
+		}
+		cur := next
+		var items []adcmComponent
+		items, next, err = r.fetchComponentPage(ctx, cur, token)
+		if err != nil {
+			return nil, err
+		}
+		if next, err = samePlace(base, cur, next); err != nil {
+			return nil, err
+		}
+		for _, it := range items {
+			svc := it.ServiceName
+			if svc == "" {
+				svc = it.Service.Name
+			}
+			if it.ID == "" || it.Name == "" || svc == "" {
+				return nil, fmt.Errorf("component without id, name or service name: %+v", it)
+			}
+			ids[rules.ComponentRef{Service: svc, Component: it.Name}] = it.ID.String()
+		}
+	}
+	span.SetAttributes(attribute.Int("adcm.components", len(ids)))
+	return ids, nil
+}
+
+// samePlace resolves next, the link to the page after cur, and refuses it
+// unless it has the scheme and host of base.
+func samePlace(base *url.URL, cur, next string) (string, error) {
+	if next == "" {
+		return "", nil
+	}
+	from, err := url.Parse(cur)
+	if err != nil {
+		return "", err
+	}
+	u, err := url.Parse(next)
+	if err != nil {
+		return "", fmt.Errorf("next page: %w", err)
+	}
+	u = from.ResolveReference(u)
+	if u.Scheme != base.Scheme || u.Host != base.Host {
+		return "", fmt.Errorf("next page %s is not on adcm_url %s", next, base.Redacted())
+	}
+	return u.String(), nil
+}
+
+func (r *Runner) fetchComponentPage(ctx context.Context, pageURL, token string) ([]adcmComponent, string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, pageURL, nil)
+	if err != nil {
+		return nil, "", err
+	}
+	req.Header.Set("Authorization", "Token "+token)
+	req.Header.Set("Accept", "application/json")
+	resp, err := r.httpClient().Do(req)
+	if err != nil {
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, "", err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, "", newHTTPError(resp, string(data))
+	}
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '[' {
+		var items []adcmComponent
+		if err = json.Unmarshal(trimmed, &items); err != nil {
+			return nil, "", fmt.Errorf("decode components: %w", err)
+		}
+		return items, "", nil
+	}
+	var page adcmPage
+	if err = json.Unmarshal(data, &page); err != nil {
+		return nil, "", fmt.Errorf("decode components: %w", err)
+	}
+	return page.Results, page.Next, nil
+}
+
+func (r *Runner) httpClient() *http.Client {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if r.client == nil {
+		return http.DefaultClient
+	}
+	return r.client
+}
@@ -0,0 +1,275 @@
+package runner
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/arenadata/ad-status-sender/internal/check/checktest"
+	"github.com/arenadata/ad-status-sender/internal/config"
+	"github.com/arenadata/ad-status-sender/internal/rules"
+)
+
+// adcmComponents stands in for ADCM: it lists the components of host 7
+// and accepts status posts.
+type adcmComponents struct {
+	adcmStub
+	list string
+	down bool
+}
+
+func (s *adcmComponents) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.adcmStub.ServeHTTP(w, r)
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	switch {
+	case r.Header.Get("Authorization") != "Token t":
+		w.WriteHeader(http.StatusUnauthorized)
+	case s.down:
+		w.WriteHeader(http.StatusServiceUnavailable)
+	case r.URL.Path != "/api/v1/host/7/component/":
+		w.WriteHeader(http.StatusNotFound)
+	default:
+		_, _ = w.Write([]byte(s.list))
+	}
+}
+
+func (s *adcmComponents) set(list string, down bool) {
+	s.mu.Lock()
+	s.list, s.down = list, down
+	s.mu.Unlock()
+}
+
+func TestRunner_ResolvesComponentRefs(t *testing.T) {
+	stub := &adcmComponents{}
+	stub.set(`[{"id": 202, "name": "regionserver", "service_name": "hbase"},
+		{"id": 203, "name": "master", "service_name": "hbase"}]`, false)
+	srv := httptest.NewServer(stub)
+	defer srv.Close()
+
+	dir := t.TempDir()
+	write := func(name, data string) string {
+		t.Helper()
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	rulesPath := write("rules.yaml", `systemd:
+  - unit: a.service
+    components: ["1"]
+    component_refs:
+      - {service: hbase, component: regionserver}
+      - {service: hdfs, component: datanode}
+aggregation:
+  components:
+    "hbase/regionserver": any_ok
+`)
+	cachePath := filepath.Join(dir, "components.json")
+	cfgPath := write("config.yaml", fmt.Sprintf("adcm_url: %s\nhost_id: 7\ntoken: t\nrules_path: %s\n"+
+		"component_discovery:\n  enabled: true\n  cache_file: %s\n", srv.URL, rulesPath, cachePath))
+	sd := &checktest.FakeSystemd{Units: map[string]bool{"a.service": true}}
+	ctx := context.Background()
+
+	r := NewWithDeps(cfgPath, nil, sd, &checktest.FakeDocker{}, nil, nil)
+	rep, err := r.RunOnce(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := rep.Components["202"]; !ok || len(rep.Components) != 2 {
+		t.Fatalf("want components 1 and 202, got %v", rep.Components)
+	}
+	if !stub.got("/status/api/v1/host/7/component/202/") {
+		t.Fatal("status of hbase/regionserver not posted to its ID")
+	}
+	wantResolved(t, r, "1", "202")
+
+	// a recreated cluster renumbers its components
+	stub.set(`[{"id": 302, "name": "regionserver", "service_name": "hbase"}]`, false)
+	if err = r.refreshComponents(ctx); err != nil {
+		t.Fatal(err)
+	}
+	wantResolved(t, r, "1", "302")
+	if loaded := r.loaded.Get(); !slices.Equal(loaded.Systemd[0].Components, []string{"1"}) {
+		t.Fatalf("loaded rules modified: %v", loaded.Systemd[0].Components)
+	}
+
+	// ADCM down: the last known IDs stay, also for a fresh start
+	stub.set("", true)
+	if err = r.refreshComponents(ctx); err == nil {
+		t.Fatal("want an error while ADCM is down")
+	}
+	wantResolved(t, r, "1", "302")
+	wantSamples(t, scrape(t, r.metrics),
+		`ad_status_sender_component_discoveries_total{result="ok"} 2`,
+		`ad_status_sender_component_discoveries_total{result="fail"} 1`,
+		`ad_status_sender_discovered_components 1`)
+
+	restarted := NewWithDeps(cfgPath, nil, sd, &checktest.FakeDocker{}, nil, nil)
+	if _, err = restarted.RunOnce(ctx); err != nil {
+		t.Fatal(err)
+	}
+	wantResolved(t, restarted, "1", "302")
+}
+
+func TestRunner_FetchComponentsPaginated(t *testing.T) {
+	var srv *httptest.Server
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// IDs as strings or numbers and the service nested, as paginated APIs may answer
+		page := map[string]any{
+			"results": []map[string]any{
+				{"id": "11", "name": "server", "service": map[string]string{"name": "zookeeper"}},
+			},
+			"next": srv.URL + "/api/v2/hosts/3/components/?offset=1",
+		}
+		if r.URL.Query().Get("offset") == "1" {
+			page = map[string]any{
+				"results": []map[string]any{
+					{"id": 12, "name": "datanode", "service": map[string]string{"name": "hdfs"}},
+				},
+				"next": nil,
+			}
+		}
+		_ = json.NewEncoder(w).Encode(page)
+	}))
+	defer srv.Close()
+
+	r := NewWithDeps("", nil, nil, nil, nil, nil)
+	cfg := r.cfg
+	cfg.ADCMURL, cfg.HostID, cfg.Discovery.Path = srv.URL+"/", 3, "/api/v2/hosts/{host_id}/components/"
+	ids, err := r.fetchComponents(context.Background(), cfg, "t")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[rules.ComponentRef]string{
+		{Service: "zookeeper", Component: "server"}: "11",
+		{Service: "hdfs", Component: "datanode"}:    "12",
+	}
+	if !maps.Equal(ids, want) {
+		t.Fatalf("want %v, got %v", want, ids)
+	}
+}
+
+func TestRunner_FetchComponentsStaysOnADCM(t *testing.T) {
+	var foreignHits atomic.Int32
+	foreign := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		foreignHits.Add(1)
+		_, _ = w.Write([]byte(`{"results": []}`))
+	}))
+	defer foreign.Close()
+	var next string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		page := map[string]any{"results": []map[string]any{}, "next": next}
+		if r.URL.Query().Get("offset") == "1" {
+			page = map[string]any{
+				"results": []map[string]any{{"id": 12, "name": "datanode", "service_name": "hdfs"}},
+				"next":    nil,
+			}
+		}
+		_ = json.NewEncoder(w).Encode(page)
+	}))
+	defer srv.Close()
+
+	r := NewWithDeps("", nil, nil, nil, nil, nil)
+	cfg := r.cfg
+	cfg.ADCMURL, cfg.HostID = srv.URL, 3
+
+	// a relative link is resolved against the page it came in
+	next = "?offset=1"
+	ids, err := r.fetchComponents(context.Background(), cfg, "t")
+	if err != nil || len(ids) != 1 {
+		t.Fatalf("relative next page: want 1 component, got %v, %v", ids, err)
+	}
+
+	for _, next = range []string{
+		foreign.URL + "/api/v1/host/3/component/?offset=1",
+		strings.Replace(srv.URL, "http://", "https://", 1) + "/api/v1/host/3/component/?offset=1",
+	} {
+		if _, err = r.fetchComponents(context.Background(), cfg, "t"); err == nil ||
+			!strings.Contains(err.Error(), "is not on adcm_url") {
+			t.Fatalf("next page %s: want it refused, got %v", next, err)
+		}
+	}
+	if n := foreignHits.Load(); n != 0 {
+		t.Fatalf("the token went to another host %d times", n)
+	}
+}
+
+func TestRunner_HostChangeForgetsComponents(t *testing.T) {
+	// ADCM takes its time to answer for the new host
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-release:
+		}
+	}))
+	defer srv.Close()
+	defer close(release)
+
+	cfg := config.Config{ADCMURL: srv.URL, HostID: 7, Discovery: config.ComponentDiscovery{Enabled: true}}
+	r := newTestRunner(t, cfg, &checktest.FakeSystemd{}, &checktest.FakeDocker{}, &testPoster{}, &testClock{})
+	r.token = "t"
+	rs := rules.ComponentRef{Service: "hbase", Component: "regionserver"}
+	r.loaded.Set(rules.Rules{
+		Systemd: []rules.RuleSystemd{
+			{Unit: "a.service", Components: []string{"1"}, ComponentRefs: []rules.ComponentRef{rs}},
+		},
+		Aggregation: rules.Aggregation{
+			Components: map[string]rules.Policy{"hbase/regionserver": {Mode: rules.PolicyAnyOK}},
+		},
+	})
+	r.comps.ids, r.comps.known = map[rules.ComponentRef]string{rs: "202"}, true
+	r.installRules()
+	wantResolved(t, r, "1", "202")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	r.workers.ctx = ctx
+	moved := cfg
+	moved.HostID = 8
+	r.mu.Lock()
+	r.cfg = moved
+	r.mu.Unlock()
+	r.reconfigure(cfg, moved)
+
+	// right away, before ADCM answers for the new host
+	if got := r.ruleStore.Get().Systemd[0].Components; !slices.Equal(got, []string{"1"}) {
+		t.Fatalf("IDs of host 7 still in use: %v", got)
+	}
+	if r.componentRefs() != nil {
+		t.Fatalf("known IDs not cleared: %v", r.componentRefs())
+	}
+
+	// the refresh is given up with the runner
+	cancel()
+	waitUntil(t, func() bool {
+		return strings.Contains(scrape(t, r.metrics), `ad_status_sender_component_discoveries_total{result="fail"} 1`)
+	}, time.Second)
+}
+
+// wantResolved checks the components of the first systemd rule and that
+// the aggregation policy follows the hbase/regionserver ID.
+func wantResolved(t *testing.T, r *Runner, comps ...string) {
+	t.Helper()
+	rr := r.ruleStore.Get()
+	if got := rr.Systemd[0].Components; !slices.Equal(got, comps) {
+		t.Fatalf("want components %v, got %v", comps, got)
+	}
+	if _, ok := rr.Aggregation.Components[comps[len(comps)-1]]; !ok || len(rr.Aggregation.Components) != 1 {
+		t.Fatalf("aggregation not keyed by %s: %v", comps[len(comps)-1], rr.Aggregation.Components)
+	}
+}
@@ -0,0 +1,56 @@
+package runner
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/arenadata/ad-status-sender/internal/check/checktest"
+	"github.com/arenadata/ad-status-sender/internal/config"
+	"github.com/arenadata/ad-status-sender/internal/rules"
+)
+
+func TestRunner_DockerHealthPolicy(t *testing.T) {
+	now := time.Unix(10_000, 0)
+	dck := &checktest.FakeDocker{
+		Names:   map[string]bool{"web": true, "api": true, "cache": true},
+		Health:  map[string]string{"web": "unhealthy", "api": "starting", "cache": "healthy"},
+		Started: map[string]time.Time{"api": now.Add(-30 * time.Second)},
+		Now:     func() time.Time { return now },
+		// the same containers found by label
+		LabelMembers: map[string][]string{"app=web": {"web"}, "app=api": {"api", "cache"}},
+	}
+	post := &testPoster{}
+	cfg := config.Config{ADCMURL: "http://example", HostID: 7, ForceSendAfter: "120s"}
+	r := newTestRunner(t, cfg, &checktest.FakeSystemd{}, dck, post, &testClock{now: now})
+
+	names := func(n ...string) rules.DockerSelector { return rules.DockerSelector{Names: n} }
+	labels := func(l ...string) rules.DockerSelector { return rules.DockerSelector{Labels: l} }
+	r.ruleStore.Set(rules.Rules{
+		Docker: []rules.RuleDocker{
+			{Name: "legacy", Containers: names("web"), Components: []string{"1201"}},
+			{Name: "strict", Containers: names("web"), RequireHealthy: true, Components: []string{"1202"}},
+			{Name: "warming", Containers: names("api"), RequireHealthy: true, AllowStartingFor: "60s",
+				Components: []string{"1203"}},
+			{Name: "no-grace", Containers: names("api", "cache"), RequireHealthy: true, Components: []string{"1204"}},
+			{Name: "healthy", Containers: names("cache"), RequireHealthy: true, Components: []string{"1205"}},
+			{Name: "by-label", Containers: labels("app=web"), Components: []string{"1206"}},
+			{Name: "strict-label", Containers: labels("app=web"), RequireHealthy: true, Components: []string{"1207"}},
+			{Name: "warming-label", Containers: labels("app=api"), RequireHealthy: true, AllowStartingFor: "60s",
+				Components: []string{"1208"}},
+		},
+	})
+
+	r.scanOnce(context.Background())
+	waitUntil(t, func() bool { return post.Count() == 9 }, time.Second)
+
+	want := map[string]int{"1201": 0, "1202": 1, "1203": 0, "1204": 1, "1205": 0, "1206": 0, "1207": 1, "1208": 0}
+	for _, e := range post.Snapshot() {
+		if e.IsHost {
+			continue
+		}
+		if want[e.CompID] != e.Status {
+			t.Fatalf("comp %s: want %d, got %d", e.CompID, want[e.CompID], e.Status)
+		}
+	}
+}
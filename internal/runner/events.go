@@ -0,0 +1,247 @@
+package runner
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/arenadata/ad-status-sender/internal/check"
+	"github.com/arenadata/ad-status-sender/internal/config"
+)
+
+const (
+	watchRetryMin = time.Second
+	watchRetryMax = time.Minute
+)
+
+// unitCache holds systemd unit statuses pushed by a SystemdWatcher, along
+// with the glob expansions of the last reconciliation and the units that
+// moved since their restarts were sampled. While a watch is live, scans
+// answer from it and only hit D-Bus for unknown units and on reconciliation
+// cycles.
+type unitCache struct {
+	mu     sync.Mutex
+	live   bool
+	states map[string]int
+	globs  map[string][]string
+	moved  map[string]bool
+	polled time.Time
+}
+
+func (u *unitCache) setLive(live bool) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.live = live
+	u.states = make(map[string]int)
+	u.globs = make(map[string][]string)
+	u.moved = make(map[string]bool)
+}
+
+// reconcileDue reports whether this cycle must poll every unit and, if so,
+// records now as the last full poll.
+func (u *unitCache) reconcileDue(now time.Time, every time.Duration) bool {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if !u.live || now.Sub(u.polled) >= every {
+		u.polled = now
+		return true
+	}
+	return false
+}
+
+func (u *unitCache) get(unit string) (int, bool) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if !u.live {
+		return 0, false
+	}
+	st, ok := u.states[unit]
+	return st, ok
+}
+
+func (u *unitCache) store(unit string, status int) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if u.live {
+		u.states[unit] = status
+	}
+}
+
+// update applies a pushed transition and reports whether the unit is one
+// the rules care about (it has been checked at least once).
+func (u *unitCache) update(unit string, status int) bool {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	prev, known := u.states[unit]
+	if !u.live || !known {
+		return false
+	}
+	u.states[unit] = status
+	if prev == status {
+		return false
+	}
+	u.moved[unit] = true
+	return true
+}
+
+func (u *unitCache) expansion(glob string) ([]string, bool) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if !u.live {
+		return nil, false
+	}
+	units, ok := u.globs[glob]
+	return units, ok
+}
+
+func (u *unitCache) storeExpansion(glob string, units []string) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if u.live {
+		u.globs[glob] = units
+	}
+}
+
+// takeMoved reports whether unit changed state since the last call.
+func (u *unitCache) takeMoved(unit string) bool {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	moved := u.moved[unit]
+	delete(u.moved, unit)
+	return moved
+}
+
+func (r *Runner) startSystemdEvents(ctx context.Context, cfg config.Config) {
+	if !cfg.Events.Systemd {
+		return
+	}
+	w, ok := r.sd.(check.SystemdWatcher)
+	if !ok {
+		r.log.Warn("systemd events requested but backend cannot watch, polling only")
+		return
+	}
+	go r.keepWatching(ctx, "systemd", func(ctx context.Context) error {
+		r.units.setLive(true)
+		defer r.units.setLive(false)
+		return w.WatchUnits(ctx, r.onUnitChange)
+	})
+}
+
+func (r *Runner) startDockerEvents(ctx context.Context, cfg config.Config) {
+	if !cfg.Events.Docker {
+		return
+	}
+	w, ok := r.dck.(check.DockerWatcher)
+	if !ok {
+		r.log.Warn("docker events requested but backend cannot watch, polling only")
+		return
+	}
+	go r.keepWatching(ctx, "docker", func(ctx context.Context) error {
+		return w.WatchContainers(ctx, r.onContainerChange)
+	})
+}
+
+// keepWatching runs watch until ctx is done, resubscribing with exponential
+// backoff whenever it stops. Scans poll while no watch is live.
+func (r *Runner) keepWatching(ctx context.Context, source string, watch func(context.Context) error) {
+	var backoff time.Duration
+	for {
+		started := time.Now()
+		err := watch(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		backoff = nextWatchRetry(backoff, time.Since(started))
+		r.log.Warn("watch stopped, falling back to polling", "source", source, "err", err, "retry_in", backoff)
+		r.kick()
+		if !sleepCtx(ctx, backoff) {
+			return
+		}
+	}
+}
+
+// nextWatchRetry returns the wait before resubscribing after a watch that
+// held for held: watchRetryMin at first and after a watch that held longer
+// than watchRetryMax, otherwise twice the previous wait, up to watchRetryMax.
+func nextWatchRetry(prev, held time.Duration) time.Duration {
+	if prev == 0 || held > watchRetryMax {
+		return watchRetryMin
+	}
+	return min(prev*2, watchRetryMax)
+}
+
+func (r *Runner) onUnitChange(unit string, status int) {
+	if r.units.update(unit, status) {
+		r.log.Debug("systemd unit changed", "unit", unit, "status", status)
+		r.kick()
+	}
+}
+
+func (r *Runner) onContainerChange(name string, labels map[string]string) {
+	for _, d := range r.ruleStore.Get().Docker {
+		if slices.Contains(d.Containers.Names, name) ||
+			(len(d.Containers.Names) == 0 && len(d.Containers.Labels) > 0 &&
+				check.MatchLabels(labels, d.Containers.Labels)) {
+			r.log.Debug("docker container changed", "container", name, "group", d.Name)
+			r.kick()
+			return
+		}
+	}
+}
+
+// unitStatus answers from the event cache unless the cycle reconciles.
+func (r *Runner) unitStatus(ctx context.Context, unit string, reconcile bool) int {
+	if !reconcile {
+		if st, ok := r.units.get(unit); ok {
+			return st
+		}
+	}
+	st := r.sd.SystemdStatus(ctx, unit)
+	r.units.store(unit, st)
+	return st
+}
+
+// expandGlob answers from the event cache unless the cycle reconciles, so
+// that event-driven scans don't list units over D-Bus every time.
+func (r *Runner) expandGlob(ctx context.Context, glob string, reconcile bool) []string {
+	if !reconcile {
+		if units, ok := r.units.expansion(glob); ok {
+			return units
+		}
+	}
+	units := r.sd.ExpandUnitsByGlob(ctx, glob)
+	r.units.storeExpansion(glob, units)
+	return units
+}
+
+// systemdRestarts samples the restarts of unit on reconciliation cycles and
+// after the unit moved. Otherwise the last sample stands in, which keeps the
+// restart window sliding without asking D-Bus.
+func (r *Runner) systemdRestarts(ctx context.Context, unit string, reconcile bool) map[string]check.Restarts {
+	if !reconcile && !r.units.takeMoved(unit) {
+		if s, ok := r.restarts.last("systemd:" + unit); ok {
+			return map[string]check.Restarts{unit: s}
+		}
+	}
+	return r.unitRestarts(ctx, unit)
+}
+
+// kick requests an immediate scan outside of the ticker schedule.
+func (r *Runner) kick() {
+	select {
+	case r.trigger <- struct{}{}:
+	default:
+	}
+}
+
+func sleepCtx(ctx context.Context, d time.Duration) bool {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-t.C:
+		return true
+	}
+}
@@ -0,0 +1,189 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/arenadata/ad-status-sender/internal/check/checktest"
+	"github.com/arenadata/ad-status-sender/internal/config"
+	"github.com/arenadata/ad-status-sender/internal/rules"
+)
+
+func TestRunner_SystemdEventsPushTransitions(t *testing.T) {
+	sd := &checktest.FakeSystemd{
+		Units: map[string]bool{"nginx.service": true, "app@1.service": true},
+		Globs: map[string][]string{"app@*.service": {"app@1.service"}},
+	}
+	post := &testPoster{}
+	clk := &testClock{now: time.Unix(0, 0)}
+
+	cfg := config.Config{
+		ADCMURL:        "http://example",
+		HostID:         7,
+		ForceSendAfter: "120s",
+		Events:         config.Events{Systemd: true, ReconcileInterval: "60s"},
+	}
+	r := newTestRunner(t, cfg, sd, &checktest.FakeDocker{}, post, clk)
+
+	r.ruleStore.Set(rules.Rules{
+		Systemd: []rules.RuleSystemd{
+			{Unit: "nginx.service", Components: []string{"501"}},
+			{UnitGlob: "app@*.service", Components: []string{"502"}},
+		},
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	r.startSystemdEvents(ctx, r.cfg)
+	waitUntil(t, sd.Watching, 500*time.Millisecond)
+
+	r.scanOnce(ctx)
+	waitUntil(t, func() bool { return post.Count() == 3 }, 300*time.Millisecond)
+
+	// a change that is not pushed is invisible until reconciliation
+	post.Reset()
+	sd.Set("nginx.service", false)
+	r.scanOnce(ctx)
+	time.Sleep(20 * time.Millisecond)
+	if got := post.Count(); got != 0 {
+		t.Fatalf("expected cached status between reconciliations, got %d posts", got)
+	}
+
+	// a pushed transition triggers an immediate scan
+	sd.Emit("nginx.service", false)
+	select {
+	case <-r.trigger:
+	default:
+		t.Fatalf("event did not request a scan")
+	}
+	r.scanOnce(ctx)
+	waitUntil(t, func() bool { return post.Count() == 1 }, 300*time.Millisecond)
+	if ss := post.Snapshot(); ss[0].CompID != "501" || ss[0].Status != 1 {
+		t.Fatalf("want 501=1, got %+v", ss)
+	}
+	if got := sd.Expansions(); got != 1 {
+		t.Fatalf("globs expanded %d times between reconciliations, want 1", got)
+	}
+
+	// reconciliation polls D-Bus again
+	time.Sleep(20 * time.Millisecond)
+	post.Reset()
+	sd.Set("nginx.service", true)
+	clk.advance(61 * time.Second)
+	r.scanOnce(ctx)
+	waitUntil(t, func() bool { return post.Count() == 1 }, 300*time.Millisecond)
+	if ss := post.Snapshot(); ss[0].CompID != "501" || ss[0].Status != 0 {
+		t.Fatalf("want 501=0 after reconcile, got %+v", ss)
+	}
+	if got := sd.Expansions(); got != 2 {
+		t.Fatalf("reconciliation must expand globs again, got %d expansions", got)
+	}
+}
+
+func TestRunner_DockerEventsTriggerAffectedGroups(t *testing.T) {
+	dck := &checktest.FakeDocker{Names: map[string]bool{"db": true}}
+	post := &testPoster{}
+	clk := &testClock{now: time.Unix(0, 0)}
+
+	cfg := config.Config{
+		ADCMURL:        "http://example",
+		HostID:         7,
+		ForceSendAfter: "120s",
+		Events:         config.Events{Docker: true},
+	}
+	r := newTestRunner(t, cfg, &checktest.FakeSystemd{}, dck, post, clk)
+
+	r.ruleStore.Set(rules.Rules{
+		Docker: []rules.RuleDocker{
+			{Name: "core", Components: []string{"601"}, Containers: rules.DockerSelector{Names: []string{"db"}}},
+			{Name: "etl", Components: []string{"701"}, Containers: rules.DockerSelector{Labels: []string{"app=etl"}}},
+		},
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	r.startDockerEvents(ctx, r.cfg)
+	waitUntil(t, dck.Watching, 500*time.Millisecond)
+
+	dck.Emit("unrelated", map[string]string{"app": "web"}, false)
+	select {
+	case <-r.trigger:
+		t.Fatalf("unrelated container must not trigger a scan")
+	default:
+	}
+
+	dck.Emit("worker-1", map[string]string{"app": "etl"}, false)
+	select {
+	case <-r.trigger:
+	default:
+		t.Fatalf("label-matched container did not trigger a scan")
+	}
+
+	dck.Emit("db", nil, false)
+	select {
+	case <-r.trigger:
+	default:
+		t.Fatalf("named container did not trigger a scan")
+	}
+
+	r.scanOnce(ctx)
+	waitUntil(t, func() bool { return post.Count() == 3 }, 300*time.Millisecond)
+	for _, e := range post.Snapshot() {
+		if e.CompID == "601" && e.Status != 1 {
+			t.Fatalf("want 601=1 after die event, got %+v", e)
+		}
+	}
+}
+
+// brokenWatcher is a docker backend whose first events stream breaks.
+type brokenWatcher struct {
+	checktest.FakeDocker
+	watches atomic.Int32
+}
+
+func (w *brokenWatcher) WatchContainers(ctx context.Context, _ func(string, map[string]string)) error {
+	if w.watches.Add(1) == 1 {
+		return errors.New("docker: events stream closed")
+	}
+	<-ctx.Done()
+	return nil
+}
+
+func TestRunner_DockerWatchResubscribesAfterBreak(t *testing.T) {
+	dck := &brokenWatcher{}
+	cfg := config.Config{ADCMURL: "http://example", HostID: 7, Events: config.Events{Docker: true}}
+	r := newTestRunner(t, cfg, &checktest.FakeSystemd{}, dck, &testPoster{}, &testClock{now: time.Unix(0, 0)})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	r.startDockerEvents(ctx, r.cfg)
+
+	// the break requests a polling scan right away
+	select {
+	case <-r.trigger:
+	case <-time.After(time.Second):
+		t.Fatal("broken stream did not request a scan")
+	}
+	waitUntil(t, func() bool { return dck.watches.Load() == 2 }, watchRetryMin+time.Second)
+}
+
+func TestNextWatchRetry(t *testing.T) {
+	cases := []struct {
+		prev, held, want time.Duration
+	}{
+		{prev: 0, held: 0, want: watchRetryMin},
+		{prev: watchRetryMin, held: time.Second, want: 2 * watchRetryMin},
+		{prev: 16 * time.Second, held: time.Second, want: 32 * time.Second},
+		{prev: 32 * time.Second, held: time.Second, want: watchRetryMax},
+		{prev: watchRetryMax, held: time.Second, want: watchRetryMax},
+		{prev: watchRetryMax, held: 2 * watchRetryMax, want: watchRetryMin},
+	}
+	for _, tc := range cases {
+		if got := nextWatchRetry(tc.prev, tc.held); got != tc.want {
+			t.Fatalf("nextWatchRetry(%v, %v) = %v, want %v", tc.prev, tc.held, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,210 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/arenadata/ad-status-sender/internal/check"
+	"github.com/arenadata/ad-status-sender/internal/config"
+	"github.com/arenadata/ad-status-sender/internal/rules"
+)
+
+// execRuns keeps the last finished run of every exec rule. Scans report it
+// instead of waiting for scripts, so one hung script can't hold up the
+// statuses of every other check. Runs are keyed by execKey.
+type execRuns struct {
+	mu    sync.Mutex
+	rules map[string]*execRun
+}
+
+type execRun struct {
+	last     result
+	done     bool     // last is set
+	reported bool     // last has been reported by a scan
+	running  bool     // a run is in progress
+	waiting  []*cycle // cycles waiting for the first run
+}
+
+// execKey identifies the i-th exec rule. The name is optional and may
+// repeat, so the position is part of the key; the command is too, so a
+// different rule moving into the slot doesn't inherit the last run.
+func execKey(i int, rule rules.RuleExec) string {
+	return fmt.Sprintf("%d|%s|%s|%s", i, rule.Name, rule.Command, strings.Join(rule.Args, " "))
+}
+
+// execTarget names the target of an exec rule: its name or, for a rule
+// without one, its command line.
+func execTarget(rule rules.RuleExec) string {
+	if rule.Name != "" {
+		return rule.Name
+	}
+	return strings.Join(append([]string{rule.Command}, rule.Args...), " ")
+}
+
+// take returns what a scan should report for the rule under key and whether a new
+// run must be started. Before the first run finishes, cyc is made to wait
+// for it. A result reported before is returned as a repeat, which doesn't
+// count as a new observation for debouncing.
+func (x *execRuns) take(key string, cyc *cycle) (result, bool, bool) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	if x.rules == nil {
+		x.rules = make(map[string]*execRun)
+	}
+	run, ok := x.rules[key]
+	if !ok {
+		run = &execRun{}
+		x.rules[key] = run
+	}
+	start := !run.running
+	run.running = true
+	if !run.done {
+		cyc.begin()
+		run.waiting = append(run.waiting, cyc)
+		return result{}, false, start
+	}
+	res := run.last
+	res.repeat = run.reported
+	run.reported = true
+	return res, true, start
+}
+
+// finish records a finished run, or a run that never started if ok is
+// false. It reports whether the status changed unnoticed by any scan.
+func (x *execRuns) finish(key string, res result, ok bool) bool {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	run := x.rules[key]
+	run.running = false
+	changed := ok && len(run.waiting) == 0 && (!run.done || run.last.status != res.status)
+	if ok {
+		run.last, run.done, run.reported = res, true, len(run.waiting) > 0
+	}
+	for _, cyc := range run.waiting {
+		if ok {
+			cyc.add(res)
+		}
+		cyc.end()
+	}
+	run.waiting = nil
+	return changed
+}
+
+// forget drops the runs of rules no longer configured.
+func (x *execRuns) forget(rr []rules.RuleExec) {
+	live := make(map[string]bool, len(rr))
+	for i, rule := range rr {
+		live[execKey(i, rule)] = true
+	}
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	for key, run := range x.rules {
+		if !run.running && !live[key] {
+			delete(x.rules, key)
+		}
+	}
+}
+
+// scanExec reports the last finished run of every exec check and starts a
+// new run where none is in progress. Runs happen outside the worker pool,
+// at most exec_concurrency at a time, so slow scripts can't starve other
+// checks; a run that changes a status requests a scan.
+func (r *Runner) scanExec(ctx context.Context, cyc *cycle, rr rules.Rules) {
+	r.execs.forget(rr.Exec)
+	if len(rr.Exec) == 0 {
+		return
+	}
+	sem := r.getExecSem()
+	for i, rule := range rr.Exec {
+		key := execKey(i, rule)
+		comps := append([]string(nil), rule.Components...)
+		last, ok, start := r.execs.take(key, cyc)
+		if ok {
+			last.comps, last.deb = comps, rule.Debounce
+			cyc.add(last)
+		}
+		if !start {
+			continue
+		}
+		go func() {
+			select {
+			case sem <- struct{}{}:
+			case <-ctx.Done():
+				r.execs.finish(key, result{}, false)
+				return
+			}
+			defer func() { <-sem }()
+			res := r.measure(ctx, func() result {
+				st := r.runExec(ctx, rule)
+				return result{
+					kind: "exec", index: i, target: execTarget(rule), status: st, comps: comps, deb: rule.Debounce,
+				}
+			})
+			if r.execs.finish(key, res, true) {
+				r.kick()
+			}
+		}()
+	}
+}
+
+func (r *Runner) runExec(ctx context.Context, rule rules.RuleExec) int {
+	if r.exec == nil {
+		return 1
+	}
+	res := r.exec.Run(ctx, execSpec(rule))
+	switch {
+	case res.TimedOut:
+		r.log.WarnContext(ctx, "exec check timed out", "rule", execTarget(rule), "output", res.Output)
+		return 1
+	case res.ExitCode < 0:
+		r.log.WarnContext(ctx, "exec check failed to run", "rule", execTarget(rule), "err", res.Err)
+		return 1
+	}
+	st := exitStatus(rule.ExitCodes, res.ExitCode)
+	r.log.DebugContext(ctx, "exec check",
+		"rule", execTarget(rule), "exit_code", res.ExitCode, "status", st, "output", res.Output)
+	return st
+}
+
+func execSpec(rule rules.RuleExec) check.ExecSpec {
+	env := make([]string, 0, len(rule.Env))
+	for k, v := range rule.Env {
+		env = append(env, k+"="+v)
+	}
+	sort.Strings(env)
+	return check.ExecSpec{
+		Command: rule.Command,
+		Args:    rule.Args,
+		Env:     env,
+		Dir:     rule.Dir,
+		Timeout: config.MustDuration(rule.Timeout, check.DefaultExecTimeout),
+	}
+}
+
+func exitStatus(mapping map[int]int, code int) int {
+	if st, ok := mapping[code]; ok {
+		return st
+	}
+	if code == 0 {
+		return 0
+	}
+	return 1
+}
+
+// getExecSem returns the semaphore bounding concurrent exec checks,
+// recreating it when exec_concurrency changes.
+func (r *Runner) getExecSem() chan struct{} {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	n := r.cfg.ExecConcurrency
+	if n <= 0 {
+		n = config.DefaultExecConcurrency
+	}
+	if cap(r.execSem) != n {
+		r.execSem = make(chan struct{}, n)
+	}
+	return r.execSem
+}
@@ -0,0 +1,163 @@
+package runner
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/arenadata/ad-status-sender/internal/check"
+	"github.com/arenadata/ad-status-sender/internal/check/checktest"
+	"github.com/arenadata/ad-status-sender/internal/config"
+	"github.com/arenadata/ad-status-sender/internal/rules"
+)
+
+func TestRunner_ExecChecks(t *testing.T) {
+	ex := &checktest.FakeExec{
+		Delay: 20 * time.Millisecond,
+		Results: map[string]check.ExecResult{
+			"zk-ok":   {ExitCode: 0},
+			"zk-warn": {ExitCode: 1},
+			"hms":     {ExitCode: 2},
+			"hung":    {ExitCode: -1, TimedOut: true},
+		},
+	}
+	post := &testPoster{}
+	cfg := config.Config{ADCMURL: "http://example", HostID: 7, ForceSendAfter: "120s", ExecConcurrency: 2}
+	r := newTestRunner(t, cfg, &checktest.FakeSystemd{}, &checktest.FakeDocker{}, post,
+		&testClock{now: time.Unix(0, 0)})
+	r.exec = ex
+
+	r.ruleStore.Set(rules.Rules{
+		Exec: []rules.RuleExec{
+			{Name: "zk1", Command: "zk-ok", Components: []string{"801"}},
+			{Name: "zk2", Command: "zk-warn", ExitCodes: map[int]int{1: 0}, Components: []string{"802"}},
+			{Name: "hms", Command: "hms", Components: []string{"803"}},
+			{Name: "hung", Command: "hung", Components: []string{"804"}},
+			{Name: "missing", Command: "nope", Components: []string{"805"}},
+		},
+	})
+
+	r.scanOnce(context.Background())
+	waitUntil(t, func() bool { return post.Count() == 6 }, time.Second)
+
+	want := map[string]int{"801": 0, "802": 0, "803": 1, "804": 1, "805": 1}
+	for _, e := range post.Snapshot() {
+		if e.IsHost {
+			continue
+		}
+		if want[e.CompID] != e.Status {
+			t.Fatalf("comp %s: want %d, got %d", e.CompID, want[e.CompID], e.Status)
+		}
+	}
+	if p := ex.Peak(); p > 2 {
+		t.Fatalf("exec concurrency not limited: peak %d", p)
+	}
+}
+
+func TestRunner_ExecRulesWithoutName(t *testing.T) {
+	ex := &checktest.FakeExec{
+		Results: map[string]check.ExecResult{"ok": {ExitCode: 0}, "fail": {ExitCode: 1}},
+	}
+	post := &testPoster{}
+	cfg := config.Config{ADCMURL: "http://example", HostID: 7, ForceSendAfter: "1h"}
+	r := newTestRunner(t, cfg, &checktest.FakeSystemd{}, &checktest.FakeDocker{}, post,
+		&testClock{now: time.Unix(0, 0)})
+	r.exec = ex
+	r.ruleStore.Set(rules.Rules{
+		Exec: []rules.RuleExec{
+			{Command: "ok", Components: []string{"1"}},
+			{Command: "fail", Components: []string{"2"}},
+		},
+	})
+	ctx := context.Background()
+
+	r.scanOnce(ctx)
+	waitUntil(t, func() bool { return post.Count() == 3 }, 2*time.Second)
+	want := map[string]int{"1": 0, "2": 1}
+	for _, e := range post.Snapshot() {
+		if !e.IsHost && want[e.CompID] != e.Status {
+			t.Fatalf("comp %s: want %d, got %d", e.CompID, want[e.CompID], e.Status)
+		}
+	}
+
+	// later cycles report each script's own last run: nothing changes
+	post.Reset()
+	for range 3 {
+		r.scanOnce(ctx)
+	}
+	time.Sleep(20 * time.Millisecond)
+	if ss := post.Snapshot(); len(ss) != 0 {
+		t.Fatalf("unnamed exec rules mixed up their runs: %+v", ss)
+	}
+}
+
+func TestRunner_ExecDoesNotHoldScans(t *testing.T) {
+	slow := make(chan struct{}, 1)
+	ex := &checktest.FakeExec{
+		Results: map[string]check.ExecResult{"slow": {ExitCode: 0}, "fast": {ExitCode: 0}},
+		Block:   map[string]chan struct{}{"slow": slow},
+	}
+	post := &testPoster{}
+	cfg := config.Config{ADCMURL: "http://example", HostID: 7, ForceSendAfter: "1h"}
+	r := newTestRunner(t, cfg, &checktest.FakeSystemd{}, &checktest.FakeDocker{}, post,
+		&testClock{now: time.Unix(0, 0)})
+	r.exec = ex
+	r.ruleStore.Set(rules.Rules{
+		Exec: []rules.RuleExec{
+			{Name: "slow", Command: "slow", Components: []string{"901"}},
+			{Name: "fast", Command: "fast", Components: []string{"902"}},
+		},
+	})
+	ctx := context.Background()
+	status := func(comp string) int {
+		r.cacheMu.Lock()
+		defer r.cacheMu.Unlock()
+		if ls, ok := r.cache["comp:7:"+comp]; ok {
+			return ls.status
+		}
+		return -1
+	}
+	sent := func(slow, fast int) {
+		t.Helper()
+		waitUntil(t, func() bool { return status("901") == slow && status("902") == fast }, 2*time.Second)
+	}
+	scan := func() {
+		t.Helper()
+		done := make(chan struct{})
+		go func() {
+			r.scanOnce(ctx)
+			close(done)
+		}()
+		select {
+		case <-done:
+		case <-time.After(2 * time.Second):
+			t.Fatal("scan waited for a running script")
+		}
+	}
+	changed := func() {
+		t.Helper()
+		select {
+		case <-r.trigger:
+		case <-time.After(2 * time.Second):
+			t.Fatal("a finished run that changed a status did not request a scan")
+		}
+	}
+
+	// the first scan waits for the first run of every script
+	slow <- struct{}{}
+	scan()
+	sent(0, 0)
+
+	// slow hangs from now on; fast keeps being reported
+	ex.SetResult("fast", check.ExecResult{ExitCode: 1})
+	scan()
+	changed()
+	scan()
+	sent(0, 1)
+
+	ex.SetResult("slow", check.ExecResult{ExitCode: 1})
+	slow <- struct{}{}
+	changed()
+	scan()
+	sent(1, 1)
+}
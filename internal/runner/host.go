@@ -0,0 +1,113 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/arenadata/ad-status-sender/internal/rules"
+)
+
+// hostState remembers which host checks failed in the previous cycle so that
+// only transitions are logged.
+type hostState struct {
+	mu      sync.Mutex
+	failing map[string]string
+}
+
+// hostProblem is one failed host check: a stable name and a description.
+type hostProblem struct {
+	name   string
+	detail string
+}
+
+// hostStatus evaluates the host rules; without them the heartbeat is 0.
+func (r *Runner) hostStatus(ctx context.Context, hr *rules.RuleHost) int {
+	if hr == nil {
+		return 0
+	}
+	if r.host == nil {
+		return 1
+	}
+	problems := r.hostProblems(ctx, hr)
+	r.logHostTransitions(ctx, problems)
+	if len(problems) > 0 {
+		return 1
+	}
+	return 0
+}
+
+func (r *Runner) hostProblems(ctx context.Context, hr *rules.RuleHost) []hostProblem {
+	var out []hostProblem
+	add := func(name, format string, args ...any) {
+		out = append(out, hostProblem{name: name, detail: fmt.Sprintf(format, args...)})
+	}
+
+	for _, fs := range hr.Filesystems {
+		u, err := r.host.Filesystem(ctx, fs.Path)
+		switch {
+		case err != nil:
+			add("fs:"+fs.Path, "statfs %s: %v", fs.Path, err)
+		case fs.MaxUsedPercent > 0 && u.UsedPercent > fs.MaxUsedPercent:
+			add("fs:"+fs.Path, "%s is %.1f%% full (max %.1f%%)", fs.Path, u.UsedPercent, fs.MaxUsedPercent)
+		case fs.MaxInodesPercent > 0 && u.InodesUsedPercent > fs.MaxInodesPercent:
+			add("fs:"+fs.Path, "%s uses %.1f%% inodes (max %.1f%%)", fs.Path, u.InodesUsedPercent, fs.MaxInodesPercent)
+		case fs.FailReadOnly && u.ReadOnly:
+			add("fs:"+fs.Path, "%s is mounted read-only", fs.Path)
+		}
+	}
+
+	if mp := hr.MemoryPressure; mp != (rules.HostPressure{}) {
+		p, err := r.host.MemoryPressure(ctx)
+		switch {
+		case err != nil:
+			add("memory", "memory pressure: %v", err)
+		case exceeds(p.SomeAvg10, mp.MaxSomeAvg10), exceeds(p.SomeAvg60, mp.MaxSomeAvg60),
+			exceeds(p.FullAvg10, mp.MaxFullAvg10), exceeds(p.FullAvg60, mp.MaxFullAvg60):
+			add("memory", "memory pressure some=%.2f/%.2f full=%.2f/%.2f (avg10/avg60)",
+				p.SomeAvg10, p.SomeAvg60, p.FullAvg10, p.FullAvg60)
+		}
+	}
+
+	if ld := hr.Load; ld != (rules.HostLoad{}) {
+		l, err := r.host.LoadAvg(ctx)
+		if err != nil {
+			add("load", "load average: %v", err)
+			return out
+		}
+		div := 1.0
+		if ld.PerCPU && l.CPUs > 0 {
+			div = float64(l.CPUs)
+		}
+		if exceeds(l.Load1/div, ld.MaxLoad1) || exceeds(l.Load5/div, ld.MaxLoad5) ||
+			exceeds(l.Load15/div, ld.MaxLoad15) {
+			add("load", "load average %.2f %.2f %.2f on %d CPUs", l.Load1, l.Load5, l.Load15, l.CPUs)
+		}
+	}
+	return out
+}
+
+func (r *Runner) logHostTransitions(ctx context.Context, problems []hostProblem) {
+	now := make(map[string]string, len(problems))
+	for _, p := range problems {
+		now[p.name] = p.detail
+	}
+
+	r.hostCheck.mu.Lock()
+	defer r.hostCheck.mu.Unlock()
+	for name, detail := range now {
+		if _, was := r.hostCheck.failing[name]; !was {
+			r.log.WarnContext(ctx, "host check failed", "check", name, "detail", detail)
+		}
+	}
+	for name := range r.hostCheck.failing {
+		if _, still := now[name]; !still {
+			r.log.InfoContext(ctx, "host check recovered", "check", name)
+		}
+	}
+	r.hostCheck.failing = now
+}
+
+func exceeds(v, limit float64) bool {
+	return limit > 0 && v > limit
+}
@@ -0,0 +1,50 @@
+package runner
+
+import (
+	"context"
+	"testing"
+
+	"github.com/arenadata/ad-status-sender/internal/check"
+	"github.com/arenadata/ad-status-sender/internal/check/checktest"
+	"github.com/arenadata/ad-status-sender/internal/rules"
+)
+
+func TestHostStatus(t *testing.T) {
+	host := &checktest.FakeHost{
+		Filesystems: map[string]check.FSUsage{
+			"/":     {UsedPercent: 50, InodesUsedPercent: 10},
+			"/data": {UsedPercent: 95, InodesUsedPercent: 10},
+			"/ro":   {UsedPercent: 1, ReadOnly: true},
+		},
+		Pressure: check.Pressure{SomeAvg10: 5, FullAvg10: 1},
+		Load:     check.LoadAvg{Load1: 6, Load5: 3, Load15: 1, CPUs: 4},
+	}
+	r := NewWithDeps("unused.yaml", nil, nil, nil, nil, nil)
+	r.host = host
+	ctx := context.Background()
+
+	if st := r.hostStatus(ctx, nil); st != 0 {
+		t.Fatalf("no host rules must keep the heartbeat at 0, got %d", st)
+	}
+
+	cases := []struct {
+		name string
+		hr   rules.RuleHost
+		want int
+	}{
+		{"root ok", rules.RuleHost{Filesystems: []rules.HostFilesystem{{Path: "/", MaxUsedPercent: 90}}}, 0},
+		{"data full", rules.RuleHost{Filesystems: []rules.HostFilesystem{{Path: "/data", MaxUsedPercent: 90}}}, 1},
+		{"inodes", rules.RuleHost{Filesystems: []rules.HostFilesystem{{Path: "/", MaxInodesPercent: 5}}}, 1},
+		{"read-only", rules.RuleHost{Filesystems: []rules.HostFilesystem{{Path: "/ro", FailReadOnly: true}}}, 1},
+		{"missing fs", rules.RuleHost{Filesystems: []rules.HostFilesystem{{Path: "/nope"}}}, 1},
+		{"psi ok", rules.RuleHost{MemoryPressure: rules.HostPressure{MaxSomeAvg10: 10}}, 0},
+		{"psi full", rules.RuleHost{MemoryPressure: rules.HostPressure{MaxFullAvg10: 0.5}}, 1},
+		{"load per cpu ok", rules.RuleHost{Load: rules.HostLoad{MaxLoad1: 2, PerCPU: true}}, 0},
+		{"load absolute", rules.RuleHost{Load: rules.HostLoad{MaxLoad1: 2}}, 1},
+	}
+	for _, c := range cases {
+		if got := r.hostStatus(ctx, &c.hr); got != c.want {
+			t.Fatalf("%s: want %d, got %d", c.name, c.want, got)
+		}
+	}
+}
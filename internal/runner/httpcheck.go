@@ -0,0 +1,75 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/arenadata/ad-status-sender/internal/check"
+	"github.com/arenadata/ad-status-sender/internal/config"
+	"github.com/arenadata/ad-status-sender/internal/rules"
+)
+
+func (r *Runner) scanHTTP(ctx context.Context, cyc *cycle, rr rules.Rules) {
+	for i, rule := range rr.HTTP {
+		comps := append([]string(nil), rule.Components...)
+		r.check(ctx, cyc, func() result {
+			st := r.probeHTTP(ctx, rule)
+			return result{
+				kind: "http", index: i, target: httpTarget(rule), status: st, comps: comps, deb: rule.Debounce,
+			}
+		})
+	}
+}
+
+func (r *Runner) probeHTTP(ctx context.Context, rule rules.RuleHTTP) int {
+	if r.web == nil {
+		return 1
+	}
+	spec, err := httpSpec(rule)
+	if err == nil {
+		err = r.web.CheckHTTP(ctx, spec)
+	}
+	if err != nil {
+		r.log.DebugContext(ctx, "http check failed", "rule", rule.Name, "url", redactURL(rule.URL), "err", err)
+		return 1
+	}
+	return 0
+}
+
+func httpSpec(rule rules.RuleHTTP) (check.HTTPSpec, error) {
+	spec := check.HTTPSpec{
+		Method:       strings.ToUpper(rule.Method),
+		URL:          rule.URL,
+		Timeout:      config.MustDuration(rule.Timeout, check.DefaultHTTPTimeout),
+		ExpectStatus: rule.ExpectStatus,
+		JSONPath:     rule.JSONPath,
+		JSONValue:    rule.JSONValue,
+	}
+	if len(rule.Headers) > 0 {
+		spec.Header = make(http.Header, len(rule.Headers))
+		for k, v := range rule.Headers {
+			spec.Header.Set(k, v)
+		}
+	}
+	if strings.HasPrefix(strings.ToLower(rule.URL), "https://") {
+		spec.TLS = rule.TLS.ClientConfig()
+	}
+	if rule.ExpectBody != "" {
+		re, err := regexp.Compile(rule.ExpectBody)
+		if err != nil {
+			return spec, fmt.Errorf("expect_body: %w", err)
+		}
+		spec.Body = re
+	}
+	return spec, nil
+}
+
+func httpTarget(rule rules.RuleHTTP) string {
+	if rule.Name != "" {
+		return rule.Name
+	}
+	return redactURL(rule.URL)
+}
@@ -0,0 +1,74 @@
+package runner
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/arenadata/ad-status-sender/internal/check"
+	"github.com/arenadata/ad-status-sender/internal/check/checktest"
+	"github.com/arenadata/ad-status-sender/internal/config"
+	"github.com/arenadata/ad-status-sender/internal/rules"
+)
+
+func TestRunner_HTTPChecks(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/health":
+			_, _ = w.Write([]byte(`{"status":"UP"}`))
+		case "/jmx":
+			if r.Header.Get("Accept") != "application/json" {
+				w.WriteHeader(http.StatusNotAcceptable)
+				return
+			}
+			_, _ = w.Write([]byte(`{"beans":[{"State":"active"}]}`))
+		default:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer srv.Close()
+
+	post := &testPoster{}
+	cfg := config.Config{ADCMURL: "http://example", HostID: 7, ForceSendAfter: "120s"}
+	r := newTestRunner(t, cfg, &checktest.FakeSystemd{}, &checktest.FakeDocker{}, post,
+		&testClock{now: time.Unix(0, 0)})
+	r.web = check.HTTPChecker{}
+
+	r.ruleStore.Set(rules.Rules{
+		HTTP: []rules.RuleHTTP{
+			{
+				Name:       "ranger",
+				URL:        srv.URL + "/health",
+				JSONPath:   "status",
+				JSONValue:  "UP",
+				Components: []string{"1001"},
+			},
+			{
+				Name:       "namenode",
+				URL:        srv.URL + "/jmx",
+				Headers:    map[string]string{"Accept": "application/json"},
+				JSONPath:   "$.beans[0].State",
+				JSONValue:  "active",
+				ExpectBody: `"active"`,
+				Components: []string{"1002"},
+			},
+			{Name: "airflow", URL: srv.URL + "/down", Components: []string{"1003"}},
+			{Name: "head", Method: "head", URL: srv.URL + "/health", Components: []string{"1004"}},
+		},
+	})
+
+	r.scanOnce(context.Background())
+	waitUntil(t, func() bool { return post.Count() == 5 }, 2*time.Second)
+
+	want := map[string]int{"1001": 0, "1002": 0, "1003": 1, "1004": 0}
+	for _, e := range post.Snapshot() {
+		if e.IsHost {
+			continue
+		}
+		if want[e.CompID] != e.Status {
+			t.Fatalf("comp %s: want %d, got %d", e.CompID, want[e.CompID], e.Status)
+		}
+	}
+}
@@ -0,0 +1,174 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/arenadata/ad-status-sender/internal/config"
+	"github.com/arenadata/ad-status-sender/internal/maintenance"
+	"github.com/arenadata/ad-status-sender/internal/rules"
+)
+
+// maintenanceState remembers which windows were active in the previous cycle
+// so that only starts and ends are logged, and which ones were invalid so
+// they are reported once. It also keeps the silence file as last read.
+type maintenanceState struct {
+	mu       sync.Mutex
+	active   map[string]bool
+	invalid  map[string]bool
+	silences silenceCache
+}
+
+// silenceCache is the content of the silence file, valid while the file
+// keeps its path, modification time and size.
+type silenceCache struct {
+	loaded bool
+	path   string
+	exists bool
+	mod    time.Time
+	size   int64
+	list   []maintenance.Silence
+	err    error
+}
+
+// applyMaintenance rewrites statuses for components in an active window or
+// silence: they get the window's status or are dropped from the cycle.
+func (r *Runner) applyMaintenance(ctx context.Context, cfg config.Config, rr rules.Rules, statuses map[string]int) {
+	windows := r.maintenanceWindows(ctx, cfg, rr)
+	if len(windows) == 0 && !r.maint.hadActive() {
+		return
+	}
+	now := r.clk.Now()
+	active := make(map[string]bool)
+	for _, w := range windows {
+		until, ok := w.Active(now)
+		if !ok {
+			continue
+		}
+		active[w.Name] = true
+		if !r.maint.wasActive(w.Name) {
+			r.log.InfoContext(ctx, "maintenance started", "name", w.Name, "until", until,
+				"components", w.Components, "status", statusAttr(w.Status))
+		}
+		for comp := range statuses {
+			if !w.Covers(comp) {
+				continue
+			}
+			if w.Status == nil {
+				delete(statuses, comp)
+			} else {
+				statuses[comp] = *w.Status
+			}
+		}
+	}
+	for _, name := range r.maint.swap(active) {
+		r.log.InfoContext(ctx, "maintenance ended", "name", name)
+	}
+}
+
+// maintenanceWindows builds the windows declared in rules plus the entries of
+// the silence file. Windows that don't parse are skipped.
+func (r *Runner) maintenanceWindows(ctx context.Context, cfg config.Config, rr rules.Rules) []maintenance.Window {
+	out := make([]maintenance.Window, 0, len(rr.Maintenance))
+	for i, mw := range rr.Maintenance {
+		name := mw.Name
+		if name == "" {
+			name = "maintenance#" + strconv.Itoa(i)
+		}
+		w, err := mw.Window(name)
+		if err != nil {
+			if r.maint.firstInvalid(name + ": " + err.Error()) {
+				r.log.WarnContext(ctx, "maintenance window ignored", "name", name, "err", err)
+			}
+			continue
+		}
+		out = append(out, w)
+	}
+	silences, err := r.maint.loadSilences(cfg.SilenceFile)
+	if err != nil && r.maint.firstInvalid(err.Error()) {
+		r.log.WarnContext(ctx, "silence file ignored", "path", cfg.SilenceFile, "err", err)
+	}
+	for _, s := range silences {
+		out = append(out, s.Window(s.Name()))
+	}
+	return out
+}
+
+func statusAttr(st *int) string {
+	if st == nil {
+		return "not posted"
+	}
+	return strconv.Itoa(*st)
+}
+
+func (m *maintenanceState) wasActive(name string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.active[name]
+}
+
+func (m *maintenanceState) hadActive() bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.active) > 0
+}
+
+// swap stores the windows active in this cycle and returns those that ended.
+func (m *maintenanceState) swap(active map[string]bool) []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var ended []string
+	for name := range m.active {
+		if !active[name] {
+			ended = append(ended, name)
+		}
+	}
+	m.active = active
+	slices.Sort(ended)
+	return ended
+}
+
+// loadSilences returns the entries of the silence file at path. The file is
+// only read again once its modification time or size changes.
+func (m *maintenanceState) loadSilences(path string) ([]maintenance.Silence, error) {
+	if path == "" {
+		return nil, nil
+	}
+	fi, err := os.Stat(path)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	c := &m.silences
+	exists := err == nil
+	if c.loaded && c.path == path && c.exists == exists &&
+		(!exists || fi.ModTime().Equal(c.mod) && fi.Size() == c.size) {
+		return c.list, c.err
+	}
+	*c = silenceCache{loaded: true, path: path, exists: exists}
+	if exists {
+		c.mod, c.size = fi.ModTime(), fi.Size()
+	}
+	c.list, c.err = maintenance.LoadSilences(path)
+	return c.list, c.err
+}
+
+func (m *maintenanceState) firstInvalid(key string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.invalid == nil {
+		m.invalid = make(map[string]bool)
+	}
+	if m.invalid[key] {
+		return false
+	}
+	m.invalid[key] = true
+	return true
+}
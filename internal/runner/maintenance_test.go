@@ -0,0 +1,183 @@
+package runner
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/arenadata/ad-status-sender/internal/check/checktest"
+	"github.com/arenadata/ad-status-sender/internal/config"
+	"github.com/arenadata/ad-status-sender/internal/rules"
+)
+
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func TestRunner_MaintenanceWindowsAndSilences(t *testing.T) {
+	silence := filepath.Join(t.TempDir(), "silence.yaml")
+	logs := &syncBuffer{}
+	clk := &testClock{now: time.Date(2026, 11, 1, 1, 0, 0, 0, time.UTC)} // Sunday 01:00
+	sd := &checktest.FakeSystemd{Units: map[string]bool{}}
+	post := &testPoster{}
+	cfg := config.Config{ADCMURL: "http://example", HostID: 7, ForceSendAfter: "24h", SilenceFile: silence}
+	r := newTestRunner(t, cfg, sd, &checktest.FakeDocker{}, post, clk)
+	r.log = slog.New(slog.NewTextHandler(logs, nil))
+
+	zero := 0
+	r.ruleStore.Set(rules.Rules{
+		Systemd: []rules.RuleSystemd{
+			{Unit: "hdfs.service", Components: []string{"1501"}},
+			{Unit: "yarn.service", Components: []string{"1502"}},
+			{Unit: "hive.service", Components: []string{"1503"}},
+		},
+		Maintenance: []rules.MaintenanceWindow{
+			{Name: "weekly-hdfs", Cron: "0 2 * * SUN", Duration: "2h", Timezone: "UTC", Components: []string{"1501"}},
+			{Name: "yarn-upgrade", From: "2026-11-01T00:30:00Z", To: "2026-11-01T03:00:00Z",
+				Components: []string{"1502"}, Status: &zero},
+			{Name: "broken", Cron: "every sunday", Duration: "1h"},
+		},
+	})
+
+	scan := func() map[string]int {
+		t.Helper()
+		post.Reset()
+		r.scanOnce(context.Background())
+		time.Sleep(30 * time.Millisecond)
+		out := map[string]int{}
+		for _, e := range post.Snapshot() {
+			if !e.IsHost {
+				out[e.CompID] = e.Status
+			}
+		}
+		return out
+	}
+
+	// 01:00: only the absolute yarn window is active
+	got := scan()
+	if got["1501"] != 1 || got["1502"] != 0 || got["1503"] != 1 {
+		t.Fatalf("01:00: unexpected posts %v", got)
+	}
+
+	// 02:30: hdfs is in its weekly window and not posted at all
+	clk.advance(90 * time.Minute)
+	r.cacheMu.Lock()
+	r.cache = make(map[string]lastSend)
+	r.cacheMu.Unlock()
+	got = scan()
+	if _, posted := got["1501"]; posted || got["1502"] != 0 || got["1503"] != 1 {
+		t.Fatalf("02:30: unexpected posts %v", got)
+	}
+
+	// an on-demand silence for hive, reported as 0
+	data := "- components: [\"1503\"]\n  until: 2026-11-01T05:00:00Z\n  status: 0\n  reason: \"hive upgrade\"\n"
+	if err := os.WriteFile(silence, []byte(data), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	clk.advance(time.Minute)
+	got = scan()
+	if got["1503"] != 0 {
+		t.Fatalf("02:31: silence not applied: %v", got)
+	}
+
+	// 04:10: windows over, silence still on; real yarn status comes through
+	clk.advance(99 * time.Minute)
+	got = scan()
+	if got["1502"] != 1 {
+		t.Fatalf("04:10: unexpected posts %v", got)
+	}
+	if _, posted := got["1503"]; posted {
+		t.Fatalf("04:10: silenced status changed: %v", got)
+	}
+
+	out := logs.String()
+	for _, want := range []string{
+		"maintenance started", "name=weekly-hdfs", "name=yarn-upgrade",
+		`"silence 1503 until 2026-11-01T05:00:00Z: hive upgrade"`,
+		"maintenance ended", "maintenance window ignored", "name=broken",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("log lacks %q", want)
+		}
+	}
+	if n := strings.Count(out, "maintenance window ignored"); n != 1 {
+		t.Errorf("invalid window logged %d times", n)
+	}
+}
+
+func TestRunner_SilenceFile(t *testing.T) {
+	silence := filepath.Join(t.TempDir(), "silence.yaml")
+	write := func(data string, mod time.Time) {
+		t.Helper()
+		if err := os.WriteFile(silence, []byte(data), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(silence, mod, mod); err != nil {
+			t.Fatal(err)
+		}
+	}
+	names := func(r *Runner) []string {
+		var out []string
+		for _, w := range r.maintenanceWindows(context.Background(), r.cfg, rules.Rules{}) {
+			out = append(out, w.Name+"="+strings.Join(w.Components, ","))
+		}
+		return out
+	}
+	cfg := config.Config{ADCMURL: "http://example", HostID: 7, SilenceFile: silence}
+	r := newTestRunner(t, cfg, &checktest.FakeSystemd{}, &checktest.FakeDocker{}, &testPoster{}, &testClock{})
+
+	// two entries with the same reason are two windows
+	mod := time.Date(2026, 11, 1, 0, 0, 0, 0, time.UTC)
+	write("- {components: [\"1\"], until: 2026-11-02T00:00:00Z, reason: disks}\n"+
+		"- {components: [\"2\"], until: 2026-11-03T00:00:00Z, reason: disks}\n", mod)
+	want := []string{
+		"silence 1 until 2026-11-02T00:00:00Z: disks=1",
+		"silence 2 until 2026-11-03T00:00:00Z: disks=2",
+	}
+	if got := names(r); !slices.Equal(got, want) {
+		t.Fatalf("unexpected silences %v", got)
+	}
+
+	// an unchanged file is not read again
+	write("- {components: [\"3\"], until: 2026-11-02T00:00:00Z, reason: disks}\n"+
+		"- {components: [\"4\"], until: 2026-11-03T00:00:00Z, reason: disks}\n", mod)
+	if got := names(r); !slices.Equal(got, want) {
+		t.Fatalf("file read again while unchanged: %v", got)
+	}
+
+	// removing an entry doesn't rename the others
+	write("- {components: [\"2\"], until: 2026-11-03T00:00:00Z, reason: disks}\n", mod.Add(time.Second))
+	if got := names(r); !slices.Equal(got, want[1:]) {
+		t.Fatalf("changed file not read: %v", got)
+	}
+	write("- {until: 2026-11-02T00:00:00Z}\n", mod.Add(2*time.Second))
+	if got := names(r); !slices.Equal(got, []string{"silence * until 2026-11-02T00:00:00Z="}) {
+		t.Fatalf("unexpected silences %v", got)
+	}
+	if err := os.Remove(silence); err != nil {
+		t.Fatal(err)
+	}
+	if got := names(r); len(got) != 0 {
+		t.Fatalf("removed file still silences: %v", got)
+	}
+}
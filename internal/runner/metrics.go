@@ -0,0 +1,201 @@
+package runner
+
+import (
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/arenadata/ad-status-sender/internal/metrics"
+	"github.com/arenadata/ad-status-sender/internal/rules"
+)
+
+const metricPrefix = "ad_status_sender_"
+
+// runnerMetrics are the counters and histograms updated as things happen.
+// A nil *runnerMetrics records nothing, so posters built without a runner
+// work as before.
+type runnerMetrics struct {
+	checks        *metrics.Vec
+	checkDuration *metrics.HistogramVec
+	posts         *metrics.Vec
+	postDuration  *metrics.HistogramVec
+	retries       *metrics.Vec
+	overflows     *metrics.Vec
+	reloads       *metrics.Vec
+	ruleChanges   *metrics.Vec
+	discoveries   *metrics.Vec
+}
+
+func newRunnerMetrics(m *metrics.Registry) *runnerMetrics {
+	return &runnerMetrics{
+		checks: m.Counter(metricPrefix+"checks_total",
+			"Check targets evaluated, by kind and result (ok or fail).", "kind", "result"),
+		checkDuration: m.Histogram(metricPrefix+"check_duration_seconds",
+			"Time spent evaluating one check target.", metrics.DefaultBuckets(), "kind"),
+		posts: m.Counter(metricPrefix+"posts_total",
+			"HTTP requests to ADCM, by outcome and response code (none for transport errors).", "outcome", "code"),
+		postDuration: m.Histogram(metricPrefix+"post_duration_seconds",
+			"Latency of HTTP requests to ADCM.", metrics.DefaultBuckets()),
+		retries: m.Counter(metricPrefix+"post_retries_total",
+			"Requests to ADCM repeated after a retryable failure."),
+		overflows: m.Counter(metricPrefix+"job_queue_overflows_total",
+			"Jobs run in a separate goroutine because the worker queue was full."),
+		reloads: m.Counter(metricPrefix+"rules_reloads_total",
+			"Rules loads, by result (ok, partial, fail or refused).", "result"),
+		ruleChanges: m.Counter(metricPrefix+"rules_changes_total",
+			"Rules added, removed or changed by applied rules loads.", "change"),
+		discoveries: m.Counter(metricPrefix+"component_discoveries_total",
+			"Requests for the components of the host to ADCM, by result (ok or fail).", "result"),
+	}
+}
+
+func (m *runnerMetrics) checked(kind string, status int, d time.Duration) {
+	if m == nil {
+		return
+	}
+	m.checks.With(kind, okFail(status == 0)).Inc()
+	m.checkDuration.Observe(d.Seconds(), kind)
+}
+
+// posted records one HTTP attempt; code is 0 when no response arrived.
+func (m *runnerMetrics) posted(code int, err error, d time.Duration) {
+	if m == nil {
+		return
+	}
+	outcome, label := "ok", "none"
+	if err != nil {
+		outcome = "error"
+	}
+	if errors.Is(err, ErrCircuitOpen) {
+		outcome = "circuit_open"
+	}
+	if code != 0 {
+		label = strconv.Itoa(code)
+	}
+	m.posts.With(outcome, label).Inc()
+	if d > 0 {
+		m.postDuration.Observe(d.Seconds())
+	}
+}
+
+func (m *runnerMetrics) retried() {
+	if m != nil {
+		m.retries.With().Inc()
+	}
+}
+
+func (m *runnerMetrics) overflowed() {
+	if m != nil {
+		m.overflows.With().Inc()
+	}
+}
+
+func (m *runnerMetrics) reloaded(result string, ch rules.Change) {
+	if m == nil {
+		return
+	}
+	m.reloads.With(result).Inc()
+	m.ruleChanges.With("added").Add(float64(len(ch.Added)))
+	m.ruleChanges.With("removed").Add(float64(len(ch.Removed)))
+	m.ruleChanges.With("changed").Add(float64(len(ch.Changed)))
+}
+
+func (m *runnerMetrics) discovered(ok bool) {
+	if m != nil {
+		m.discoveries.With(okFail(ok)).Inc()
+	}
+}
+
+func okFail(ok bool) string {
+	if ok {
+		return "ok"
+	}
+	return "fail"
+}
+
+// registerMetrics exposes the runner state on /metrics. The gauges are read
+// at scrape time, so nothing has to be updated from the scan path.
+func (r *Runner) registerMetrics() {
+	m := r.metrics
+	r.met = newRunnerMetrics(m)
+	m.GaugeFunc(metricPrefix+"job_queue_depth",
+		"Jobs waiting for a worker.", nil,
+		func(emit func(float64, ...string)) { emit(float64(r.queueDepth())) })
+	m.GaugeFunc(metricPrefix+"workers",
+		"Goroutines running queued jobs.", nil,
+		func(emit func(float64, ...string)) { emit(float64(r.workerCount())) })
+	m.GaugeFunc(metricPrefix+"cache_entries",
+		"Keys in the send cache.", nil,
+		func(emit func(float64, ...string)) {
+			r.cacheMu.Lock()
+			n := len(r.cache)
+			r.cacheMu.Unlock()
+			emit(float64(n))
+		})
+	m.GaugeFunc(metricPrefix+"last_scan_timestamp_seconds",
+		"Unix time of the last finished scan cycle.", nil,
+		func(emit func(float64, ...string)) {
+			if _, _, t := r.checks.last(); !t.IsZero() {
+				emit(unixSeconds(t))
+			}
+		})
+	m.GaugeFunc(metricPrefix+"check_status",
+		"Status of a check target after debouncing (0 = up), by the position of its rule among the rules of its kind.",
+		[]string{"kind", "rule", "target"},
+		func(emit func(float64, ...string)) {
+			checks, _, _ := r.checks.last()
+			for _, c := range checks {
+				emit(float64(c.Status), c.Kind, strconv.Itoa(c.index), c.Target)
+			}
+		})
+	m.GaugeFunc(metricPrefix+"component_status",
+		"Aggregated status of a component in the last cycle (0 = up).", []string{"component"},
+		func(emit func(float64, ...string)) {
+			_, comps, _ := r.checks.last()
+			for _, comp := range sortedKeys(comps) {
+				emit(float64(comps[comp]), comp)
+			}
+		})
+	m.GaugeFunc(metricPrefix+"sent_status",
+		"Status last delivered to ADCM per host or component.", []string{"key"},
+		func(emit func(float64, ...string)) {
+			for _, e := range r.cacheEntries() {
+				emit(float64(e.Status), e.Key)
+			}
+		})
+	m.GaugeFunc(metricPrefix+"sent_timestamp_seconds",
+		"Unix time a status was last delivered to ADCM.", []string{"key"},
+		func(emit func(float64, ...string)) {
+			for _, e := range r.cacheEntries() {
+				emit(unixSeconds(e.LastSent), e.Key)
+			}
+		})
+	m.GaugeFunc(metricPrefix+"spool_entries",
+		"Undelivered statuses waiting in the spool.", nil,
+		func(emit func(float64, ...string)) {
+			if sp := r.getSpool(); sp != nil {
+				emit(float64(sp.Len()))
+			}
+		})
+	m.GaugeFunc(metricPrefix+"discovered_components",
+		"Component IDs known for the host from component discovery.", nil,
+		func(emit func(float64, ...string)) { emit(float64(len(r.componentRefs()))) })
+	m.GaugeFunc(metricPrefix+"rules",
+		"Number of loaded rules per kind.", []string{"kind"},
+		func(emit func(float64, ...string)) {
+			rr := r.ruleStore.Get()
+			counts := map[string]int{
+				"systemd": len(rr.Systemd),
+				"docker":  len(rr.Docker),
+				"exec":    len(rr.Exec),
+				"tcp":     len(rr.TCP),
+				"http":    len(rr.HTTP),
+				"process": len(rr.Process),
+			}
+			for _, kind := range sortedKeys(counts) {
+				emit(float64(counts[kind]), kind)
+			}
+		})
+}
+
+func unixSeconds(t time.Time) float64 { return float64(t.UnixNano()) / float64(time.Second) }
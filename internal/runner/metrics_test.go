@@ -0,0 +1,138 @@
+package runner
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/arenadata/ad-status-sender/internal/check/checktest"
+	"github.com/arenadata/ad-status-sender/internal/config"
+	"github.com/arenadata/ad-status-sender/internal/metrics"
+	"github.com/arenadata/ad-status-sender/internal/rules"
+)
+
+func scrape(t *testing.T, reg *metrics.Registry) string {
+	t.Helper()
+	var b strings.Builder
+	if err := reg.WriteText(&b); err != nil {
+		t.Fatal(err)
+	}
+	return b.String()
+}
+
+func wantSamples(t *testing.T, text string, samples ...string) {
+	t.Helper()
+	for _, s := range samples {
+		if !strings.Contains(text, s+"\n") {
+			t.Fatalf("missing %q in:\n%s", s, text)
+		}
+	}
+}
+
+func TestHTTPPoster_Metrics(t *testing.T) {
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer srv.Close()
+
+	reg := metrics.NewRegistry()
+	p := newTestPoster(srv.URL, config.Retry{MaxAttempts: 2, InitialBackoff: "1ms", BreakerThreshold: 1})
+	p.met = newRunnerMetrics(reg)
+	if err := p.PostHost(context.Background(), 0); err != nil {
+		t.Fatal(err)
+	}
+	// both attempts fail to connect and open the breaker
+	srv.Close()
+	_ = p.PostHost(context.Background(), 0)
+	_ = p.PostHost(context.Background(), 0)
+
+	wantSamples(t, scrape(t, reg),
+		`ad_status_sender_posts_total{outcome="error",code="503"} 1`,
+		`ad_status_sender_posts_total{outcome="ok",code="200"} 1`,
+		`ad_status_sender_posts_total{outcome="error",code="none"} 2`,
+		`ad_status_sender_posts_total{outcome="circuit_open",code="none"} 1`,
+		`ad_status_sender_post_retries_total 2`,
+		`ad_status_sender_post_duration_seconds_count 4`,
+	)
+}
+
+func TestRunner_CheckMetricsAndTextfile(t *testing.T) {
+	post := &testPoster{}
+	sd := &checktest.FakeSystemd{Units: map[string]bool{"a.service": true}}
+	textfile := filepath.Join(t.TempDir(), "ad_status_sender.prom")
+	cfg := config.Config{ADCMURL: "http://example", HostID: 7, Metrics: config.Metrics{Textfile: textfile}}
+	r := newTestRunner(t, cfg, sd, &checktest.FakeDocker{}, post, &testClock{now: time.Unix(0, 0)})
+	r.ruleStore.Set(rules.Rules{Systemd: []rules.RuleSystemd{
+		{Unit: "a.service", Components: []string{"1"}},
+		{Unit: "b.service", Components: []string{"2"}},
+	}})
+
+	r.scanOnce(context.Background())
+	waitUntil(t, func() bool { return post.Count() == 3 }, 2*time.Second)
+
+	data, err := os.ReadFile(textfile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantSamples(t, string(data),
+		`ad_status_sender_checks_total{kind="systemd",result="ok"} 1`,
+		`ad_status_sender_checks_total{kind="systemd",result="fail"} 1`,
+		`ad_status_sender_check_duration_seconds_count{kind="systemd"} 2`,
+		`ad_status_sender_job_queue_depth 1`,
+	)
+	// the full queue pushed every job (2 checks, heartbeat) into its own
+	// goroutine; posts never go through the queue
+	wantSamples(t, scrape(t, r.metrics),
+		`ad_status_sender_job_queue_overflows_total 3`,
+		`ad_status_sender_cache_entries 3`,
+	)
+}
+
+func TestRunner_CheckStatusPerRule(t *testing.T) {
+	post := &testPoster{}
+	sd := &checktest.FakeSystemd{Units: map[string]bool{"a.service": true}}
+	dck := &checktest.FakeDocker{Names: map[string]bool{"db": true}}
+	r := newTestRunner(t, config.Config{ADCMURL: "http://example", HostID: 7}, sd, dck, post,
+		&testClock{now: time.Unix(0, 0)})
+	r.ruleStore.Set(rules.Rules{
+		Systemd: []rules.RuleSystemd{
+			{Unit: "a.service", Components: []string{"1"}},
+			{Unit: "a.service", Components: []string{"2"}},
+		},
+		Docker: []rules.RuleDocker{
+			{Containers: rules.DockerSelector{Names: []string{"db"}}, Components: []string{"3"}},
+			{Containers: rules.DockerSelector{Names: []string{"cache"}}, Components: []string{"4"}},
+		},
+	})
+
+	r.scanOnce(context.Background())
+	waitUntil(t, func() bool { return post.Count() == 5 }, 2*time.Second)
+
+	text := scrape(t, r.metrics)
+	wantSamples(t, text,
+		`ad_status_sender_check_status{kind="docker",rule="0",target=""} 0`,
+		`ad_status_sender_check_status{kind="docker",rule="1",target=""} 1`,
+		`ad_status_sender_check_status{kind="systemd",rule="0",target="a.service"} 0`,
+		`ad_status_sender_check_status{kind="systemd",rule="1",target="a.service"} 0`,
+	)
+	seen := make(map[string]bool)
+	for line := range strings.Lines(text) {
+		series, _, _ := strings.Cut(line, "} ")
+		if !strings.HasPrefix(series, "ad_status_sender_check_status{") {
+			continue
+		}
+		if seen[series] {
+			t.Fatalf("duplicate series %s in:\n%s", series, text)
+		}
+		seen[series] = true
+	}
+}
@@ -0,0 +1,116 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/arenadata/ad-status-sender/internal/rules"
+)
+
+// Report is the outcome of evaluating the rules once.
+type Report struct {
+	Host       int
+	Targets    []TargetStatus
+	Components map[string]int
+}
+
+// TargetStatus is the status of one check target and the components it
+// feeds.
+type TargetStatus struct {
+	Kind       string
+	Rule       string
+	Target     string
+	Status     int
+	Components []string
+}
+
+// Evaluate runs every check once and reports what would be sent, without
+// contacting ADCM. Only the config and rules files are needed, not a token;
+// component refs are resolved from component_discovery.cache_file, if any.
+func (r *Runner) Evaluate(ctx context.Context) (Report, error) {
+	c, _, err := r.loader.Load()
+	if err != nil {
+		return Report{}, err
+	}
+	r.mu.Lock()
+	r.cfg = c
+	r.mu.Unlock()
+	r.initChecks()
+	if c.Discovery.Enabled {
+		r.loadComponentCache(c)
+	}
+	return r.evaluateOnce(ctx)
+}
+
+// RunOnce runs one full scan and posts the host and every component status
+// synchronously. The error joins all failed posts.
+func (r *Runner) RunOnce(ctx context.Context) (Report, error) {
+	if err := r.reload(); err != nil {
+		return Report{}, err
+	}
+	r.discoverComponents(ctx)
+	rep, err := r.evaluateOnce(ctx)
+	if err != nil {
+		return rep, err
+	}
+	cfg, _, _ := r.snapshot()
+	r.replaySpool(ctx, cfg)
+
+	var errs []error
+	post := func(key string, upd Update) {
+		var postErr error
+		if upd.IsHost {
+			postErr = r.post.PostHost(ctx, upd.Status)
+		} else {
+			postErr = r.post.PostComponent(ctx, upd.CompID, upd.Status)
+		}
+		r.delivered(ctx, cfg, key, upd, postErr)
+		if postErr != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", key, postErr))
+		}
+	}
+	post(fmt.Sprintf("host:%d", cfg.HostID), Update{IsHost: true, Status: rep.Host})
+	for _, comp := range sortedKeys(rep.Components) {
+		post(fmt.Sprintf("comp:%d:%s", cfg.HostID, comp), Update{CompID: comp, Status: rep.Components[comp]})
+	}
+	return rep, errors.Join(errs...)
+}
+
+func (r *Runner) evaluateOnce(ctx context.Context) (Report, error) {
+	cfg, _, _ := r.snapshot()
+	if err := r.applyRules(rules.LoadAll(cfg.RulesPath, cfg.RulesDir)); err != nil {
+		return Report{}, err
+	}
+	rr := r.ruleStore.Get()
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	r.initRuntime()
+	r.startWorkers(ctx)
+
+	cyc := r.startChecks(ctx, cfg, rr)
+	host := r.hostStatus(ctx, rr.Host)
+	statuses, ok := r.finish(ctx, cfg, rr, cyc)
+	if !ok {
+		return Report{}, ctx.Err()
+	}
+	r.checks.record(cyc.list(), statuses, r.clk.Now())
+
+	rep := Report{Host: host, Components: statuses}
+	checks, _, _ := r.checks.last()
+	for _, c := range checks {
+		rule := c.Rule
+		if rule == "" {
+			rule = c.Target
+		}
+		rep.Targets = append(rep.Targets, TargetStatus{
+			Kind:       c.Kind,
+			Rule:       rule,
+			Target:     c.Target,
+			Status:     c.Status,
+			Components: c.Components,
+		})
+	}
+	return rep, nil
+}
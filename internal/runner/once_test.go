@@ -0,0 +1,97 @@
+package runner_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/arenadata/ad-status-sender/internal/check/checktest"
+	"github.com/arenadata/ad-status-sender/internal/runner"
+	"github.com/arenadata/ad-status-sender/internal/runner/runnertest"
+)
+
+func writeFile(t *testing.T, path, data string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func onceFixture(t *testing.T, token string) (string, *checktest.FakeSystemd) {
+	t.Helper()
+	dir := t.TempDir()
+	rulesPath := filepath.Join(dir, "rules.yaml")
+	writeFile(t, rulesPath, `
+systemd:
+  - unit_glob: "kafka@*.service"
+    components: ["701"]
+  - unit: "zookeeper.service"
+    components: ["701", "702"]
+aggregation:
+  components:
+    "701": any_ok
+`)
+	cfgPath := filepath.Join(dir, "config.yaml")
+	writeFile(t, cfgPath, `
+adcm_url: "http://adcm.invalid"
+host_id: 7
+`+token+`
+rules_path: "`+rulesPath+`"
+`)
+	sd := &checktest.FakeSystemd{Globs: map[string][]string{"kafka@*.service": {"kafka@1.service", "kafka@2.service"}}}
+	sd.Emit("kafka@1.service", true)
+	sd.Emit("kafka@2.service", false)
+	sd.Emit("zookeeper.service", false)
+	return cfgPath, sd
+}
+
+func TestRunner_EvaluateDoesNotPost(t *testing.T) {
+	cfgPath, sd := onceFixture(t, "") // no token needed for a dry run
+	post := &runnertest.FakePoster{}
+	r := runner.NewWithDeps(cfgPath, nil, sd, &checktest.FakeDocker{}, post, nil)
+
+	rep, err := r.Evaluate(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(post.Snapshot()) != 0 {
+		t.Fatalf("dry run posted %v", post.Snapshot())
+	}
+	want := []runner.TargetStatus{
+		{Kind: "systemd", Rule: "kafka@*.service", Target: "kafka@1.service", Status: 0, Components: []string{"701"}},
+		{Kind: "systemd", Rule: "kafka@*.service", Target: "kafka@2.service", Status: 1, Components: []string{"701"}},
+		{Kind: "systemd", Rule: "zookeeper.service", Target: "zookeeper.service", Status: 1,
+			Components: []string{"701", "702"}},
+	}
+	if len(rep.Targets) != len(want) {
+		t.Fatalf("targets: %+v", rep.Targets)
+	}
+	for i, w := range want {
+		g := rep.Targets[i]
+		if g.Kind != w.Kind || g.Rule != w.Rule || g.Target != w.Target || g.Status != w.Status ||
+			len(g.Components) != len(w.Components) {
+			t.Fatalf("target %d: want %+v, got %+v", i, w, g)
+		}
+	}
+	if rep.Components["701"] != 0 || rep.Components["702"] != 1 || rep.Host != 0 {
+		t.Fatalf("components: %v host %d", rep.Components, rep.Host)
+	}
+}
+
+func TestRunner_RunOncePostsAndReportsFailures(t *testing.T) {
+	cfgPath, sd := onceFixture(t, `token: "t"`)
+	boom := errors.New("boom")
+	post := &runnertest.FakePoster{FailComp: map[string]error{"702": boom}}
+	r := runner.NewWithDeps(cfgPath, nil, sd, &checktest.FakeDocker{}, post, nil)
+
+	_, err := r.RunOnce(context.Background())
+	if !errors.Is(err, boom) {
+		t.Fatalf("want post failure, got %v", err)
+	}
+	sent := post.Snapshot()
+	if len(sent) != 3 || !sent[0].IsHost || sent[1].CompID != "701" || sent[2].CompID != "702" {
+		t.Fatalf("unexpected posts %+v", sent)
+	}
+}
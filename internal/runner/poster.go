@@ -2,9 +2,48 @@ package runner
 
 import (
 	"context"
+	"errors"
+	"fmt"
 )
 
 type Poster interface {
 	PostHost(ctx context.Context, status int) error
 	PostComponent(ctx context.Context, compID string, status int) error
+	PostBatch(ctx context.Context, updates []Update) error
+}
+
+// Update is one status destined for ADCM; CompID is empty for the host.
+type Update struct {
+	IsHost bool
+	CompID string
+	Status int
+}
+
+// BatchError reports partial failure of PostBatch. Errs is aligned with the
+// posted updates and holds nil for the delivered ones.
+type BatchError struct {
+	Errs []error
+}
+
+func (e *BatchError) Error() string {
+	failed := 0
+	var first error
+	for _, err := range e.Errs {
+		if err != nil {
+			failed++
+			if first == nil {
+				first = err
+			}
+		}
+	}
+	return fmt.Sprintf("batch: %d of %d updates failed: %v", failed, len(e.Errs), first)
+}
+
+// itemErr returns the error for the i-th update of a PostBatch call.
+func itemErr(err error, i int) error {
+	var be *BatchError
+	if errors.As(err, &be) && i < len(be.Errs) {
+		return be.Errs[i]
+	}
+	return err
 }
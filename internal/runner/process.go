@@ -0,0 +1,77 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+
+	"github.com/arenadata/ad-status-sender/internal/check"
+	"github.com/arenadata/ad-status-sender/internal/rules"
+)
+
+func (r *Runner) scanProcess(ctx context.Context, cyc *cycle, rr rules.Rules) {
+	for i, rule := range rr.Process {
+		comps := append([]string(nil), rule.Components...)
+		r.check(ctx, cyc, func() result {
+			st := r.countProcesses(ctx, rule)
+			return result{
+				kind: "process", index: i, target: processTarget(rule), status: st, comps: comps, deb: rule.Debounce,
+			}
+		})
+	}
+}
+
+func (r *Runner) countProcesses(ctx context.Context, rule rules.RuleProcess) int {
+	if r.procs == nil {
+		return 1
+	}
+	spec, err := processSpec(rule)
+	if err != nil {
+		r.log.WarnContext(ctx, "process rule invalid", "rule", rule.Name, "err", err)
+		return 1
+	}
+	n, err := r.procs.CountProcesses(ctx, spec)
+	if err != nil {
+		r.log.WarnContext(ctx, "process scan failed", "rule", rule.Name, "err", err)
+		return 1
+	}
+	lo := 1
+	if rule.Min != nil {
+		lo = *rule.Min
+	}
+	if n < lo || (rule.Max > 0 && n > rule.Max) {
+		r.log.DebugContext(ctx, "process count out of range",
+			"rule", rule.Name, "count", n, "min", lo, "max", rule.Max)
+		return 1
+	}
+	return 0
+}
+
+func processSpec(rule rules.RuleProcess) (check.ProcessSpec, error) {
+	spec := check.ProcessSpec{Name: rule.Process, User: rule.User, PidFile: rule.PidFile}
+	if rule.Cmdline != "" {
+		re, err := regexp.Compile(rule.Cmdline)
+		if err != nil {
+			return spec, fmt.Errorf("cmdline: %w", err)
+		}
+		spec.Cmdline = re
+	}
+	if spec.Name == "" && spec.Cmdline == nil && spec.User == "" && spec.PidFile == "" {
+		return spec, errors.New("no selector set")
+	}
+	return spec, nil
+}
+
+func processTarget(rule rules.RuleProcess) string {
+	switch {
+	case rule.Name != "":
+		return rule.Name
+	case rule.PidFile != "":
+		return rule.PidFile
+	case rule.Process != "":
+		return rule.Process
+	default:
+		return rule.Cmdline
+	}
+}
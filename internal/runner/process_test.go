@@ -0,0 +1,52 @@
+package runner
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/arenadata/ad-status-sender/internal/check"
+	"github.com/arenadata/ad-status-sender/internal/check/checktest"
+	"github.com/arenadata/ad-status-sender/internal/config"
+	"github.com/arenadata/ad-status-sender/internal/rules"
+)
+
+func TestRunner_ProcessChecks(t *testing.T) {
+	root := t.TempDir()
+	checktest.WriteProcfs(t, root,
+		checktest.FakeProc{PID: 10, Comm: "gunicorn", Argv: []string{"gunicorn", "app:web"}},
+		checktest.FakeProc{PID: 11, Comm: "gunicorn", Argv: []string{"gunicorn", "app:web"}},
+		checktest.FakeProc{PID: 12, Comm: "gunicorn", Argv: []string{"gunicorn", "app:web"}},
+	)
+
+	post := &testPoster{}
+	cfg := config.Config{ADCMURL: "http://example", HostID: 7, ForceSendAfter: "120s"}
+	r := newTestRunner(t, cfg, &checktest.FakeSystemd{}, &checktest.FakeDocker{}, post,
+		&testClock{now: time.Unix(0, 0)})
+	r.procs = &check.ProcessScanner{ProcRoot: root}
+
+	zero, two := 0, 2
+	r.ruleStore.Set(rules.Rules{
+		Process: []rules.RuleProcess{
+			{Name: "web", Process: "gunicorn", Min: &two, Max: 4, Components: []string{"1101"}},
+			{Name: "too-many", Process: "gunicorn", Max: 2, Components: []string{"1102"}},
+			{Name: "absent", Cmdline: `collector\s+--daemon`, Components: []string{"1103"}},
+			{Name: "optional", Process: "gunicorn", Min: &zero, Max: 3, Components: []string{"1104"}},
+			{Name: "bad-regex", Cmdline: "(", Components: []string{"1105"}},
+			{Name: "no-selector", Components: []string{"1106"}},
+		},
+	})
+
+	r.scanOnce(context.Background())
+	waitUntil(t, func() bool { return post.Count() == 7 }, time.Second)
+
+	want := map[string]int{"1101": 0, "1102": 1, "1103": 1, "1104": 0, "1105": 1, "1106": 1}
+	for _, e := range post.Snapshot() {
+		if e.IsHost {
+			continue
+		}
+		if want[e.CompID] != e.Status {
+			t.Fatalf("comp %s: want %d, got %d", e.CompID, want[e.CompID], e.Status)
+		}
+	}
+}
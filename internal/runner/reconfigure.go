@@ -0,0 +1,249 @@
+package runner
+
+import (
+	"context"
+	"reflect"
+	"sync"
+	"sync/atomic"
+
+	"github.com/arenadata/ad-status-sender/internal/config"
+	"github.com/arenadata/ad-status-sender/internal/rules"
+)
+
+// posterSwitch forwards to the current httpPoster. reload builds a new one
+// and swaps it in, so posts in flight finish on the poster they started
+// with and never see half-applied settings.
+type posterSwitch struct {
+	cur atomic.Pointer[httpPoster]
+}
+
+func (s *posterSwitch) PostHost(ctx context.Context, status int) error {
+	return s.cur.Load().PostHost(ctx, status)
+}
+
+func (s *posterSwitch) PostComponent(ctx context.Context, compID string, status int) error {
+	return s.cur.Load().PostComponent(ctx, compID, status)
+}
+
+func (s *posterSwitch) PostBatch(ctx context.Context, updates []Update) error {
+	return s.cur.Load().PostBatch(ctx, updates)
+}
+
+// workerPool tracks the goroutines running queued jobs, so that their
+// number can follow concurrency while the runner is up.
+type workerPool struct {
+	mu    sync.Mutex
+	ctx   context.Context //nolint:containedctx // bounds workers started on reload
+	stops []context.CancelFunc
+}
+
+// swapPoster installs next unless a Poster was injected. The circuit
+// breaker, set up from retry, and what was learned about the batch endpoint
+// carry over. Once the runner runs, the caller holds reloadMu.
+func (r *Runner) swapPoster(next *httpPoster, retry config.Retry) {
+	if r.post == nil {
+		r.post = &posterSwitch{}
+	}
+	sw, ok := r.post.(*posterSwitch)
+	if !ok {
+		return
+	}
+	if prev := sw.cur.Load(); prev != nil {
+		prev.breaker.configure(retry)
+		next.breaker = prev.breaker
+		if prev.batchPath == next.batchPath {
+			next.batchUnsupported.Store(prev.batchUnsupported.Load())
+		}
+	}
+	sw.cur.Store(next)
+}
+
+// startWorkers runs concurrency workers until ctx is done, replacing any
+// started before.
+func (r *Runner) startWorkers(ctx context.Context) {
+	r.workers.mu.Lock()
+	for _, stop := range r.workers.stops {
+		stop()
+	}
+	r.workers.ctx, r.workers.stops = ctx, nil
+	r.workers.mu.Unlock()
+	cfg, _, _ := r.snapshot()
+	r.resizeWorkers(cfg.Concurrency)
+}
+
+// resizeWorkers starts or stops workers until n are running. A stopped
+// worker finishes its current job first; queued jobs stay for the others.
+func (r *Runner) resizeWorkers(n int) {
+	p := &r.workers
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.ctx == nil {
+		return
+	}
+	for len(p.stops) < n {
+		ctx, stop := context.WithCancel(p.ctx)
+		p.stops = append(p.stops, stop)
+		go r.work(ctx)
+	}
+	for len(p.stops) > n {
+		last := len(p.stops) - 1
+		p.stops[last]()
+		p.stops = p.stops[:last]
+	}
+}
+
+// runContext returns the context of the running runner, done on Stop.
+func (r *Runner) runContext() context.Context {
+	r.workers.mu.Lock()
+	defer r.workers.mu.Unlock()
+	return r.workers.ctx
+}
+
+// running reports whether Start has set the workers up.
+func (r *Runner) running() bool {
+	r.workers.mu.Lock()
+	defer r.workers.mu.Unlock()
+	return r.workers.ctx != nil
+}
+
+func (r *Runner) workerCount() int {
+	r.workers.mu.Lock()
+	defer r.workers.mu.Unlock()
+	return len(r.workers.stops)
+}
+
+// queueDepth returns the number of jobs waiting for a worker; the queue is
+// replaced under r.mu when the runner starts.
+func (r *Runner) queueDepth() int {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return len(r.jobs)
+}
+
+func (r *Runner) work(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case fn, ok := <-r.jobs:
+			if !ok {
+				return
+			}
+			fn()
+		}
+	}
+}
+
+// startRulesWatcher watches the rules location of the current config.
+// Once the runner runs, the caller holds reloadMu.
+func (r *Runner) startRulesWatcher() {
+	stop := make(chan struct{})
+	r.mu.Lock()
+	r.stopWatch = stop
+	cfg := r.cfg
+	r.mu.Unlock()
+	go func() {
+		err := rules.Watch(stop, cfg.RulesPath, cfg.RulesDir, func(rr rules.Rules, loadErr error) {
+			r.reloadMu.Lock()
+			defer r.reloadMu.Unlock()
+			select {
+			case <-stop:
+				return // replaced while waiting for the lock
+			default:
+			}
+			_ = r.applyRules(rr, loadErr)
+		})
+		if err != nil {
+			r.log.Error("rules watch", "err", err)
+		}
+	}()
+}
+
+func (r *Runner) stopRulesWatcher() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.stopWatch != nil {
+		close(r.stopWatch)
+		r.stopWatch = nil
+	}
+}
+
+// startConfigWatcher reloads the config and rules whenever the config file
+// changes, until ctx is done.
+func (r *Runner) startConfigWatcher(ctx context.Context) {
+	go func() {
+		if err := config.Watch(ctx.Done(), r.loader.Path, r.reloadAll); err != nil {
+			r.log.Error("config watch", "err", err)
+		}
+	}()
+}
+
+// reloadAll re-reads the config, then the rules, as on SIGHUP, and scans
+// right away with the result. Concurrent calls run one after the other.
+func (r *Runner) reloadAll() {
+	r.reloadMu.Lock()
+	defer r.reloadMu.Unlock()
+	if err := r.reload(); err != nil {
+		r.log.Error("reload config, keeping previous config", "err", err)
+	}
+	_ = r.loadRulesOnce()
+	r.kick()
+}
+
+// reconfigure applies what changed from prev to c to the running runner.
+// Settings only read by Start are reported instead. The caller holds
+// reloadMu.
+func (r *Runner) reconfigure(prev, c config.Config) {
+	if c.Concurrency != prev.Concurrency {
+		r.resizeWorkers(c.Concurrency)
+		r.log.Info("workers resized", "from", prev.Concurrency, "to", c.Concurrency)
+	}
+	if c.RulesPath != prev.RulesPath || c.RulesDir != prev.RulesDir {
+		r.stopRulesWatcher()
+		r.startRulesWatcher()
+		r.log.Info("rules location changed", "rules_path", c.RulesPath, "rules_dir", c.RulesDir)
+	}
+	if c.HostID != prev.HostID {
+		// every key names the host; nothing has been sent to the new one
+		r.cacheMu.Lock()
+		dropped := len(r.cache)
+		r.cache = make(map[string]lastSend)
+		r.cacheMu.Unlock()
+		r.log.Info("host_id changed, send cache cleared", "from", prev.HostID, "to", c.HostID, "entries", dropped)
+	}
+	otherADCM := c.HostID != prev.HostID || c.ADCMURL != prev.ADCMURL
+	if c.Discovery.Enabled && (otherADCM || c.Discovery.Path != prev.Discovery.Path) {
+		if otherADCM {
+			// the known component IDs belong to the previous host: stop
+			// posting to them before the new ones are known
+			r.forgetComponents()
+			r.installRules()
+		}
+		ctx := r.runContext()
+		go func() { _ = r.refreshComponents(ctx) }()
+	}
+	if fields := restartOnly(prev, c); len(fields) > 0 {
+		r.log.Warn("config changes take effect after a restart", "fields", fields)
+	}
+}
+
+// restartOnly lists the changed settings that are only read by Start.
+func restartOnly(prev, c config.Config) []string {
+	var fields []string
+	if prev.Server != c.Server {
+		fields = append(fields, "server")
+	}
+	if !reflect.DeepEqual(prev.Tracing, c.Tracing) {
+		fields = append(fields, "tracing")
+	}
+	if prev.Events.Systemd != c.Events.Systemd || prev.Events.Docker != c.Events.Docker {
+		fields = append(fields, "events")
+	}
+	if prev.Discovery.Enabled != c.Discovery.Enabled || prev.Discovery.Refresh != c.Discovery.Refresh {
+		fields = append(fields, "component_discovery")
+	}
+	if prev.WatchConfig != c.WatchConfig {
+		fields = append(fields, "watch_config")
+	}
+	return fields
+}
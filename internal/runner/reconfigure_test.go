@@ -0,0 +1,185 @@
+package runner
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/arenadata/ad-status-sender/internal/check/checktest"
+)
+
+type adcmStub struct {
+	mu    sync.Mutex
+	paths []string
+}
+
+func (s *adcmStub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	s.paths = append(s.paths, r.URL.Path)
+	s.mu.Unlock()
+	w.WriteHeader(http.StatusOK)
+}
+
+func (s *adcmStub) got(path string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, p := range s.paths {
+		if p == path {
+			return true
+		}
+	}
+	return false
+}
+
+func TestRunner_ConfigWatchReconfigures(t *testing.T) {
+	stubA, stubB := &adcmStub{}, &adcmStub{}
+	srvA, srvB := httptest.NewServer(stubA), httptest.NewServer(stubB)
+	defer srvA.Close()
+	defer srvB.Close()
+
+	dir := t.TempDir()
+	write := func(name, data string) string {
+		t.Helper()
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	rulesA := write("a.yaml", "systemd:\n  - unit: a.service\n    components: [\"1\"]\n")
+	rulesB := write("b.yaml", "systemd:\n  - unit: b.service\n    components: [\"2\"]\n")
+	config := func(url string, hostID int, rulesPath string, workers int) string {
+		return fmt.Sprintf("adcm_url: %s\nhost_id: %d\ntoken: t\nrules_path: %s\n"+
+			"interval: 1h\nconcurrency: %d\nwatch_config: true\n", url, hostID, rulesPath, workers)
+	}
+	cfgPath := write("config.yaml", config(srvA.URL, 7, rulesA, 2))
+
+	sd := &checktest.FakeSystemd{Units: map[string]bool{"a.service": true, "b.service": true}}
+	r := NewWithDeps(cfgPath, nil, sd, &checktest.FakeDocker{}, nil, nil)
+	if err := r.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer r.Stop()
+	waitUntil(t, func() bool { return stubA.got("/status/api/v1/host/7/component/1/") }, 2*time.Second)
+
+	sw, ok := r.post.(*posterSwitch)
+	if !ok {
+		t.Fatalf("want a posterSwitch, got %T", r.post)
+	}
+	first := sw.cur.Load()
+	if r.workerCount() != 2 {
+		t.Fatalf("want 2 workers, got %d", r.workerCount())
+	}
+
+	write("config.yaml", config(srvB.URL, 8, rulesB, 4))
+	waitUntil(t, func() bool { return stubB.got("/status/api/v1/host/8/component/2/") }, 3*time.Second)
+
+	if r.workerCount() != 4 {
+		t.Fatalf("want 4 workers, got %d", r.workerCount())
+	}
+	if cur := sw.cur.Load(); cur == first || cur.breaker != first.breaker || first.adcmURL != srvA.URL {
+		t.Fatal("poster must be replaced, not modified, and keep its breaker")
+	}
+	r.cacheMu.Lock()
+	for key := range r.cache {
+		if strings.Contains(key, ":7") {
+			t.Errorf("cache entry %q of the old host survived", key)
+		}
+	}
+	r.cacheMu.Unlock()
+
+	// the rules watcher follows rules_path
+	write("b.yaml", "systemd:\n  - unit: b.service\n    components: [\"3\"]\n")
+	waitUntil(t, func() bool {
+		rr := r.ruleStore.Get()
+		return len(rr.Systemd) == 1 && rr.Systemd[0].Components[0] == "3"
+	}, 2*time.Second)
+	write("a.yaml", "systemd:\n  - unit: a.service\n    components: [\"9\"]\n")
+	time.Sleep(300 * time.Millisecond)
+	if rr := r.ruleStore.Get(); rr.Systemd[0].Unit != "b.service" {
+		t.Fatalf("old rules_path still watched: %+v", rr.Systemd)
+	}
+}
+
+func TestRunner_ConcurrentReloads(t *testing.T) {
+	stub := &adcmStub{}
+	srv := httptest.NewServer(stub)
+	defer srv.Close()
+
+	dir := t.TempDir()
+	write := func(name, data string) string {
+		t.Helper()
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path+".tmp", []byte(data), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Rename(path+".tmp", path); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	rulesA := write("a.yaml", "systemd:\n  - unit: a.service\n    components: [\"1\"]\n")
+	rulesB := write("b.yaml", "systemd:\n  - unit: b.service\n    components: [\"2\"]\n")
+	config := func(rulesPath string, workers int) string {
+		return fmt.Sprintf("adcm_url: %s\nhost_id: 7\ntoken: t\nrules_path: %s\ninterval: 1h\nconcurrency: %d\n",
+			srv.URL, rulesPath, workers)
+	}
+	cfgPath := write("config.yaml", config(rulesA, 1))
+
+	sd := &checktest.FakeSystemd{Units: map[string]bool{"a.service": true, "b.service": true}}
+	r := NewWithDeps(cfgPath, nil, sd, &checktest.FakeDocker{}, nil, nil)
+	if err := r.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer r.Stop()
+	sw, ok := r.post.(*posterSwitch)
+	if !ok {
+		t.Fatalf("want a posterSwitch, got %T", r.post)
+	}
+	breaker := sw.cur.Load().breaker
+
+	// reloads racing each other while the config moves between the rules files
+	for i := range 10 {
+		rulesPath := rulesA
+		if i%2 == 1 {
+			rulesPath = rulesB
+		}
+		write("config.yaml", config(rulesPath, i%3+1))
+		var wg sync.WaitGroup
+		for range 4 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				r.reloadAll()
+			}()
+		}
+		wg.Wait()
+	}
+
+	if rr := r.ruleStore.Get(); len(rr.Systemd) != 1 || rr.Systemd[0].Unit != "b.service" {
+		t.Fatalf("want the rules of b.yaml, got %+v", rr.Systemd)
+	}
+	if r.workerCount() != 1 {
+		t.Fatalf("want the 1 worker of the last config, got %d", r.workerCount())
+	}
+	if sw.cur.Load().breaker != breaker {
+		t.Fatal("breaker not handed over")
+	}
+	// only b.yaml is watched
+	write("a.yaml", "systemd:\n  - unit: a.service\n    components: [\"9\"]\n")
+	time.Sleep(300 * time.Millisecond)
+	if rr := r.ruleStore.Get(); rr.Systemd[0].Unit != "b.service" {
+		t.Fatalf("a stale watcher applied a.yaml: %+v", rr.Systemd)
+	}
+	write("b.yaml", "systemd:\n  - unit: b.service\n    components: [\"3\"]\n")
+	waitUntil(t, func() bool {
+		rr := r.ruleStore.Get()
+		return len(rr.Systemd) == 1 && rr.Systemd[0].Components[0] == "3"
+	}, 2*time.Second)
+}
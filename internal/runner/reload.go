@@ -0,0 +1,67 @@
+package runner
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/arenadata/ad-status-sender/internal/rules"
+)
+
+const (
+	reloadOK      = "ok"
+	reloadPartial = "partial"
+	reloadFail    = "fail"
+	reloadRefused = "refused"
+)
+
+func (r *Runner) loadRulesOnce() error {
+	cfg, _, _ := r.snapshot()
+	return r.applyRules(rules.LoadAll(cfg.RulesPath, cfg.RulesDir))
+}
+
+// applyRules installs the result of rules.LoadAll. A failed load keeps the
+// current rules, and so does one removing more of them than
+// rules_reload.max_drop_percent allows. Skipped files and rules are logged;
+// a file that fails to load keeps its current rules and the rules of the
+// other files apply. Every attempt is logged and counted with what it adds,
+// removes and changes.
+func (r *Runner) applyRules(rr rules.Rules, err error) error {
+	var skipped *rules.SkippedError
+	switch {
+	case errors.As(err, &skipped):
+		for _, e := range skipped.Errs {
+			r.log.Warn("rules skipped", "err", e)
+		}
+		var kept []string
+		rr, kept = rr.KeepBroken(r.loaded.Get())
+		for _, file := range kept {
+			r.log.Warn("keeping previous rules of file", "file", file)
+		}
+	case err != nil:
+		r.met.reloaded(reloadFail, rules.Change{})
+		r.log.Warn("rules reload failed, keeping previous rules", "result", reloadFail, "err", err)
+		return err
+	}
+
+	cfg, _, _ := r.snapshot()
+	ch := rules.Diff(r.loaded.Get(), rr)
+	if limit := cfg.RulesReload.MaxDropPercent; limit > 0 && ch.DropPercent() > limit {
+		refused := fmt.Errorf("reload removes %d of %d rules (%.0f%%), more than max_drop_percent %v",
+			len(ch.Removed), ch.Before, ch.DropPercent(), limit)
+		r.met.reloaded(reloadRefused, rules.Change{})
+		r.log.Error("rules reload refused, keeping previous rules",
+			"result", reloadRefused, "err", refused, "removed", ch.Removed)
+		return refused
+	}
+
+	r.loaded.Set(rr)
+	r.installRules()
+	result := reloadOK
+	if skipped != nil {
+		result = reloadPartial
+	}
+	r.met.reloaded(result, ch)
+	r.log.Info("rules reloaded", "result", result, "rules", ch.After,
+		"added", ch.Added, "removed", ch.Removed, "changed", ch.Changed)
+	return nil
+}
@@ -0,0 +1,94 @@
+package runner
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/arenadata/ad-status-sender/internal/config"
+	"github.com/arenadata/ad-status-sender/internal/rules"
+)
+
+func systemdRules(units ...string) rules.Rules {
+	var rr rules.Rules
+	for _, u := range units {
+		rr.Systemd = append(rr.Systemd, rules.RuleSystemd{Unit: u, Components: []string{"1"}})
+	}
+	return rr
+}
+
+func TestRunner_ApplyRulesGuard(t *testing.T) {
+	r := NewWithDeps("unused.yaml", nil, nil, nil, &testPoster{}, nil)
+	r.cfg = config.Config{RulesReload: config.RulesReload{MaxDropPercent: 50}}
+
+	if err := r.applyRules(systemdRules("a", "b", "c", "d"), nil); err != nil {
+		t.Fatal(err)
+	}
+	// a broken load keeps the rules
+	if err := r.applyRules(rules.Rules{}, errors.New("rules.yaml:3:5: unknown field")); err == nil {
+		t.Fatal("want the load error back")
+	}
+	// three of four gone: more than 50%
+	err := r.applyRules(systemdRules("a"), nil)
+	if err == nil || !strings.Contains(err.Error(), "removes 3 of 4 rules") {
+		t.Fatalf("want the reload refused, got %v", err)
+	}
+	if got := len(r.ruleStore.Get().Systemd); got != 4 {
+		t.Fatalf("refused reload must keep the rules, got %d", got)
+	}
+	// two of four removed, one added, with a file skipped
+	skipped := &rules.SkippedError{Errs: []error{errors.New("conf.d/x.yaml: file is empty")}}
+	if err = r.applyRules(systemdRules("a", "b", "e"), skipped); err != nil {
+		t.Fatal(err)
+	}
+	if got := len(r.ruleStore.Get().Systemd); got != 3 {
+		t.Fatalf("want 3 rules applied, got %d", got)
+	}
+
+	wantSamples(t, scrape(t, r.metrics),
+		`ad_status_sender_rules_reloads_total{result="ok"} 1`,
+		`ad_status_sender_rules_reloads_total{result="fail"} 1`,
+		`ad_status_sender_rules_reloads_total{result="refused"} 1`,
+		`ad_status_sender_rules_reloads_total{result="partial"} 1`,
+		`ad_status_sender_rules_changes_total{change="added"} 5`,
+		`ad_status_sender_rules_changes_total{change="removed"} 2`,
+		`ad_status_sender_rules_changes_total{change="changed"} 0`,
+	)
+}
+
+func TestRunner_ReloadKeepsRulesOfBrokenFile(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, data string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(data), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("10-a.yaml", "systemd:\n  - unit: a.service\n    components: [\"1\"]\n")
+	write("20-b.yaml", "systemd:\n  - unit: b.service\n    components: [\"2\"]\n")
+	r := NewWithDeps("unused.yaml", nil, nil, nil, &testPoster{}, nil)
+	r.cfg = config.Config{RulesDir: dir}
+	if err := r.loadRulesOnce(); err != nil {
+		t.Fatal(err)
+	}
+
+	write("20-b.yaml", "systemd: [")
+	write("30-c.yaml", "systemd:\n  - unit: c.service\n    components: [\"3\"]\n")
+	if err := r.loadRulesOnce(); err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, rule := range r.ruleStore.Get().Systemd {
+		got = append(got, rule.Unit)
+	}
+	if !slices.Equal(got, []string{"a.service", "b.service", "c.service"}) {
+		t.Fatalf("want the rules of the broken file kept, got %v", got)
+	}
+	wantSamples(t, scrape(t, r.metrics),
+		`ad_status_sender_rules_reloads_total{result="partial"} 1`,
+		`ad_status_sender_rules_changes_total{change="removed"} 0`,
+	)
+}
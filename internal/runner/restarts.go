@@ -0,0 +1,148 @@
+package runner
+
+import (
+	"context"
+	"maps"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/arenadata/ad-status-sender/internal/check"
+	"github.com/arenadata/ad-status-sender/internal/config"
+	"github.com/arenadata/ad-status-sender/internal/rules"
+)
+
+const (
+	defaultRestartWindow = 10 * time.Minute
+	staleWindows         = 2
+)
+
+// restartTracker keeps per-target restart history between cycles, next to
+// the send cache, to catch units and containers that restart in a loop but
+// look healthy whenever they are polled.
+type restartTracker struct {
+	mu      sync.Mutex
+	targets map[string]*restartHistory
+}
+
+type restartHistory struct {
+	last     check.Restarts
+	seenAt   time.Time
+	restarts []time.Time
+	flapping bool
+}
+
+// observe records a sample for key and returns the number of restarts seen
+// within window, plus whether the flapping verdict changed.
+func (t *restartTracker) observe(
+	key string,
+	s check.Restarts,
+	now time.Time,
+	window time.Duration,
+	limit int,
+) (int, bool, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.targets == nil {
+		t.targets = make(map[string]*restartHistory)
+	}
+	for k, h := range t.targets {
+		// targets no longer sampled (rule removed, glob changed) go away
+		if now.Sub(h.seenAt) > staleWindows*window {
+			delete(t.targets, k)
+		}
+	}
+	h, ok := t.targets[key]
+	if !ok {
+		t.targets[key] = &restartHistory{last: s, seenAt: now}
+		return 0, false, false
+	}
+	delta := max(s.Count-h.last.Count, 0)
+	if delta == 0 && !s.StartedAt.IsZero() && !h.last.StartedAt.IsZero() && !s.StartedAt.Equal(h.last.StartedAt) {
+		// restarted by hand or the counter was reset
+		delta = 1
+	}
+	for range delta {
+		h.restarts = append(h.restarts, now)
+	}
+	cut := now.Add(-window)
+	h.restarts = slices.DeleteFunc(h.restarts, func(at time.Time) bool { return !at.After(cut) })
+	h.last, h.seenAt = s, now
+
+	n := len(h.restarts)
+	flapping := n > limit
+	changed := flapping != h.flapping
+	h.flapping = flapping
+	return n, flapping, changed
+}
+
+// last returns the latest sample recorded for key.
+func (t *restartTracker) last(key string) (check.Restarts, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	h, ok := t.targets[key]
+	if !ok {
+		return check.Restarts{}, false
+	}
+	return h.last, true
+}
+
+// restartStatus applies lim to status: a target that is up but restarted too
+// often within the window is reported with the limit's status.
+func (r *Runner) restartStatus(
+	ctx context.Context,
+	kind string,
+	lim *rules.RestartLimit,
+	status int,
+	samples map[string]check.Restarts,
+) int {
+	window := config.MustDuration(lim.Window, defaultRestartWindow)
+	now := r.clk.Now()
+	flapping := false
+	for _, target := range slices.Sorted(maps.Keys(samples)) {
+		n, bad, changed := r.restarts.observe(kind+":"+target, samples[target], now, window, lim.Max)
+		switch {
+		case changed && bad:
+			r.log.WarnContext(ctx, "restart loop detected", "kind", kind, "target", target,
+				"restarts", n, "max", lim.Max, "window", window)
+		case changed:
+			r.log.InfoContext(ctx, "restart loop cleared", "kind", kind, "target", target, "restarts", n)
+		}
+		flapping = flapping || bad
+	}
+	if !flapping || status != 0 {
+		return status
+	}
+	if lim.Status != 0 {
+		return lim.Status
+	}
+	return 1
+}
+
+// unitRestarts samples a systemd unit, if the backend can.
+func (r *Runner) unitRestarts(ctx context.Context, unit string) map[string]check.Restarts {
+	src, ok := r.sd.(check.SystemdRestarts)
+	if !ok {
+		return nil
+	}
+	s, err := src.UnitRestarts(ctx, unit)
+	if err != nil {
+		r.log.DebugContext(ctx, "systemd restarts unavailable", "unit", unit, "err", err)
+		return nil
+	}
+	return map[string]check.Restarts{unit: s}
+}
+
+// containerRestarts samples the containers of a docker rule, if the backend can.
+func (r *Runner) containerRestarts(ctx context.Context, sel rules.DockerSelector) map[string]check.Restarts {
+	src, ok := r.dck.(check.DockerRestarts)
+	if !ok {
+		return nil
+	}
+	samples, err := src.ContainerRestarts(ctx, sel.Names, sel.Labels)
+	if err != nil {
+		r.log.DebugContext(ctx, "docker restarts unavailable", "err", err)
+		return nil
+	}
+	return samples
+}
@@ -0,0 +1,106 @@
+package runner
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/arenadata/ad-status-sender/internal/check"
+	"github.com/arenadata/ad-status-sender/internal/check/checktest"
+	"github.com/arenadata/ad-status-sender/internal/config"
+	"github.com/arenadata/ad-status-sender/internal/rules"
+)
+
+func TestRestartTracker_Window(t *testing.T) {
+	var tr restartTracker
+	t0 := time.Unix(0, 0)
+	started := t0.Add(-time.Hour)
+
+	n, bad, _ := tr.observe("systemd:a", check.Restarts{Count: 5, StartedAt: started}, t0, time.Minute, 1)
+	if n != 0 || bad {
+		t.Fatalf("first sample must not count history, got n=%d bad=%v", n, bad)
+	}
+	// two automatic restarts
+	n, bad, changed := tr.observe("systemd:a", check.Restarts{Count: 7, StartedAt: t0},
+		t0.Add(10*time.Second), time.Minute, 1)
+	if n != 2 || !bad || !changed {
+		t.Fatalf("want 2 restarts flapping, got n=%d bad=%v changed=%v", n, bad, changed)
+	}
+	// manual restart: counter unchanged, start time moved
+	n, _, changed = tr.observe("systemd:a", check.Restarts{Count: 7, StartedAt: t0.Add(20 * time.Second)},
+		t0.Add(20*time.Second), time.Minute, 1)
+	if n != 3 || changed {
+		t.Fatalf("want 3 restarts, still flapping, got n=%d changed=%v", n, changed)
+	}
+	// quiet for longer than the window
+	n, bad, changed = tr.observe("systemd:a", check.Restarts{Count: 7, StartedAt: t0.Add(20 * time.Second)},
+		t0.Add(90*time.Second), time.Minute, 1)
+	if n != 0 || bad || !changed {
+		t.Fatalf("want cleared, got n=%d bad=%v changed=%v", n, bad, changed)
+	}
+	// counter reset (unit reloaded) is not a burst of restarts
+	if n, _, _ = tr.observe("systemd:a", check.Restarts{Count: 0, StartedAt: t0.Add(20 * time.Second)},
+		t0.Add(100*time.Second), time.Minute, 1); n != 0 {
+		t.Fatalf("counter reset counted as %d restarts", n)
+	}
+}
+
+func TestRunner_RestartLoopMarksComponentFailed(t *testing.T) {
+	clk := &testClock{now: time.Unix(10_000, 0)}
+	sd := &checktest.FakeSystemd{Units: map[string]bool{"kafka.service": true}}
+	dck := &checktest.FakeDocker{Names: map[string]bool{"ranger": true}}
+	post := &testPoster{}
+	cfg := config.Config{ADCMURL: "http://example", HostID: 7, ForceSendAfter: "1h"}
+	r := newTestRunner(t, cfg, sd, dck, post, clk)
+
+	r.ruleStore.Set(rules.Rules{
+		Systemd: []rules.RuleSystemd{{
+			Unit:         "kafka.service",
+			Components:   []string{"1301"},
+			RestartLimit: &rules.RestartLimit{Max: 2, Window: "5m"},
+		}},
+		Docker: []rules.RuleDocker{{
+			Name:         "ranger",
+			Containers:   rules.DockerSelector{Names: []string{"ranger"}},
+			Components:   []string{"1302"},
+			RestartLimit: &rules.RestartLimit{Max: 0, Window: "5m", Status: 2},
+		}},
+	})
+
+	status := func(comp string) int {
+		r.cacheMu.Lock()
+		defer r.cacheMu.Unlock()
+		if ls, ok := r.cache["comp:7:"+comp]; ok {
+			return ls.status
+		}
+		return -1
+	}
+	scan := func(wantKafka, wantRanger int) {
+		t.Helper()
+		r.scanOnce(context.Background())
+		waitUntil(t, func() bool { return status("1301") == wantKafka && status("1302") == wantRanger }, time.Second)
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	sd.SetRestarts("kafka.service", check.Restarts{Count: 1})
+	dck.SetRestarts("ranger", check.Restarts{Count: 0})
+	scan(0, 0)
+
+	clk.advance(time.Minute)
+	sd.SetRestarts("kafka.service", check.Restarts{Count: 3})
+	scan(0, 0)
+
+	clk.advance(time.Minute)
+	sd.SetRestarts("kafka.service", check.Restarts{Count: 4})
+	dck.SetRestarts("ranger", check.Restarts{Count: 1})
+	scan(1, 2)
+
+	// a target that is down keeps its own status
+	sd.Set("kafka.service", false)
+	clk.advance(time.Minute)
+	scan(1, 2)
+
+	sd.Set("kafka.service", true)
+	clk.advance(6 * time.Minute)
+	scan(0, 0)
+}
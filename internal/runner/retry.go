@@ -0,0 +1,279 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/arenadata/ad-status-sender/internal/config"
+)
+
+const (
+	maxErrBody = 256
+	jitterDiv  = 2
+)
+
+var (
+	ErrAuth        = errors.New("adcm: authentication failed")
+	ErrClient      = errors.New("adcm: request rejected")
+	ErrServer      = errors.New("adcm: server error")
+	ErrThrottled   = errors.New("adcm: throttled")
+	ErrCircuitOpen = errors.New("adcm: circuit open, not posting")
+)
+
+// HTTPError is returned for non-2xx answers. It unwraps to one of ErrAuth,
+// ErrClient, ErrServer or ErrThrottled.
+type HTTPError struct {
+	Code       int
+	RetryAfter time.Duration
+	Body       string
+	kind       error
+}
+
+func (e *HTTPError) Error() string {
+	msg := fmt.Sprintf("%v: http %d", e.kind, e.Code)
+	if e.Body != "" {
+		msg += ": " + e.Body
+	}
+	return msg
+}
+
+func (e *HTTPError) Unwrap() error { return e.kind }
+
+func newHTTPError(resp *http.Response, body string) *HTTPError {
+	e := &HTTPError{Code: resp.StatusCode, Body: truncate(body, maxErrBody)}
+	switch {
+	case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+		e.kind = ErrAuth
+	case resp.StatusCode == http.StatusTooManyRequests:
+		e.kind = ErrThrottled
+		e.RetryAfter = parseRetryAfter(resp.Header.Get("Retry-After"), time.Now())
+	case resp.StatusCode >= http.StatusInternalServerError:
+		e.kind = ErrServer
+		e.RetryAfter = parseRetryAfter(resp.Header.Get("Retry-After"), time.Now())
+	default:
+		e.kind = ErrClient
+	}
+	return e
+}
+
+// parseRetryAfter accepts both delta-seconds and HTTP-date forms.
+func parseRetryAfter(v string, now time.Time) time.Duration {
+	v = strings.TrimSpace(v)
+	if v == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(v); err == nil && secs > 0 {
+		return time.Duration(secs) * time.Second
+	}
+	if t, err := http.ParseTime(v); err == nil && t.After(now) {
+		return t.Sub(now)
+	}
+	return 0
+}
+
+func truncate(s string, n int) string {
+	s = strings.TrimSpace(s)
+	if len(s) <= n {
+		return s
+	}
+	return s[:n] + "..."
+}
+
+// retryable reports whether err is worth another attempt: transport errors
+// (including client timeouts), 5xx and 429. Auth and other 4xx answers are
+// final.
+func retryable(err error) bool {
+	if err == nil {
+		return false
+	}
+	return !errors.Is(err, ErrAuth) && !errors.Is(err, ErrClient) && !errors.Is(err, ErrCircuitOpen)
+}
+
+type retryPolicy struct {
+	attempts   int
+	backoff    time.Duration
+	maxBackoff time.Duration
+}
+
+func newRetryPolicy(c config.Retry) retryPolicy {
+	p := retryPolicy{
+		attempts:   c.MaxAttempts,
+		backoff:    config.MustDuration(c.InitialBackoff, config.DefaultRetryBackoff),
+		maxBackoff: config.MustDuration(c.MaxBackoff, config.DefaultRetryMaxBackoff),
+	}
+	if p.attempts <= 0 {
+		p.attempts = config.DefaultRetryAttempts
+	}
+	return p
+}
+
+// delay returns the wait before attempt n+1: exponential backoff with equal
+// jitter, or the server's Retry-After if that is longer.
+func (p retryPolicy) delay(n int, err error) (time.Duration, bool) {
+	d := p.backoffAfter(n)
+	if d > 0 {
+		half := d / jitterDiv
+		d = half + rand.N(half+1)
+	}
+	var he *HTTPError
+	if errors.As(err, &he) && he.RetryAfter > 0 {
+		if he.RetryAfter > p.maxBackoff {
+			return 0, false
+		}
+		d = max(d, he.RetryAfter)
+	}
+	return d, true
+}
+
+// backoffAfter doubles the initial backoff n times, stopping at maxBackoff
+// so that a large max_attempts can't overflow it.
+func (p retryPolicy) backoffAfter(n int) time.Duration {
+	d := p.backoff
+	for range n {
+		if d <= 0 || d >= p.maxBackoff {
+			break
+		}
+		if d > p.maxBackoff/2 {
+			return p.maxBackoff
+		}
+		d *= 2
+	}
+	return min(d, p.maxBackoff)
+}
+
+// do runs fn until it succeeds, fails with a final error or attempts run out.
+func (p retryPolicy) do(ctx context.Context, fn func() error) error {
+	attempts := max(p.attempts, 1)
+	var err error
+	for n := range attempts {
+		if err = fn(); !retryable(err) || n == attempts-1 || ctx.Err() != nil {
+			return err
+		}
+		d, ok := p.delay(n, err)
+		if !ok || !sleepCtx(ctx, d) {
+			return err
+		}
+	}
+	return err
+}
+
+type breakerState int
+
+const (
+	breakerClosed breakerState = iota
+	breakerOpen
+	breakerHalfOpen
+)
+
+// breaker stops posting after threshold consecutive retryable failures and
+// lets a single probe through every cooldown until one succeeds.
+type breaker struct {
+	mu        sync.Mutex
+	threshold int
+	cooldown  time.Duration
+	now       func() time.Time
+
+	state    breakerState
+	failures int
+	openedAt time.Time
+}
+
+func newBreaker(c config.Retry) *breaker {
+	b := &breaker{now: time.Now}
+	b.configure(c)
+	return b
+}
+
+func (b *breaker) configure(c config.Retry) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.threshold = c.BreakerThreshold
+	if b.threshold == 0 {
+		b.threshold = config.DefaultBreakerThreshold
+	}
+	b.cooldown = config.MustDuration(c.BreakerCooldown, config.DefaultBreakerCooldown)
+}
+
+// allow reports whether a request may be sent now.
+func (b *breaker) allow() bool {
+	if b == nil {
+		return true
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	switch b.state {
+	case breakerOpen:
+		if b.now().Sub(b.openedAt) < b.cooldown {
+			return false
+		}
+		b.state = breakerHalfOpen
+		return true
+	case breakerHalfOpen:
+		return false
+	case breakerClosed:
+		return true
+	default:
+		return true
+	}
+}
+
+// done records the outcome of a request let through by allow. It returns
+// the state transition, if any, for logging. A failure of a request sent
+// before the breaker opened is not counted: it would only push the next
+// probe back.
+func (b *breaker) done(err error) (breakerState, bool) {
+	if b == nil {
+		return breakerClosed, false
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.threshold < 0 {
+		return b.state, false
+	}
+	prev := b.state
+	switch {
+	case !retryable(err):
+		b.state, b.failures = breakerClosed, 0
+	case prev == breakerOpen:
+		// sent while closed, answered after the breaker opened
+	default:
+		b.failures++
+		if prev == breakerHalfOpen || b.failures >= b.threshold {
+			b.state, b.openedAt = breakerOpen, b.now()
+		}
+	}
+	return b.state, prev != b.state
+}
+
+// abort gives back a request let through by allow whose outcome says
+// nothing about ADCM. A probe let through while half-open is owed again.
+func (b *breaker) abort() {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.state == breakerHalfOpen {
+		b.state = breakerOpen
+	}
+}
+
+func (s breakerState) String() string {
+	switch s {
+	case breakerOpen:
+		return "open"
+	case breakerHalfOpen:
+		return "half-open"
+	case breakerClosed:
+		return "closed"
+	default:
+		return "unknown"
+	}
+}
@@ -0,0 +1,197 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/arenadata/ad-status-sender/internal/config"
+)
+
+func newTestPoster(url string, retry config.Retry) *httpPoster {
+	return &httpPoster{
+		log:     slog.Default(),
+		c:       makeHTTPClient(config.Config{ADCMURL: url}),
+		adcmURL: url,
+		hostID:  7,
+		token:   "T",
+		retry:   newRetryPolicy(retry),
+		breaker: newBreaker(retry),
+	}
+}
+
+func TestHTTPPoster_TypedErrors(t *testing.T) {
+	cases := []struct {
+		code int
+		want error
+	}{
+		{http.StatusUnauthorized, ErrAuth},
+		{http.StatusForbidden, ErrAuth},
+		{http.StatusBadRequest, ErrClient},
+		{http.StatusNotFound, ErrClient},
+		{http.StatusTooManyRequests, ErrThrottled},
+		{http.StatusBadGateway, ErrServer},
+	}
+	for _, c := range cases {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			w.WriteHeader(c.code)
+		}))
+		p := newTestPoster(srv.URL, config.Retry{MaxAttempts: 1, BreakerThreshold: -1})
+		err := p.PostComponent(context.Background(), "1", 0)
+		srv.Close()
+
+		var he *HTTPError
+		if !errors.Is(err, c.want) || !errors.As(err, &he) || he.Code != c.code {
+			t.Fatalf("code %d: want %v, got %v", c.code, c.want, err)
+		}
+	}
+}
+
+func TestHTTPPoster_RetriesRetryableFailures(t *testing.T) {
+	var calls int32
+	var reject atomic.Bool
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		n := atomic.AddInt32(&calls, 1)
+		if reject.Load() {
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			return
+		}
+		switch n {
+		case 1:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		case 2:
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusTooManyRequests)
+		default:
+			w.WriteHeader(http.StatusOK)
+		}
+	}))
+	defer srv.Close()
+
+	p := newTestPoster(srv.URL, config.Retry{MaxAttempts: 3, InitialBackoff: "1ms", MaxBackoff: "2s"})
+	start := time.Now()
+	if err := p.PostHost(context.Background(), 0); err != nil {
+		t.Fatalf("want success after retries, got %v", err)
+	}
+	if atomic.LoadInt32(&calls) != 3 {
+		t.Fatalf("want 3 attempts, got %d", calls)
+	}
+	if time.Since(start) < time.Second {
+		t.Fatalf("Retry-After was not honored")
+	}
+
+	// client errors are final
+	atomic.StoreInt32(&calls, 0)
+	reject.Store(true)
+	if err := p.PostHost(context.Background(), 0); !errors.Is(err, ErrClient) {
+		t.Fatalf("want ErrClient, got %v", err)
+	}
+	if atomic.LoadInt32(&calls) != 1 {
+		t.Fatalf("client error must not be retried, got %d attempts", calls)
+	}
+}
+
+func TestBreaker_OpensAndProbes(t *testing.T) {
+	now := time.Unix(0, 0)
+	b := newBreaker(config.Retry{BreakerThreshold: 2, BreakerCooldown: "30s"})
+	b.now = func() time.Time { return now }
+	fail := &HTTPError{Code: http.StatusBadGateway, kind: ErrServer}
+
+	for range 2 {
+		if !b.allow() {
+			t.Fatalf("closed breaker must allow")
+		}
+		b.done(fail)
+	}
+	if b.allow() {
+		t.Fatalf("breaker must be open after threshold failures")
+	}
+
+	now = now.Add(31 * time.Second)
+	if !b.allow() {
+		t.Fatalf("probe must be allowed after cooldown")
+	}
+	if b.allow() {
+		t.Fatalf("only one probe may be in flight")
+	}
+	b.done(fail)
+	if b.allow() {
+		t.Fatalf("failed probe must reopen the breaker")
+	}
+
+	now = now.Add(31 * time.Second)
+	_ = b.allow()
+	if st, changed := b.done(nil); st != breakerClosed || !changed {
+		t.Fatalf("successful probe must close the breaker, got %v", st)
+	}
+	if !b.allow() {
+		t.Fatalf("closed breaker must allow")
+	}
+}
+
+func TestBreaker_LateFailureKeepsCooldown(t *testing.T) {
+	now := time.Unix(0, 0)
+	b := newBreaker(config.Retry{BreakerThreshold: 1, BreakerCooldown: "30s"})
+	b.now = func() time.Time { return now }
+	fail := &HTTPError{Code: http.StatusBadGateway, kind: ErrServer}
+
+	// two requests in flight; the first failure opens the breaker
+	b.allow()
+	b.allow()
+	b.done(fail)
+	now = now.Add(20 * time.Second)
+	if st, changed := b.done(fail); st != breakerOpen || changed {
+		t.Fatalf("late failure: want open without a transition, got %v %v", st, changed)
+	}
+	now = now.Add(11 * time.Second)
+	if !b.allow() {
+		t.Fatal("a late failure pushed the probe past the cooldown")
+	}
+}
+
+func TestRetryPolicy_BackoffDoesNotOverflow(t *testing.T) {
+	p := newRetryPolicy(config.Retry{MaxAttempts: 1000, InitialBackoff: "1s", MaxBackoff: "30s"})
+	for _, n := range []int{0, 1, 5, 6, 40, 63, 64, 999} {
+		d := p.backoffAfter(n)
+		if d <= 0 || d > 30*time.Second {
+			t.Fatalf("attempt %d: backoff %v out of (0, 30s]", n, d)
+		}
+		if n >= 5 && d != 30*time.Second {
+			t.Fatalf("attempt %d: want the 30s cap, got %v", n, d)
+		}
+	}
+	if d := p.backoffAfter(2); d != 4*time.Second {
+		t.Fatalf("want 4s after two doublings, got %v", d)
+	}
+}
+
+func TestHTTPPoster_ProbeGivenBackWhenNotSent(t *testing.T) {
+	now := time.Unix(0, 0)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer srv.Close()
+	p := newTestPoster(srv.URL, config.Retry{MaxAttempts: 1, BreakerThreshold: 1, BreakerCooldown: "30s"})
+	p.breaker.now = func() time.Time { return now }
+	_ = p.PostHost(context.Background(), 0)
+	now = now.Add(31 * time.Second)
+
+	// neither a payload that can't be encoded nor a cancelled post may
+	// use up the probe and leave the breaker half-open
+	if err := p.post(context.Background(), srv.URL, func() {}, "bad payload"); err == nil {
+		t.Fatal("want an encoding error")
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := p.PostHost(ctx, 0); errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("probe not let through: %v", err)
+	}
+	if !p.breaker.allow() {
+		t.Fatal("breaker stuck half-open after a cancelled probe")
+	}
+}
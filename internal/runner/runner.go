@@ -4,7 +4,6 @@ import (
 	"bytes"
 	"context"
 	"crypto/tls"
-	"crypto/x509"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -14,23 +13,27 @@ import (
 	"os/signal"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"syscall"
 	"time"
 
 	"github.com/arenadata/ad-status-sender/internal/check"
 	"github.com/arenadata/ad-status-sender/internal/config"
+	"github.com/arenadata/ad-status-sender/internal/metrics"
 	"github.com/arenadata/ad-status-sender/internal/rules"
+	"github.com/arenadata/ad-status-sender/internal/spool"
+	"github.com/arenadata/ad-status-sender/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
 )
 
 const (
 	jobQueueSize       = 2048
+	maxPostsInFlight   = 64
 	httpMaxIdle        = 100
 	httpMaxIdlePerHost = 100
 	httpIdleTimeout    = 90 * time.Second
-
-	defaultInterval    = 5 * time.Second
-	defaultHTTPTimeout = 5 * time.Second
-	defaultForceSend   = 120 * time.Second
 )
 
 type httpPoster struct {
@@ -40,35 +43,19 @@ type httpPoster struct {
 	hostID    int
 	token     string
 	logBodies bool
+	retry     retryPolicy
+	breaker   *breaker
+	met       *runnerMetrics
+	tracer    trace.Tracer
+
+	batchPath        string
+	batchShape       string
+	batchUnsupported atomic.Bool
 }
 
 func (p *httpPoster) PostHost(ctx context.Context, status int) error {
 	url := fmt.Sprintf("%s/status/api/v1/host/%d/", strings.TrimRight(p.adcmURL, "/"), p.hostID)
-	payload := map[string]int{"status": status}
-	body, _ := json.Marshal(payload)
-
-	req, _ := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
-	req.Header.Set("Authorization", "Token "+p.token)
-	req.Header.Set("Content-Type", "application/json")
-
-	resp, err := p.c.Do(req)
-	if err != nil {
-		return err
-	}
-	defer resp.Body.Close()
-
-	if p.logBodies || resp.StatusCode >= http.StatusMultipleChoices {
-		data, _ := io.ReadAll(resp.Body)
-		p.log.InfoContext(
-			ctx,
-			"host post",
-			"url", url,
-			"code", resp.StatusCode,
-			"sent_status", status,
-			"body", strings.TrimSpace(string(data)),
-		)
-	}
-	return nil
+	return p.post(ctx, url, map[string]int{"status": status}, "host post", "sent_status", status)
 }
 
 func (p *httpPoster) PostComponent(ctx context.Context, compID string, status int) error {
@@ -78,59 +65,139 @@ func (p *httpPoster) PostComponent(ctx context.Context, compID string, status in
 		p.hostID,
 		compID,
 	)
-	payload := map[string]int{"status": status}
-	body, _ := json.Marshal(payload)
+	return p.post(ctx, url, map[string]int{"status": status}, "status post", "comp", compID, "sent_status", status)
+}
 
+// post sends payload to url through the circuit breaker, retrying
+// retryable failures according to the retry policy.
+func (p *httpPoster) post(ctx context.Context, url string, payload any, msg string, attrs ...any) (err error) {
+	ctx, span := p.startSpan(ctx, msg, trace.WithAttributes(spanAttrs(attrs)...))
+	defer func() { endSpan(span, err) }()
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	if !p.breaker.allow() {
+		p.met.posted(0, ErrCircuitOpen, 0)
+		return ErrCircuitOpen
+	}
+	attempt := 0
+	err = p.retry.do(ctx, func() error {
+		if attempt > 0 {
+			p.met.retried()
+		}
+		attempt++
+		return p.send(ctx, url, body, msg, attrs...)
+	})
+	if ctx.Err() != nil {
+		// shutting down; says nothing about ADCM health
+		p.breaker.abort()
+		return err
+	}
+	if st, changed := p.breaker.done(err); changed {
+		p.log.WarnContext(ctx, "adcm circuit breaker", "state", st.String(), "err", err)
+	}
+	return err
+}
+
+func (p *httpPoster) send(ctx context.Context, url string, body []byte, msg string, attrs ...any) (err error) {
+	ctx, span := p.startSpan(ctx, http.MethodPost, trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(
+		attribute.String("http.request.method", http.MethodPost),
+		attribute.String("url.full", url),
+	))
+	defer func() { endSpan(span, err) }()
 	req, _ := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
 	req.Header.Set("Authorization", "Token "+p.token)
 	req.Header.Set("Content-Type", "application/json")
+	tracing.Inject(ctx, propagation.HeaderCarrier(req.Header))
 
+	start := time.Now()
 	resp, err := p.c.Do(req)
 	if err != nil {
+		p.met.posted(0, err, time.Since(start))
 		return err
 	}
 	defer resp.Body.Close()
+	span.SetAttributes(attribute.Int("http.response.status_code", resp.StatusCode))
 
-	if p.logBodies || resp.StatusCode >= http.StatusMultipleChoices {
-		data, _ := io.ReadAll(resp.Body)
+	failed := resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices
+	var data []byte
+	if p.logBodies || failed {
+		data, _ = io.ReadAll(resp.Body)
 		p.log.InfoContext(
 			ctx,
-			"status post",
-			"url", url,
-			"comp", compID,
-			"code", resp.StatusCode,
-			"sent_status", status,
-			"body", strings.TrimSpace(string(data)),
+			msg,
+			append([]any{
+				"url", url,
+				"code", resp.StatusCode,
+				"body", strings.TrimSpace(string(data)),
+			}, attrs...)...,
 		)
 	}
-	return nil
+	if failed {
+		err = newHTTPError(resp, string(data))
+	}
+	p.met.posted(resp.StatusCode, err, time.Since(start))
+	return err
 }
 
 type Runner struct {
-	cfgPath string
-	log     *slog.Logger
+	loader config.Loader
+	log    *slog.Logger
 
 	mu     sync.RWMutex
 	cfg    config.Config
 	token  string
 	client *http.Client
-
-	ruleStore rules.Store
+	spool  *spool.Spool
+	batch  *batcher
+
+	// reloadMu runs one reload at a time: SIGHUP, the config watcher and
+	// the rules watcher, with everything they reconfigure.
+	reloadMu  sync.Mutex
+	loaded    rules.Store // as read from the files
+	ruleStore rules.Store // loaded, with component refs resolved
+	comps     componentIDs
 	stopWatch chan struct{}
+	workers   workerPool
 
 	tickerMu sync.Mutex
 	ticker   Ticker
 	jobs     chan func()
+	trigger  chan struct{}
 	cancel   context.CancelFunc
 
-	sd   check.Systemd
-	dck  check.Docker
-	post Poster
-	clk  Clock
+	sd    check.Systemd
+	dck   check.Docker
+	host  check.Host
+	exec  check.Exec
+	probe check.Prober
+	web   check.HTTP
+	procs check.Processes
+
+	execSem   chan struct{}
+	postSem   chan struct{}
+	replaying atomic.Bool
+	post      Poster
+	clk       Clock
 
 	cacheMu    sync.Mutex
 	cache      map[string]lastSend // key -> last
 	forceAfter time.Duration
+	hyst       hysteresis
+
+	units     unitCache
+	hostCheck hostState
+	execs     execRuns
+	restarts  restartTracker
+	maint     maintenanceState
+
+	checks  checkStore
+	metrics *metrics.Registry
+	met     *runnerMetrics
+	traces  tracing.Provider
+	tracer  trace.Tracer
+	server  *http.Server
 }
 
 type lastSend struct {
@@ -139,10 +206,15 @@ type lastSend struct {
 }
 
 func NewWithLogger(cfgPath string, logger *slog.Logger) *Runner {
-	if logger == nil {
-		logger = slog.Default()
-	}
-	return NewWithDeps(cfgPath, logger, nil, nil, nil, nil)
+	return NewWithLoader(config.Loader{Path: cfgPath}, logger)
+}
+
+// NewWithLoader is like NewWithLogger, with environment and flag overrides
+// applied on top of the config file.
+func NewWithLoader(loader config.Loader, logger *slog.Logger) *Runner {
+	r := NewWithDeps(loader.Path, logger, nil, nil, nil, nil)
+	r.loader = loader
+	return r
 }
 
 func New(cfgPath string) *Runner { return NewWithLogger(cfgPath, slog.Default()) }
@@ -161,22 +233,31 @@ func NewWithDeps(
 	if clk == nil {
 		clk = realClock{}
 	}
-	return &Runner{
-		cfgPath: cfgPath,
+	r := &Runner{
+		loader:  config.Loader{Path: cfgPath},
 		log:     logger,
 		sd:      sd,
 		dck:     dck,
 		post:    post,
 		clk:     clk,
+		postSem: make(chan struct{}, maxPostsInFlight),
+		metrics: metrics.NewRegistry(),
+		traces:  tracing.Noop(),
 	}
+	r.tracer = r.traces.Tracer(tracing.Name)
+	r.registerMetrics()
+	return r
 }
 
 func (r *Runner) Start() error {
 	if err := r.reload(); err != nil {
 		return err
 	}
-	if err := r.loadRulesOnce(); err != nil {
-		r.log.Warn("rules initial load", "err", err)
+	r.discoverComponents(context.Background())
+	_ = r.loadRulesOnce() // logged; checks start once the rules load
+
+	if err := r.startTracing(r.cfg); err != nil {
+		return err
 	}
 
 	ctx, cancel := context.WithCancel(context.Background())
@@ -184,10 +265,20 @@ func (r *Runner) Start() error {
 	r.initRuntime()
 
 	r.startWorkers(ctx)
+	r.startSystemdEvents(ctx, r.cfg)
+	r.startDockerEvents(ctx, r.cfg)
 	r.startTickerLoop(ctx)
 	r.startRulesWatcher()
+	r.startComponentDiscovery(ctx, r.cfg)
+	if r.cfg.WatchConfig {
+		r.startConfigWatcher(ctx)
+	}
 	r.startSignalHandler()
 
+	if err := r.startServer(r.cfg.Server); err != nil {
+		r.Stop()
+		return err
+	}
 	return nil
 }
 
@@ -195,50 +286,26 @@ func (r *Runner) Stop() {
 	if r.cancel != nil {
 		r.cancel()
 	}
+	r.reloadMu.Lock()
+	r.stopRulesWatcher()
+	r.reloadMu.Unlock()
+	r.stopServer()
+	r.stopTracing()
 }
 
 func (r *Runner) initRuntime() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
 	r.jobs = make(chan func(), jobQueueSize)
+	r.trigger = make(chan struct{}, 1)
 	r.cache = make(map[string]lastSend)
 }
 
-func (r *Runner) startWorkers(ctx context.Context) {
-	n := r.cfg.Concurrency
-	for range n {
-		go func() {
-			for {
-				select {
-				case <-ctx.Done():
-					return
-				case fn, ok := <-r.jobs:
-					if !ok {
-						return
-					}
-					fn()
-				}
-			}
-		}()
-	}
-}
-
 func (r *Runner) startTickerLoop(ctx context.Context) {
-	r.resetTicker(config.MustDuration(r.cfg.Interval, defaultInterval))
+	r.resetTicker(config.MustDuration(r.cfg.Interval, config.DefaultInterval))
 	go r.loop(ctx)
 }
 
-func (r *Runner) startRulesWatcher() {
-	r.stopWatch = make(chan struct{})
-	go func() {
-		err := rules.Watch(r.stopWatch, r.cfg.RulesPath, func(rr rules.Rules) {
-			r.ruleStore.Set(rr)
-			r.log.Info("rules reloaded", "systemd", len(rr.Systemd), "docker", len(rr.Docker))
-		})
-		if err != nil {
-			r.log.Error("rules watch", "err", err)
-		}
-	}()
-}
-
 func (r *Runner) startSignalHandler() {
 	go func() {
 		const sigBuf = 2
@@ -247,15 +314,9 @@ func (r *Runner) startSignalHandler() {
 		for s := range sigCh {
 			switch s {
 			case syscall.SIGHUP:
-				if err := r.reload(); err != nil {
-					r.log.Error("reload config", "err", err)
-				}
-				if err := r.loadRulesOnce(); err != nil {
-					r.log.Error("reload rules", "err", err)
-				}
+				r.reloadAll()
 			default:
 				r.Stop()
-				close(r.stopWatch)
 				return
 			}
 		}
@@ -263,7 +324,7 @@ func (r *Runner) startSignalHandler() {
 }
 
 func (r *Runner) reload() error {
-	c, loadErr := config.Load(r.cfgPath)
+	c, _, loadErr := r.loader.Load()
 	if loadErr != nil {
 		return loadErr
 	}
@@ -272,6 +333,61 @@ func (r *Runner) reload() error {
 		return tokenErr
 	}
 
+	r.initChecks()
+
+	r.openSpool(c)
+	httpc := makeHTTPClient(c)
+
+	shape, shapeErr := batchShape(c.Batch.Shape)
+	if shapeErr != nil {
+		return shapeErr
+	}
+	batchPath := ""
+	if c.Batch.Enabled {
+		batchPath = c.Batch.Path
+		if batchPath == "" {
+			batchPath = config.DefaultBatchPath
+		}
+	}
+
+	r.swapPoster(&httpPoster{
+		log:        r.log,
+		c:          httpc,
+		adcmURL:    c.ADCMURL,
+		hostID:     c.HostID,
+		token:      tok,
+		logBodies:  c.LogBodies,
+		retry:      newRetryPolicy(c.Retry),
+		breaker:    newBreaker(c.Retry),
+		met:        r.met,
+		tracer:     r.tracer,
+		batchPath:  batchPath,
+		batchShape: shape,
+	}, c.Retry)
+
+	var bat *batcher
+	if c.Batch.Enabled {
+		bat = newBatcher(c.Batch, r.flushBatch)
+	}
+
+	r.mu.Lock()
+	prev := r.cfg
+	r.cfg = c
+	r.batch = bat
+	r.token = tok
+	r.client = httpc
+	r.forceAfter = config.MustDuration(c.ForceSendAfter, config.DefaultForceSendAfter)
+	r.mu.Unlock()
+
+	r.resetTicker(config.MustDuration(c.Interval, config.DefaultInterval))
+	if r.running() {
+		r.reconfigure(prev, c)
+	}
+	return nil
+}
+
+// initChecks creates the check backends that were not injected.
+func (r *Runner) initChecks() {
 	if r.dck == nil {
 		if d, err := check.NewDockerChecker(); err == nil {
 			r.dck = d
@@ -287,39 +403,26 @@ func (r *Runner) reload() error {
 		}
 	}
 
-	httpc := makeHTTPClient(c)
-
-	if r.post == nil {
-		r.post = &httpPoster{
-			log:       r.log,
-			c:         httpc,
-			adcmURL:   c.ADCMURL,
-			hostID:    c.HostID,
-			token:     tok,
-			logBodies: c.LogBodies,
-		}
-	} else if hp, ok := r.post.(*httpPoster); ok {
-		hp.c = httpc
-		hp.adcmURL = c.ADCMURL
-		hp.hostID = c.HostID
-		hp.token = tok
-		hp.logBodies = c.LogBodies
+	if r.host == nil {
+		r.host = check.NewHostChecker()
+	}
+	if r.exec == nil {
+		r.exec = check.ExecRunner{}
+	}
+	if r.probe == nil {
+		r.probe = check.TCPProber{}
+	}
+	if r.web == nil {
+		r.web = check.HTTPChecker{}
+	}
+	if r.procs == nil {
+		r.procs = check.NewProcessScanner()
 	}
-
-	r.mu.Lock()
-	r.cfg = c
-	r.token = tok
-	r.client = httpc
-	r.forceAfter = config.MustDuration(c.ForceSendAfter, defaultForceSend)
-	r.mu.Unlock()
-
-	r.resetTicker(config.MustDuration(c.Interval, defaultInterval))
-	return nil
 }
 
 func makeHTTPClient(c config.Config) *http.Client {
 	tr := buildTransport(c)
-	httpTimeout := config.MustDuration(c.HTTPTimeout, defaultHTTPTimeout)
+	httpTimeout := config.MustDuration(c.HTTPTimeout, config.DefaultHTTPTimeout)
 	return &http.Client{Timeout: httpTimeout, Transport: tr}
 }
 
@@ -337,39 +440,7 @@ func buildTransport(c config.Config) *http.Transport {
 }
 
 func buildTLSConfig(c config.Config) *tls.Config {
-	tlsConf := &tls.Config{MinVersion: tls.VersionTLS12}
-	roots, sysErr := x509.SystemCertPool()
-	if sysErr != nil || roots == nil {
-		roots = x509.NewCertPool()
-	}
-	if strings.TrimSpace(c.TLS.CAFile) != "" {
-		if pem, rdErr := os.ReadFile(c.TLS.CAFile); rdErr == nil {
-			_ = roots.AppendCertsFromPEM(pem)
-		}
-	}
-	tlsConf.RootCAs = roots
-
-	if c.TLS.CertFile != "" && c.TLS.KeyFile != "" {
-		if cert, ckErr := tls.LoadX509KeyPair(c.TLS.CertFile, c.TLS.KeyFile); ckErr == nil {
-			tlsConf.Certificates = []tls.Certificate{cert}
-		}
-	}
-	if c.TLS.ServerName != "" {
-		tlsConf.ServerName = c.TLS.ServerName
-	}
-	if c.TLS.InsecureSkipVerify {
-		tlsConf.InsecureSkipVerify = true
-	}
-	return tlsConf
-}
-
-func (r *Runner) loadRulesOnce() error {
-	rr, err := rules.Load(r.cfg.RulesPath)
-	if err != nil {
-		return err
-	}
-	r.ruleStore.Set(rr)
-	return nil
+	return c.TLS.ClientConfig()
 }
 
 func (r *Runner) resetTicker(d time.Duration) {
@@ -396,16 +467,60 @@ func (r *Runner) loop(ctx context.Context) {
 			return
 		case <-c:
 			r.scanOnce(ctx)
+		case <-r.trigger:
+			r.scanOnce(ctx)
 		}
 	}
 }
 
 func (r *Runner) scanOnce(ctx context.Context) {
-	cfg, token, force := r.snapshot()
-	_ = token
-	r.scanSystemd(ctx, cfg, force)
-	r.scanDocker(ctx, cfg, force)
-	r.sendHeartbeat(ctx, cfg, force)
+	cfg, _, force := r.snapshot()
+	rr := r.ruleStore.Get()
+	ctx, span := r.tracer.Start(ctx, "scan", trace.WithAttributes(attribute.Int("adcm.host_id", cfg.HostID)))
+	defer span.End()
+	r.startReplay(ctx, cfg)
+
+	cyc := r.startChecks(ctx, cfg, rr)
+	r.sendHeartbeat(ctx, cfg, force, rr.Host)
+	statuses, ok := r.finish(ctx, cfg, rr, cyc)
+	if !ok {
+		return
+	}
+	span.SetAttributes(attribute.Int("scan.components", len(statuses)))
+	r.checks.record(cyc.list(), statuses, r.clk.Now())
+	r.writeTextfile(cfg.Metrics)
+	for _, comp := range sortedKeys(statuses) {
+		r.maybePostComponent(ctx, cfg, comp, statuses[comp], force)
+	}
+}
+
+// startChecks starts every check of rr on the worker pool.
+func (r *Runner) startChecks(ctx context.Context, cfg config.Config, rr rules.Rules) *cycle {
+	reconcileEvery := config.MustDuration(cfg.Events.ReconcileInterval, config.DefaultReconcileInterval)
+	reconcile := r.units.reconcileDue(r.clk.Now(), reconcileEvery)
+
+	cyc := &cycle{}
+	r.scanSystemd(ctx, cyc, rr, reconcile)
+	r.scanDocker(ctx, cyc, rr)
+	r.scanExec(ctx, cyc, rr)
+	r.scanTCP(ctx, cyc, rr)
+	r.scanHTTP(ctx, cyc, rr)
+	r.scanProcess(ctx, cyc, rr)
+	return cyc
+}
+
+// finish waits for the checks of cyc and returns the status to report per
+// component: debounced, aggregated and with maintenance applied.
+func (r *Runner) finish(ctx context.Context, cfg config.Config, rr rules.Rules, cyc *cycle) (map[string]int, bool) {
+	if !cyc.wait(ctx) {
+		return nil, false
+	}
+	for _, res := range cyc.debounce(&r.hyst, r.clk.Now()) {
+		r.log.DebugContext(ctx, "status change held", "kind", res.kind, "target", res.target, "status", res.status)
+	}
+	statuses := cyc.reduce(rr.Aggregation, ruleComponents(rr))
+	r.applyMaintenance(ctx, cfg, rr, statuses)
+	return statuses, true
 }
 
 func (r *Runner) snapshot() (config.Config, string, time.Duration) {
@@ -414,57 +529,99 @@ func (r *Runner) snapshot() (config.Config, string, time.Duration) {
 	return r.cfg, r.token, r.forceAfter
 }
 
-func (r *Runner) scanSystemd(ctx context.Context, cfg config.Config, forceAfter time.Duration) {
-	rr := r.ruleStore.Get()
-	for _, rule := range rr.Systemd {
+// check runs fn on the worker pool and records its result in cyc.
+func (r *Runner) check(ctx context.Context, cyc *cycle, fn func() result) {
+	cyc.begin()
+	r.enqueue(func() {
+		defer cyc.end()
+		cyc.add(r.measure(ctx, fn))
+	})
+}
+
+// measure runs one check in a span of the cycle and records its metrics.
+func (r *Runner) measure(ctx context.Context, fn func() result) result {
+	_, span := r.tracer.Start(ctx, "check")
+	defer span.End()
+	start := time.Now()
+	res := fn()
+	r.met.checked(res.kind, res.status, time.Since(start))
+	span.SetName("check " + res.kind)
+	span.SetAttributes(
+		attribute.String("check.kind", res.kind),
+		attribute.String("check.target", res.target),
+		attribute.Int("check.status", res.status),
+		attribute.StringSlice("adcm.component_ids", res.comps),
+	)
+	res.raw = res.status
+	return res
+}
+
+func (r *Runner) scanSystemd(ctx context.Context, cyc *cycle, rr rules.Rules, reconcile bool) {
+	for i, rule := range rr.Systemd {
 		comps := append([]string(nil), rule.Components...)
 		var units []string
 		if rule.Unit != "" {
 			units = append(units, rule.Unit)
 		}
 		if rule.UnitGlob != "" && r.sd != nil {
-			units = append(units, r.sd.ExpandUnitsByGlob(ctx, rule.UnitGlob)...)
+			units = append(units, r.expandGlob(ctx, rule.UnitGlob, reconcile)...)
+		}
+		lim := rule.RestartLimit
+		name := rule.UnitGlob
+		if rule.Unit != "" {
+			name = rule.Unit
 		}
 		for _, unit := range units {
-			unitLocal := unit
-			r.enqueue(func() {
+			r.check(ctx, cyc, func() result {
 				st := 1
 				if r.sd != nil {
-					st = r.sd.SystemdStatus(ctx, unitLocal)
+					st = r.unitStatus(ctx, unit, reconcile)
+					if lim != nil {
+						st = r.restartStatus(ctx, "systemd", lim, st, r.systemdRestarts(ctx, unit, reconcile))
+					}
 				}
-				for _, comp := range comps {
-					r.maybePostComponent(ctx, cfg, comp, st, forceAfter)
+				return result{
+					kind: "systemd", rule: name, index: i, target: unit, status: st, comps: comps, deb: rule.Debounce,
 				}
 			})
 		}
 	}
 }
 
-func (r *Runner) scanDocker(ctx context.Context, cfg config.Config, forceAfter time.Duration) {
-	rr := r.ruleStore.Get()
-	for _, d := range rr.Docker {
+func (r *Runner) scanDocker(ctx context.Context, cyc *cycle, rr rules.Rules) {
+	for i, d := range rr.Docker {
 		comps := append([]string(nil), d.Components...)
 		sel := d.Containers
-		r.enqueue(func() {
+		hp := check.HealthPolicy{
+			RequireHealthy:   d.RequireHealthy,
+			AllowStartingFor: config.MustDuration(d.AllowStartingFor, 0),
+		}
+		lim := d.RestartLimit
+		r.check(ctx, cyc, func() result {
 			status := 1
 			if r.dck != nil {
 				if len(sel.Names) > 0 {
-					status = r.dck.AllRunningNames(context.Background(), sel.Names)
+					status = r.dck.AllRunningNames(context.Background(), sel.Names, hp)
 				} else {
-					status = r.dck.AllRunningByLabels(context.Background(), sel.Labels)
+					status = r.dck.AllRunningByLabels(context.Background(), sel.Labels, hp)
+				}
+				if lim != nil {
+					status = r.restartStatus(ctx, "docker", lim, status, r.containerRestarts(ctx, sel))
 				}
 			}
-			for _, comp := range comps {
-				r.maybePostComponent(ctx, cfg, comp, status, forceAfter)
-			}
+			return result{kind: "docker", index: i, target: d.Name, status: status, comps: comps, deb: d.Debounce}
 		})
 	}
 }
 
-func (r *Runner) sendHeartbeat(ctx context.Context, cfg config.Config, forceAfter time.Duration) {
+func (r *Runner) sendHeartbeat(
+	ctx context.Context,
+	cfg config.Config,
+	forceAfter time.Duration,
+	hr *rules.RuleHost,
+) {
 	r.enqueue(func() {
-		const ok = 0
-		r.maybePostHost(ctx, cfg, ok, forceAfter)
+		r.maybePostHost(ctx, cfg, r.hostStatus(ctx, hr), forceAfter)
 	})
 }
 
@@ -472,10 +629,23 @@ func (r *Runner) enqueue(fn func()) {
 	select {
 	case r.jobs <- fn:
 	default:
+		r.met.overflowed()
 		go fn()
 	}
 }
 
+// send runs fn, which posts, on a goroutine of its own, so that retry
+// backoff never holds a check worker. At most maxPostsInFlight run at once.
+// Whether to post is decided before, in scan order: a decision made late
+// against a newer send cache could post a status that is already stale.
+func (r *Runner) send(fn func()) {
+	go func() {
+		r.postSem <- struct{}{}
+		defer func() { <-r.postSem }()
+		fn()
+	}()
+}
+
 func (r *Runner) maybePostComponent(
 	ctx context.Context,
 	cfg config.Config,
@@ -487,13 +657,7 @@ func (r *Runner) maybePostComponent(
 	if !r.shouldSend(key, status, forceAfter) {
 		return
 	}
-	if r.post != nil {
-		if err := r.post.PostComponent(ctx, compID, status); err != nil {
-			r.log.WarnContext(ctx, "post component failed", "comp", compID, "err", err)
-			return
-		}
-	}
-	r.markSent(key, status)
+	r.send(func() { r.deliver(ctx, cfg, key, Update{CompID: compID, Status: status}) })
 }
 
 func (r *Runner) maybePostHost(ctx context.Context, cfg config.Config, status int, forceAfter time.Duration) {
@@ -501,13 +665,44 @@ func (r *Runner) maybePostHost(ctx context.Context, cfg config.Config, status in
 	if !r.shouldSend(key, status, forceAfter) {
 		return
 	}
-	if r.post != nil {
-		if err := r.post.PostHost(ctx, status); err != nil {
+	r.send(func() { r.deliver(ctx, cfg, key, Update{IsHost: true, Status: status}) })
+}
+
+// deliver posts upd right away or queues it for the next batch.
+func (r *Runner) deliver(ctx context.Context, cfg config.Config, key string, upd Update) {
+	if r.post == nil {
+		r.markSent(key, upd.Status)
+		return
+	}
+	if r.spoolHolds(ctx, cfg, key, upd) {
+		return
+	}
+	if bat := r.getBatcher(); bat != nil {
+		bat.add(ctx, key, upd)
+		return
+	}
+	var err error
+	if upd.IsHost {
+		err = r.post.PostHost(ctx, upd.Status)
+	} else {
+		err = r.post.PostComponent(ctx, upd.CompID, upd.Status)
+	}
+	r.delivered(ctx, cfg, key, upd, err)
+}
+
+// delivered records the outcome of posting upd.
+func (r *Runner) delivered(ctx context.Context, cfg config.Config, key string, upd Update, err error) {
+	if err != nil {
+		if upd.IsHost {
 			r.log.WarnContext(ctx, "post host failed", "host", cfg.HostID, "err", err)
-			return
+		} else {
+			r.log.WarnContext(ctx, "post component failed", "comp", upd.CompID, "err", err)
 		}
+		r.spoolFailed(cfg, spool.Entry{Key: key, IsHost: upd.IsHost, CompID: upd.CompID, Status: upd.Status})
+		return
 	}
-	r.markSent(key, status)
+	r.spoolDelivered(key)
+	r.markSent(key, upd.Status)
 }
 
 func (r *Runner) shouldSend(key string, status int, forceAfter time.Duration) bool {
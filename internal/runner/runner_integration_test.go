@@ -6,16 +6,30 @@ import (
 	"testing"
 	"time"
 
+	"github.com/arenadata/ad-status-sender/internal/check"
 	"github.com/arenadata/ad-status-sender/internal/check/checktest"
 	"github.com/arenadata/ad-status-sender/internal/config"
 	"github.com/arenadata/ad-status-sender/internal/rules"
 )
 
-type testClock struct{ now time.Time }
+type testClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *testClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
 
-func (c *testClock) Now() time.Time                   { return c.now }
 func (c *testClock) NewTicker(_ time.Duration) Ticker { return nil }
-func (c *testClock) advance(d time.Duration)          { c.now = c.now.Add(d) }
+
+func (c *testClock) advance(d time.Duration) {
+	c.mu.Lock()
+	c.now = c.now.Add(d)
+	c.mu.Unlock()
+}
 
 type sentEvent struct {
 	IsHost bool
@@ -39,6 +53,16 @@ func (p *testPoster) PostComponent(_ context.Context, compID string, status int)
 	p.mu.Unlock()
 	return nil
 }
+func (p *testPoster) PostBatch(ctx context.Context, updates []Update) error {
+	for _, u := range updates {
+		if u.IsHost {
+			_ = p.PostHost(ctx, u.Status)
+		} else {
+			_ = p.PostComponent(ctx, u.CompID, u.Status)
+		}
+	}
+	return nil
+}
 func (p *testPoster) Count() int {
 	p.mu.Lock()
 	defer p.mu.Unlock()
@@ -50,6 +74,30 @@ func (p *testPoster) Reset() {
 	p.mu.Unlock()
 }
 
+// newTestRunner returns a runner set up with cfg as Start would leave it,
+// except that its job queue is full and never drained, so every post runs
+// on a goroutine of its own.
+func newTestRunner(
+	t *testing.T,
+	cfg config.Config,
+	sd check.Systemd,
+	dck check.Docker,
+	post Poster,
+	clk Clock,
+) *Runner {
+	t.Helper()
+	r := NewWithDeps("unused.yaml", nil, sd, dck, post, clk)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.cfg = cfg
+	r.forceAfter = config.MustDuration(cfg.ForceSendAfter, time.Hour)
+	r.cache = make(map[string]lastSend)
+	r.jobs = make(chan func(), 1)
+	r.jobs <- func() {}
+	r.trigger = make(chan struct{}, 1)
+	return r
+}
+
 func waitUntil(t *testing.T, cond func() bool, timeout time.Duration) {
 	t.Helper()
 	deadline := time.Now().Add(timeout)
@@ -81,20 +129,13 @@ func TestRunner_SendsStatuses_WithCacheAndForceResend(t *testing.T) {
 	post := &testPoster{}
 	clk := &testClock{now: time.Unix(0, 0)}
 
-	r := NewWithDeps("unused.yaml", nil, sd, dck, post, clk)
-
-	r.mu.Lock()
-	r.cfg = config.Config{
+	cfg := config.Config{
 		ADCMURL:        "http://example",
 		HostID:         7,
 		ForceSendAfter: "120s",
 		Concurrency:    0,
 	}
-	r.forceAfter = 120 * time.Second
-	r.cache = make(map[string]lastSend)
-	r.jobs = make(chan func(), 1)
-	r.jobs <- func() {}
-	r.mu.Unlock()
+	r := newTestRunner(t, cfg, sd, dck, post, clk)
 
 	r.ruleStore.Set(rules.Rules{
 		Systemd: []rules.RuleSystemd{
@@ -148,14 +189,8 @@ func TestRunner_StatusChangeTriggersSend(t *testing.T) {
 	post := &testPoster{}
 	clk := &testClock{now: time.Unix(0, 0)}
 
-	r := NewWithDeps("unused.yaml", nil, sd, dck, post, clk)
-	r.mu.Lock()
-	r.cfg = config.Config{ADCMURL: "http://example", HostID: 7, ForceSendAfter: "120s", Concurrency: 0}
-	r.forceAfter = 120 * time.Second
-	r.cache = make(map[string]lastSend)
-	r.jobs = make(chan func(), 1)
-	r.jobs <- func() {}
-	r.mu.Unlock()
+	cfg := config.Config{ADCMURL: "http://example", HostID: 7, ForceSendAfter: "120s", Concurrency: 0}
+	r := newTestRunner(t, cfg, sd, dck, post, clk)
 
 	r.ruleStore.Set(rules.Rules{
 		Systemd: []rules.RuleSystemd{
@@ -168,7 +203,7 @@ func TestRunner_StatusChangeTriggersSend(t *testing.T) {
 	waitUntil(t, func() bool { return post.Count() == 2 }, 300*time.Millisecond)
 
 	post.Reset()
-	sd.Units["nginx.service"] = false
+	sd.Set("nginx.service", false)
 	r.scanOnce(ctx)
 	waitUntil(t, func() bool { return post.Count() == 1 }, 300*time.Millisecond)
 
@@ -186,14 +221,8 @@ func TestRunner_DockerLabelsOnly(t *testing.T) {
 	post := &testPoster{}
 	clk := &testClock{now: time.Unix(0, 0)}
 
-	r := NewWithDeps("unused.yaml", nil, sd, dck, post, clk)
-	r.mu.Lock()
-	r.cfg = config.Config{ADCMURL: "http://example", HostID: 7, ForceSendAfter: "120s", Concurrency: 0}
-	r.forceAfter = 120 * time.Second
-	r.cache = make(map[string]lastSend)
-	r.jobs = make(chan func(), 1)
-	r.jobs <- func() {}
-	r.mu.Unlock()
+	cfg := config.Config{ADCMURL: "http://example", HostID: 7, ForceSendAfter: "120s", Concurrency: 0}
+	r := newTestRunner(t, cfg, sd, dck, post, clk)
 
 	r.ruleStore.Set(rules.Rules{
 		Docker: []rules.RuleDocker{
@@ -235,14 +264,8 @@ func TestRunner_RulesReloadAddsComponent(t *testing.T) {
 	post := &testPoster{}
 	clk := &testClock{now: time.Unix(0, 0)}
 
-	r := NewWithDeps("unused.yaml", nil, sd, dck, post, clk)
-	r.mu.Lock()
-	r.cfg = config.Config{ADCMURL: "http://example", HostID: 7, ForceSendAfter: "120s", Concurrency: 0}
-	r.forceAfter = 120 * time.Second
-	r.cache = make(map[string]lastSend)
-	r.jobs = make(chan func(), 1)
-	r.jobs <- func() {}
-	r.mu.Unlock()
+	cfg := config.Config{ADCMURL: "http://example", HostID: 7, ForceSendAfter: "120s", Concurrency: 0}
+	r := newTestRunner(t, cfg, sd, dck, post, clk)
 
 	r.ruleStore.Set(rules.Rules{
 		Systemd: []rules.RuleSystemd{
@@ -270,3 +293,52 @@ func TestRunner_RulesReloadAddsComponent(t *testing.T) {
 		t.Fatalf("want one comp event 502=1 after rules update, got: %+v", ss)
 	}
 }
+
+func TestRunner_AggregatesGlobInstancesPerComponent(t *testing.T) {
+	sd := &checktest.FakeSystemd{
+		Units: map[string]bool{
+			"app@1.service": true,
+			"app@2.service": false,
+			"web.service":   true,
+		},
+		Globs: map[string][]string{
+			"app@*.service": {"app@1.service", "app@2.service"},
+		},
+	}
+	dck := &checktest.FakeDocker{Names: map[string]bool{"db": true}}
+	post := &testPoster{}
+	clk := &testClock{now: time.Unix(0, 0)}
+
+	cfg := config.Config{ADCMURL: "http://example", HostID: 7, ForceSendAfter: "120s", Concurrency: 0}
+	r := newTestRunner(t, cfg, sd, dck, post, clk)
+
+	r.ruleStore.Set(rules.Rules{
+		Systemd: []rules.RuleSystemd{
+			{UnitGlob: "app@*.service", Components: []string{"501", "502"}},
+			{Unit: "web.service", Components: []string{"503"}},
+		},
+		Docker: []rules.RuleDocker{
+			{Name: "db", Components: []string{"503"}, Containers: rules.DockerSelector{Names: []string{"db"}}},
+		},
+		Aggregation: rules.Aggregation{
+			Components: map[string]rules.Policy{"502": {Mode: rules.PolicyAnyOK}},
+		},
+	})
+
+	r.scanOnce(context.Background())
+	waitUntil(t, func() bool { return post.Count() == 4 }, 500*time.Millisecond)
+	time.Sleep(20 * time.Millisecond)
+
+	got := map[string][]int{}
+	for _, e := range post.Snapshot() {
+		if !e.IsHost {
+			got[e.CompID] = append(got[e.CompID], e.Status)
+		}
+	}
+	want := map[string]int{"501": 1, "502": 0, "503": 0}
+	for comp, st := range want {
+		if len(got[comp]) != 1 || got[comp][0] != st {
+			t.Fatalf("comp %s: want exactly one post with %d, got %v", comp, st, got[comp])
+		}
+	}
+}
@@ -1,6 +1,7 @@
 package runnertest
 
 import (
+	"sync"
 	"time"
 
 	"github.com/arenadata/ad-status-sender/internal/runner"
@@ -9,6 +10,7 @@ import (
 const tickerBuf = 16
 
 type FakeClock struct {
+	mu  sync.Mutex
 	now time.Time
 	ch  chan time.Time
 }
@@ -17,11 +19,19 @@ func NewFakeClock(start time.Time) *FakeClock {
 	return &FakeClock{now: start, ch: make(chan time.Time, tickerBuf)}
 }
 
-func (c *FakeClock) Now() time.Time { return c.now }
+func (c *FakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
 
-func (c *FakeClock) Advance(d time.Duration) { c.now = c.now.Add(d) }
+func (c *FakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
 
-func (c *FakeClock) Tick() { c.ch <- c.now }
+func (c *FakeClock) Tick() { c.ch <- c.Now() }
 
 type fakeTicker struct{ ch <-chan time.Time }
 
@@ -3,6 +3,8 @@ package runnertest
 import (
 	"context"
 	"sync"
+
+	"github.com/arenadata/ad-status-sender/internal/runner"
 )
 
 type Sent struct {
@@ -10,11 +12,14 @@ type Sent struct {
 	CompID string
 	Status int
 	IsHost bool
+	Batch  bool
 }
 
 type FakePoster struct {
 	mu   sync.Mutex
 	Sent []Sent
+	// FailComp makes posts for these component IDs fail after recording them.
+	FailComp map[string]error
 }
 
 func (f *FakePoster) PostHost(_ context.Context, status int) error {
@@ -27,5 +32,21 @@ func (f *FakePoster) PostComponent(_ context.Context, compID string, status int)
 	f.mu.Lock()
 	defer f.mu.Unlock()
 	f.Sent = append(f.Sent, Sent{IsHost: false, CompID: compID, Status: status})
+	return f.FailComp[compID]
+}
+
+func (f *FakePoster) PostBatch(_ context.Context, updates []runner.Update) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, u := range updates {
+		f.Sent = append(f.Sent, Sent{IsHost: u.IsHost, CompID: u.CompID, Status: u.Status, Batch: true})
+	}
 	return nil
 }
+
+// Snapshot returns a copy of everything sent so far.
+func (f *FakePoster) Snapshot() []Sent {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]Sent(nil), f.Sent...)
+}
@@ -0,0 +1,88 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/arenadata/ad-status-sender/internal/config"
+)
+
+const (
+	serverHeaderTimeout = 5 * time.Second
+	serverStopTimeout   = 5 * time.Second
+	socketProbeTimeout  = time.Second
+	unixPrefix          = "unix:"
+)
+
+// startServer starts the status endpoint when c.Listen is set.
+func (r *Runner) startServer(c config.Server) error {
+	if c.Listen == "" {
+		return nil
+	}
+	ln, err := listen(c.Listen)
+	if err != nil {
+		return fmt.Errorf("status listener: %w", err)
+	}
+	srv := &http.Server{Handler: r.handler(), ReadHeaderTimeout: serverHeaderTimeout}
+	r.mu.Lock()
+	r.server = srv
+	r.mu.Unlock()
+	r.log.Info("status listener started", "listen", c.Listen)
+	go func() {
+		if err := srv.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			r.log.Error("status listener", "err", err)
+		}
+	}()
+	return nil
+}
+
+// stopServer lets in-flight requests finish before closing the listener.
+func (r *Runner) stopServer() {
+	r.mu.Lock()
+	srv := r.server
+	r.server = nil
+	r.mu.Unlock()
+	if srv == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), serverStopTimeout)
+	defer cancel()
+	if err := srv.Shutdown(ctx); err != nil {
+		r.log.Warn("status listener shutdown", "err", err)
+	}
+}
+
+// writeTextfile dumps the metrics for the node_exporter textfile collector.
+func (r *Runner) writeTextfile(c config.Metrics) {
+	if c.Textfile == "" {
+		return
+	}
+	if err := r.metrics.WriteFile(c.Textfile); err != nil {
+		r.log.Warn("metrics textfile", "path", c.Textfile, "err", err)
+	}
+}
+
+// listen opens addr, which is "host:port" or "unix:/path". A socket file
+// left over from a previous run is removed first; one that still accepts
+// connections belongs to a running process and is left alone.
+func listen(addr string) (net.Listener, error) {
+	path, ok := strings.CutPrefix(addr, unixPrefix)
+	if !ok {
+		return net.Listen("tcp", addr)
+	}
+	if fi, err := os.Lstat(path); err == nil && fi.Mode()&os.ModeSocket != 0 {
+		conn, dialErr := net.DialTimeout("unix", path, socketProbeTimeout)
+		if dialErr == nil {
+			_ = conn.Close()
+			return nil, fmt.Errorf("socket %s is in use by another process", path)
+		}
+		_ = os.Remove(path)
+	}
+	return net.Listen("unix", path)
+}
@@ -0,0 +1,166 @@
+package runner
+
+import (
+	"context"
+
+	"github.com/arenadata/ad-status-sender/internal/config"
+	"github.com/arenadata/ad-status-sender/internal/spool"
+)
+
+// openSpool (re)opens the on-disk spool when its directory changes and
+// applies changed limits to the open one.
+func (r *Runner) openSpool(c config.Config) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	maxEntries := c.Spool.MaxEntries
+	if maxEntries <= 0 {
+		maxEntries = config.DefaultSpoolEntries
+	}
+	maxAge := config.MustDuration(c.Spool.MaxAge, config.DefaultSpoolAge)
+	if c.Spool.Dir == r.cfg.Spool.Dir && r.spool != nil {
+		if c.Spool == r.cfg.Spool {
+			return
+		}
+		if err := r.spool.SetLimits(maxEntries, maxAge); err != nil {
+			r.log.Warn("spool write failed", "err", err)
+		}
+		r.log.Info("spool limits changed", "max_entries", maxEntries, "max_age", maxAge, "pending", r.spool.Len())
+		return
+	}
+	r.spool = nil
+	if c.Spool.Dir == "" {
+		return
+	}
+	sp, err := spool.Open(c.Spool.Dir, maxEntries, maxAge)
+	if err != nil {
+		r.log.Error("spool open failed, undelivered statuses will be dropped", "dir", c.Spool.Dir, "err", err)
+		return
+	}
+	if q := sp.Quarantined(); q != "" {
+		r.log.Warn("spool file unreadable, moved aside and starting empty", "dir", c.Spool.Dir, "file", q)
+	}
+	r.spool = sp
+	r.log.Info("spool opened", "dir", c.Spool.Dir, "pending", sp.Len())
+}
+
+func (r *Runner) getSpool() *spool.Spool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.spool
+}
+
+// spoolFailed remembers a status that could not be delivered.
+func (r *Runner) spoolFailed(cfg config.Config, e spool.Entry) {
+	sp := r.getSpool()
+	if sp == nil {
+		return
+	}
+	e.HostID = cfg.HostID
+	e.Time = r.clk.Now()
+	if err := sp.Put(e); err != nil {
+		r.log.Warn("spool write failed", "key", e.Key, "err", err)
+	}
+}
+
+// spoolDelivered drops a spooled status superseded by a successful post.
+func (r *Runner) spoolDelivered(key string) {
+	sp := r.getSpool()
+	if sp == nil {
+		return
+	}
+	if err := sp.Remove(key, 0); err != nil {
+		r.log.Warn("spool write failed", "key", key, "err", err)
+	}
+}
+
+// startReplay replays the spool in the background unless a replay is
+// running already. Statuses spooled while the replay wound down are picked
+// up by another one.
+func (r *Runner) startReplay(ctx context.Context, cfg config.Config) {
+	sp := r.getSpool()
+	if sp == nil || r.post == nil || !r.replaying.CompareAndSwap(false, true) {
+		return
+	}
+	go func() {
+		for {
+			drained := r.replaySpool(ctx, cfg)
+			r.replaying.Store(false)
+			if !drained || sp.Len() == 0 || !r.replaying.CompareAndSwap(false, true) {
+				return
+			}
+		}
+	}()
+}
+
+// spoolHolds queues upd behind a spooled status for the same key, so that
+// a replay running alongside can't post the older status last. The replay
+// posts it instead.
+func (r *Runner) spoolHolds(ctx context.Context, cfg config.Config, key string, upd Update) bool {
+	sp := r.getSpool()
+	if sp == nil || !sp.Has(key) {
+		return false
+	}
+	r.spoolFailed(cfg, spool.Entry{Key: key, IsHost: upd.IsHost, CompID: upd.CompID, Status: upd.Status})
+	r.startReplay(ctx, cfg)
+	return true
+}
+
+// replayPoster returns the poster a replay posts an entry through and the
+// host that poster posts for. A host_id change swaps the poster while a
+// replay may run, so entries are matched against the poster, not against
+// the config the replay started with.
+func (r *Runner) replayPoster(cfg config.Config) (Poster, int) {
+	if sw, ok := r.post.(*posterSwitch); ok {
+		if p := sw.cur.Load(); p != nil {
+			return p, p.hostID
+		}
+	}
+	return r.post, cfg.HostID
+}
+
+// replaySpool posts spooled statuses oldest first and stops at the first
+// failure, leaving the rest for the next cycle. Entries superseded while
+// it posted are replayed in another round, entries of another host are
+// dropped. It reports whether the spool was drained.
+func (r *Runner) replaySpool(ctx context.Context, cfg config.Config) bool {
+	sp := r.getSpool()
+	if sp == nil || r.post == nil {
+		return true
+	}
+	replayed := 0
+	for {
+		pending, err := sp.Pending(r.clk.Now())
+		if err != nil {
+			r.log.Warn("spool expiry failed", "err", err)
+		}
+		if len(pending) == 0 {
+			break
+		}
+		for i, e := range pending {
+			post, hostID := r.replayPoster(cfg)
+			if e.HostID != hostID {
+				_ = sp.Remove(e.Key, e.Seq)
+				continue
+			}
+			var postErr error
+			if e.IsHost {
+				postErr = post.PostHost(ctx, e.Status)
+			} else {
+				postErr = post.PostComponent(ctx, e.CompID, e.Status)
+			}
+			if postErr != nil {
+				r.log.DebugContext(ctx, "spool replay deferred", "pending", len(pending)-i, "err", postErr)
+				return false
+			}
+			if rmErr := sp.Remove(e.Key, e.Seq); rmErr != nil {
+				r.log.Warn("spool write failed", "key", e.Key, "err", rmErr)
+			}
+			r.markSent(e.Key, e.Status)
+			replayed++
+		}
+	}
+	if replayed > 0 {
+		r.log.InfoContext(ctx, "spool replayed", "count", replayed)
+	}
+	return true
+}
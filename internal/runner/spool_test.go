@@ -0,0 +1,179 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/arenadata/ad-status-sender/internal/check/checktest"
+	"github.com/arenadata/ad-status-sender/internal/config"
+	"github.com/arenadata/ad-status-sender/internal/rules"
+	"github.com/arenadata/ad-status-sender/internal/spool"
+)
+
+type flakyPoster struct {
+	testPoster
+	mu   sync.Mutex
+	down bool
+}
+
+func (p *flakyPoster) setDown(v bool) {
+	p.mu.Lock()
+	p.down = v
+	p.mu.Unlock()
+}
+
+func (p *flakyPoster) isDown() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.down
+}
+
+func (p *flakyPoster) PostHost(ctx context.Context, status int) error {
+	if p.isDown() {
+		return errors.New("unreachable")
+	}
+	return p.testPoster.PostHost(ctx, status)
+}
+
+func (p *flakyPoster) PostComponent(ctx context.Context, compID string, status int) error {
+	if p.isDown() {
+		return errors.New("unreachable")
+	}
+	return p.testPoster.PostComponent(ctx, compID, status)
+}
+
+func (p *flakyPoster) PostBatch(ctx context.Context, updates []Update) error {
+	if p.isDown() {
+		return errors.New("unreachable")
+	}
+	return p.testPoster.PostBatch(ctx, updates)
+}
+
+func TestRunner_SpoolReplaysAfterOutageAndRestart(t *testing.T) {
+	dir := t.TempDir()
+	sd := &checktest.FakeSystemd{Units: map[string]bool{"nginx.service": false}}
+	post := &flakyPoster{down: true}
+	clk := &testClock{now: time.Unix(0, 0)}
+	cfg := config.Config{
+		ADCMURL:        "http://example",
+		HostID:         7,
+		ForceSendAfter: "120s",
+		Spool:          config.Spool{Dir: dir},
+	}
+
+	newRunner := func() *Runner {
+		r := newTestRunner(t, cfg, sd, &checktest.FakeDocker{}, post, clk)
+		r.openSpool(cfg)
+		r.ruleStore.Set(rules.Rules{
+			Systemd: []rules.RuleSystemd{{Unit: "nginx.service", Components: []string{"501"}}},
+		})
+		return r
+	}
+
+	r := newRunner()
+	ctx := context.Background()
+	r.scanOnce(ctx)
+	waitUntil(t, func() bool { return r.getSpool().Len() == 2 }, 500*time.Millisecond)
+
+	// restart while ADCM is still down: the spool is loaded from disk
+	r = newRunner()
+	if got := r.getSpool().Len(); got != 2 {
+		t.Fatalf("spool not persisted, got %d entries", got)
+	}
+
+	post.setDown(false)
+	sd.Set("nginx.service", true)
+	r.scanOnce(ctx)
+	lastIsFresh := func() bool {
+		got := componentPosts(post)
+		return len(got) > 0 && got[len(got)-1] == 0
+	}
+	waitUntil(t, func() bool { return r.getSpool().Len() == 0 && lastIsFresh() }, 500*time.Millisecond)
+
+	// the replay runs alongside the scan: the spooled 501=1 is either
+	// replayed before the fresh 501=0 or superseded by it, never posted last
+	if got := componentPosts(post); len(got) > 2 || (len(got) == 2 && got[0] != 1) {
+		t.Fatalf("want at most 501=1 then 501=0, got %v", got)
+	}
+	if ss := post.Snapshot(); !slices.Contains(ss, sentEvent{IsHost: true}) {
+		t.Fatalf("spooled host status not replayed, got %+v", ss)
+	}
+}
+
+// componentPosts returns the statuses posted for component 501, in order.
+func componentPosts(p *flakyPoster) []int {
+	var out []int
+	for _, e := range p.Snapshot() {
+		if e.CompID == "501" {
+			out = append(out, e.Status)
+		}
+	}
+	return out
+}
+
+func TestRunner_OpenSpoolAppliesLimitChanges(t *testing.T) {
+	cfg := config.Config{ADCMURL: "http://example", HostID: 7, Spool: config.Spool{Dir: t.TempDir()}}
+	r := newTestRunner(t, cfg, &checktest.FakeSystemd{}, &checktest.FakeDocker{}, &testPoster{},
+		&testClock{now: time.Unix(0, 0)})
+	r.openSpool(cfg)
+	sp := r.getSpool()
+	for _, comp := range []string{"1", "2", "3"} {
+		r.spoolFailed(cfg, spool.Entry{Key: "comp:7:" + comp, CompID: comp, Status: 1})
+	}
+
+	next := cfg
+	next.Spool.MaxEntries = 1
+	r.openSpool(next)
+	if r.getSpool() != sp || sp.Len() != 1 {
+		t.Fatalf("want the open spool trimmed to 1 entry, got %d", r.getSpool().Len())
+	}
+}
+
+func TestRunner_ReplayFollowsHostChange(t *testing.T) {
+	var (
+		mu    sync.Mutex
+		paths []string
+		sw    = &posterSwitch{}
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(_ http.ResponseWriter, req *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		paths = append(paths, req.URL.Path)
+		if len(paths) == 1 {
+			// a reload to host_id 8 lands while the replay runs
+			next := sw.cur.Load()
+			moved := newTestPoster(next.adcmURL, config.Retry{MaxAttempts: 1})
+			moved.hostID = 8
+			sw.cur.Store(moved)
+		}
+	}))
+	defer srv.Close()
+	sw.cur.Store(newTestPoster(srv.URL, config.Retry{MaxAttempts: 1}))
+
+	cfg := config.Config{ADCMURL: srv.URL, HostID: 7, Spool: config.Spool{Dir: t.TempDir()}}
+	r := newTestRunner(t, cfg, &checktest.FakeSystemd{}, &checktest.FakeDocker{}, sw,
+		&testClock{now: time.Unix(0, 0)})
+	r.openSpool(cfg)
+	for _, comp := range []string{"1", "2", "3"} {
+		r.spoolFailed(cfg, spool.Entry{Key: "comp:7:" + comp, CompID: comp, Status: 1})
+	}
+
+	if !r.replaySpool(context.Background(), cfg) {
+		t.Fatal("replay did not drain the spool")
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(paths) != 1 || !strings.Contains(paths[0], "/host/7/") {
+		t.Fatalf("want one post to host 7 and the rest dropped, got %v", paths)
+	}
+	if n := r.getSpool().Len(); n != 0 {
+		t.Fatalf("want the spool emptied, %d left", n)
+	}
+}
@@ -0,0 +1,202 @@
+package runner
+
+import (
+	"cmp"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/arenadata/ad-status-sender/internal/config"
+	"github.com/arenadata/ad-status-sender/internal/metrics"
+	"github.com/arenadata/ad-status-sender/internal/rules"
+)
+
+// readyCycles is how many scan intervals may pass without a finished cycle
+// before /readyz fails.
+const readyCycles = 3
+
+// checkStore keeps the results of the last finished cycle for /status and
+// /metrics.
+type checkStore struct {
+	mu         sync.RWMutex
+	results    []checkResult
+	components map[string]int
+	scanned    time.Time
+}
+
+type checkResult struct {
+	Kind       string   `json:"kind"`
+	Rule       string   `json:"rule,omitempty"`
+	Target     string   `json:"target"`
+	Status     int      `json:"status"`
+	Raw        int      `json:"raw_status"`
+	Components []string `json:"components"`
+	index      int      // of the rule, which tells apart rules sharing a target
+}
+
+func (s *checkStore) record(results []result, components map[string]int, now time.Time) {
+	out := make([]checkResult, 0, len(results))
+	for _, res := range results {
+		out = append(out, checkResult{
+			Kind:       res.kind,
+			Rule:       res.rule,
+			index:      res.index,
+			Target:     res.target,
+			Status:     res.status,
+			Raw:        res.raw,
+			Components: res.comps,
+		})
+	}
+	slices.SortFunc(out, func(a, b checkResult) int {
+		return cmp.Or(cmp.Compare(a.Kind, b.Kind), cmp.Compare(a.Target, b.Target), cmp.Compare(a.index, b.index))
+	})
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.results = out
+	s.components = components
+	s.scanned = now
+}
+
+func (s *checkStore) last() ([]checkResult, map[string]int, time.Time) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.results, s.components, s.scanned
+}
+
+type cacheEntry struct {
+	Key      string    `json:"key"`
+	Status   int       `json:"status"`
+	LastSent time.Time `json:"last_sent"`
+}
+
+func (r *Runner) cacheEntries() []cacheEntry {
+	r.cacheMu.Lock()
+	out := make([]cacheEntry, 0, len(r.cache))
+	for k, v := range r.cache {
+		out = append(out, cacheEntry{Key: k, Status: v.status, LastSent: v.lastTime})
+	}
+	r.cacheMu.Unlock()
+	slices.SortFunc(out, func(a, b cacheEntry) int { return cmp.Compare(a.Key, b.Key) })
+	return out
+}
+
+// handler serves the local status endpoints.
+func (r *Runner) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /healthz", func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte("ok\n"))
+	})
+	mux.HandleFunc("GET /readyz", r.serveReady)
+	mux.HandleFunc("GET /status", r.serveStatus)
+	mux.HandleFunc("GET /metrics", func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", metrics.ContentType)
+		_ = r.metrics.WriteText(w)
+	})
+	return mux
+}
+
+// serveReady succeeds once a scan cycle has finished recently.
+func (r *Runner) serveReady(w http.ResponseWriter, _ *http.Request) {
+	cfg, _, _ := r.snapshot()
+	_, _, scanned := r.checks.last()
+	maxAge := readyCycles * config.MustDuration(cfg.Interval, config.DefaultInterval)
+	switch {
+	case scanned.IsZero():
+		http.Error(w, "no scan finished yet", http.StatusServiceUnavailable)
+	case r.clk.Now().Sub(scanned) > maxAge:
+		http.Error(w, "last scan at "+scanned.Format(time.RFC3339), http.StatusServiceUnavailable)
+	default:
+		_, _ = w.Write([]byte("ok\n"))
+	}
+}
+
+type statusDump struct {
+	HostID        int               `json:"host_id"`
+	LastScan      *time.Time        `json:"last_scan"`
+	Rules         rules.Rules       `json:"rules"`
+	Checks        []checkResult     `json:"checks"`
+	Components    map[string]int    `json:"components"`
+	ComponentRefs map[string]string `json:"component_refs,omitempty"` // "service/component" -> discovered ID
+	Cache         []cacheEntry      `json:"cache"`
+	Spooled       int               `json:"spooled"`
+}
+
+func (r *Runner) serveStatus(w http.ResponseWriter, _ *http.Request) {
+	cfg, _, _ := r.snapshot()
+	checks, comps, scanned := r.checks.last()
+	dump := statusDump{
+		HostID:        cfg.HostID,
+		Rules:         redactRules(r.ruleStore.Get()),
+		Checks:        checks,
+		Components:    comps,
+		ComponentRefs: r.componentRefs(),
+		Cache:         r.cacheEntries(),
+	}
+	if !scanned.IsZero() {
+		dump.LastScan = &scanned
+	}
+	if sp := r.getSpool(); sp != nil {
+		dump.Spooled = sp.Len()
+	}
+	w.Header().Set("Content-Type", "application/json")
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	_ = enc.Encode(dump)
+}
+
+// redactRules hides what often carries credentials: header values of HTTP
+// rules, env values of exec rules and passwords in HTTP URLs and TCP
+// addresses.
+func redactRules(rr rules.Rules) rules.Rules {
+	out := rr
+	out.HTTP = slices.Clone(rr.HTTP)
+	for i := range out.HTTP {
+		out.HTTP[i].URL = redactURL(out.HTTP[i].URL)
+		out.HTTP[i].Headers = redactValues(out.HTTP[i].Headers)
+	}
+	out.TCP = slices.Clone(rr.TCP)
+	for i := range out.TCP {
+		out.TCP[i].Address = redactAddress(out.TCP[i].Address)
+	}
+	out.Exec = slices.Clone(rr.Exec)
+	for i := range out.Exec {
+		out.Exec[i].Env = redactValues(out.Exec[i].Env)
+	}
+	return out
+}
+
+func redactValues(m map[string]string) map[string]string {
+	if len(m) == 0 {
+		return m
+	}
+	out := make(map[string]string, len(m))
+	for k, v := range m {
+		if strings.TrimSpace(v) != "" {
+			v = "<redacted>"
+		}
+		out[k] = v
+	}
+	return out
+}
+
+// redactURL masks the password of a URL with userinfo.
+func redactURL(s string) string {
+	u, err := url.Parse(s)
+	if err != nil || u.User == nil {
+		return s
+	}
+	return u.Redacted()
+}
+
+// redactAddress masks the password of a host:port address with userinfo.
+func redactAddress(s string) string {
+	u, err := url.Parse("//" + s)
+	if err != nil || u.User == nil {
+		return s
+	}
+	return strings.TrimPrefix(u.Redacted(), "//")
+}
@@ -0,0 +1,71 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+
+	"github.com/arenadata/ad-status-sender/internal/check"
+	"github.com/arenadata/ad-status-sender/internal/config"
+	"github.com/arenadata/ad-status-sender/internal/rules"
+)
+
+func (r *Runner) scanTCP(ctx context.Context, cyc *cycle, rr rules.Rules) {
+	for i, rule := range rr.TCP {
+		comps := append([]string(nil), rule.Components...)
+		r.check(ctx, cyc, func() result {
+			st := r.probeTCP(ctx, rule)
+			return result{kind: "tcp", index: i, target: tcpTarget(rule), status: st, comps: comps, deb: rule.Debounce}
+		})
+	}
+}
+
+func (r *Runner) probeTCP(ctx context.Context, rule rules.RuleTCP) int {
+	if r.probe == nil {
+		return 1
+	}
+	spec, err := probeSpec(rule)
+	if err == nil {
+		err = r.probe.Probe(ctx, spec)
+	}
+	if err != nil {
+		r.log.DebugContext(ctx, "tcp check failed", "rule", rule.Name, "target", tcpTarget(rule), "err", err)
+		return 1
+	}
+	return 0
+}
+
+func probeSpec(rule rules.RuleTCP) (check.ProbeSpec, error) {
+	spec := check.ProbeSpec{
+		Network: "tcp",
+		Address: rule.Address,
+		Timeout: config.MustDuration(rule.Timeout, check.DefaultProbeTimeout),
+	}
+	if rule.Socket != "" {
+		spec.Network, spec.Address = "unix", rule.Socket
+	}
+	if rule.Send != "" {
+		spec.Send = []byte(rule.Send)
+	}
+	if rule.ExpectPrefix != "" {
+		spec.ExpectPrefix = []byte(rule.ExpectPrefix)
+	}
+	if rule.ExpectRegex != "" {
+		re, err := regexp.Compile(rule.ExpectRegex)
+		if err != nil {
+			return spec, fmt.Errorf("expect_regex: %w", err)
+		}
+		spec.Expect = re
+	}
+	return spec, nil
+}
+
+func tcpTarget(rule rules.RuleTCP) string {
+	if rule.Name != "" {
+		return rule.Name
+	}
+	if rule.Socket != "" {
+		return rule.Socket
+	}
+	return redactAddress(rule.Address)
+}
@@ -0,0 +1,67 @@
+package runner
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/arenadata/ad-status-sender/internal/check"
+	"github.com/arenadata/ad-status-sender/internal/check/checktest"
+	"github.com/arenadata/ad-status-sender/internal/config"
+	"github.com/arenadata/ad-status-sender/internal/rules"
+)
+
+func TestRunner_TCPChecks(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	go func() {
+		for {
+			c, aErr := ln.Accept()
+			if aErr != nil {
+				return
+			}
+			_, _ = c.Write([]byte("+OK ready\r\n"))
+			_ = c.Close()
+		}
+	}()
+	closed, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	closedAddr := closed.Addr().String()
+	_ = closed.Close()
+
+	post := &testPoster{}
+	cfg := config.Config{ADCMURL: "http://example", HostID: 7, ForceSendAfter: "120s"}
+	r := newTestRunner(t, cfg, &checktest.FakeSystemd{}, &checktest.FakeDocker{}, post,
+		&testClock{now: time.Unix(0, 0)})
+	r.probe = check.TCPProber{}
+
+	addr := ln.Addr().String()
+	r.ruleStore.Set(rules.Rules{
+		TCP: []rules.RuleTCP{
+			{Name: "up", Address: addr, Components: []string{"901"}},
+			{Name: "banner", Address: addr, ExpectRegex: `^\+OK`, Components: []string{"902"}},
+			{Name: "bad-banner", Address: addr, ExpectPrefix: "-ERR", Timeout: "200ms", Components: []string{"903"}},
+			{Name: "down", Address: closedAddr, Timeout: "200ms", Components: []string{"904"}},
+			{Name: "bad-regex", Address: addr, ExpectRegex: "(", Components: []string{"905"}},
+		},
+	})
+
+	r.scanOnce(context.Background())
+	waitUntil(t, func() bool { return post.Count() == 6 }, 2*time.Second)
+
+	want := map[string]int{"901": 0, "902": 0, "903": 1, "904": 1, "905": 1}
+	for _, e := range post.Snapshot() {
+		if e.IsHost {
+			continue
+		}
+		if want[e.CompID] != e.Status {
+			t.Fatalf("comp %s: want %d, got %d", e.CompID, want[e.CompID], e.Status)
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/arenadata/ad-status-sender/internal/config"
+	"github.com/arenadata/ad-status-sender/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+const tracingStopTimeout = serverStopTimeout
+
+// startTracing replaces the no-op tracer when c.Tracing has an endpoint.
+// Like the listener it is set up once per Start.
+func (r *Runner) startTracing(c config.Config) error {
+	tp, err := tracing.New(context.Background(), c.Tracing, c.HostID)
+	if err != nil {
+		return err
+	}
+	r.traces = tp
+	r.tracer = tp.Tracer(tracing.Name)
+	if sw, ok := r.post.(*posterSwitch); ok {
+		// workers are not up yet, nothing posts concurrently
+		sw.cur.Load().tracer = r.tracer
+	}
+	if c.Tracing.Endpoint != "" {
+		r.log.Info("tracing enabled", "endpoint", c.Tracing.Endpoint)
+	}
+	return nil
+}
+
+// stopTracing flushes spans that have not been exported yet.
+func (r *Runner) stopTracing() {
+	ctx, cancel := context.WithTimeout(context.Background(), tracingStopTimeout)
+	defer cancel()
+	if err := r.traces.Shutdown(ctx); err != nil {
+		r.log.Warn("tracing shutdown", "err", err)
+	}
+}
+
+func (p *httpPoster) startSpan(ctx context.Context, name string, opts ...trace.SpanStartOption) (
+	context.Context,
+	trace.Span,
+) {
+	tr := p.tracer
+	if tr == nil {
+		tr = noop.NewTracerProvider().Tracer(tracing.Name)
+	}
+	return tr.Start(ctx, name, opts...)
+}
+
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// spanAttrs turns the log attributes of a post ("comp", id, "sent_status",
+// status, ...) into span attributes.
+func spanAttrs(attrs []any) []attribute.KeyValue {
+	var out []attribute.KeyValue
+	for i := 0; i+1 < len(attrs); i += 2 {
+		key := fmt.Sprint(attrs[i])
+		switch key {
+		case "comp":
+			key = "adcm.component_id"
+		case "sent_status":
+			key = "adcm.status"
+		default:
+			key = "adcm." + key
+		}
+		switch v := attrs[i+1].(type) {
+		case int:
+			out = append(out, attribute.Int(key, v))
+		default:
+			out = append(out, attribute.String(key, fmt.Sprint(v)))
+		}
+	}
+	return out
+}
@@ -0,0 +1,88 @@
+package runner
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/arenadata/ad-status-sender/internal/check/checktest"
+	"github.com/arenadata/ad-status-sender/internal/config"
+	"github.com/arenadata/ad-status-sender/internal/rules"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func spanAttr(s sdktrace.ReadOnlySpan, key string) attribute.Value {
+	for _, kv := range s.Attributes() {
+		if string(kv.Key) == key {
+			return kv.Value
+		}
+	}
+	return attribute.Value{}
+}
+
+func TestRunner_TracesCycleChecksAndPosts(t *testing.T) {
+	var mu sync.Mutex
+	var parents []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		parents = append(parents, r.Header.Get("Traceparent"))
+		mu.Unlock()
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	rec := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(rec))
+	p := newTestPoster(srv.URL, config.Retry{MaxAttempts: 1, BreakerThreshold: -1})
+	p.tracer = tp.Tracer("test")
+
+	sd := &checktest.FakeSystemd{Units: map[string]bool{"a.service": true}}
+	cfg := config.Config{ADCMURL: srv.URL, HostID: 7}
+	r := newTestRunner(t, cfg, sd, &checktest.FakeDocker{}, p, &testClock{now: time.Unix(0, 0)})
+	r.tracer = tp.Tracer("test")
+	r.ruleStore.Set(rules.Rules{Systemd: []rules.RuleSystemd{{Unit: "a.service", Components: []string{"501"}}}})
+
+	r.scanOnce(context.Background())
+	// scan, check, host post + POST, status post + POST
+	waitUntil(t, func() bool { return len(rec.Ended()) == 6 }, 2*time.Second)
+
+	byName := make(map[string]sdktrace.ReadOnlySpan)
+	for _, s := range rec.Ended() {
+		byName[s.Name()] = s
+	}
+	scan, chk, post := byName["scan"], byName["check systemd"], byName["status post"]
+	if scan == nil || chk == nil || post == nil {
+		t.Fatalf("missing spans: %v", byName)
+	}
+	if chk.Parent().SpanID() != scan.SpanContext().SpanID() || post.Parent().SpanID() != scan.SpanContext().SpanID() {
+		t.Fatalf("check and post spans are not children of the cycle")
+	}
+	if spanAttr(chk, "check.target").AsString() != "a.service" ||
+		spanAttr(post, "adcm.component_id").AsString() != "501" {
+		t.Fatalf("unexpected attributes: %v / %v", chk.Attributes(), post.Attributes())
+	}
+
+	var req sdktrace.ReadOnlySpan
+	for _, s := range rec.Ended() {
+		if s.Name() == http.MethodPost && s.Parent().SpanID() == post.SpanContext().SpanID() {
+			req = s
+		}
+	}
+	if req == nil || spanAttr(req, "http.response.status_code").AsInt64() != http.StatusOK {
+		t.Fatalf("request span missing or without status code")
+	}
+	want := "00-" + req.SpanContext().TraceID().String() + "-" + req.SpanContext().SpanID().String() + "-01"
+	mu.Lock()
+	defer mu.Unlock()
+	for _, h := range parents {
+		if h == want {
+			return
+		}
+	}
+	t.Fatalf("traceparent %q not sent, got %v", want, parents)
+}
@@ -0,0 +1,200 @@
+// Package schema decodes YAML strictly and reports validation problems with
+// the line and column of the offending node.
+package schema
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/goccy/go-yaml"
+	"github.com/goccy/go-yaml/ast"
+	"github.com/goccy/go-yaml/parser"
+)
+
+// ValidationError is one problem in a file. Path is the field in
+// "systemd[0].unit" form; Line and Column are 0 if the node is unknown.
+type ValidationError struct {
+	File   string
+	Line   int
+	Column int
+	Path   string
+	Msg    string
+}
+
+func (e *ValidationError) Error() string {
+	var b strings.Builder
+	b.WriteString(e.File)
+	if e.Line > 0 {
+		fmt.Fprintf(&b, ":%d:%d", e.Line, e.Column)
+	}
+	if e.Path != "" {
+		b.WriteString(": " + e.Path)
+	}
+	b.WriteString(": " + e.Msg)
+	return b.String()
+}
+
+// Decode unmarshals data into v, rejecting unknown fields. Errors carry the
+// position reported by the YAML decoder.
+func Decode(file string, data []byte, v any) error {
+	err := yaml.UnmarshalWithOptions(data, v, yaml.Strict())
+	if err == nil {
+		return nil
+	}
+	var yerr yaml.Error
+	if errors.As(err, &yerr) && yerr.GetToken() != nil {
+		pos := yerr.GetToken().Position
+		return &ValidationError{File: file, Line: pos.Line, Column: pos.Column, Msg: yerr.GetMessage()}
+	}
+	return &ValidationError{File: file, Msg: err.Error()}
+}
+
+// Checker collects the problems of one decoded document.
+type Checker struct {
+	file string
+	data []byte
+	errs []*ValidationError
+}
+
+// NewChecker returns a Checker for the document decoded from data.
+func NewChecker(file string, data []byte) *Checker {
+	return &Checker{file: file, data: data}
+}
+
+// Addf records a problem at path.
+func (c *Checker) Addf(path, format string, args ...any) {
+	c.errs = append(c.errs, &ValidationError{File: c.file, Path: path, Msg: fmt.Sprintf(format, args...)})
+}
+
+// Err returns all recorded problems joined, each located in the source, or
+// nil.
+func (c *Checker) Err() error {
+	if len(c.errs) == 0 {
+		return nil
+	}
+	f, parseErr := parser.ParseBytes(c.data, 0)
+	errs := make([]error, 0, len(c.errs))
+	for _, e := range c.errs {
+		if parseErr == nil {
+			e.Line, e.Column = locate(f, e.Path)
+		}
+		errs = append(errs, e)
+	}
+	return errors.Join(errs...)
+}
+
+// Required reports an empty value.
+func (c *Checker) Required(path, v string) {
+	if strings.TrimSpace(v) == "" {
+		c.Addf(path, "is required")
+	}
+}
+
+// Duration reports a value that is set but does not parse or is negative.
+func (c *Checker) Duration(path, v string) {
+	if strings.TrimSpace(v) == "" {
+		return
+	}
+	d, err := time.ParseDuration(strings.TrimSpace(v))
+	switch {
+	case err != nil:
+		c.Addf(path, "invalid duration %q", v)
+	case d < 0:
+		c.Addf(path, "duration must not be negative")
+	}
+}
+
+// Regexp reports a pattern that is set but does not compile.
+func (c *Checker) Regexp(path, v string) {
+	if v == "" {
+		return
+	}
+	if _, err := regexp.Compile(v); err != nil {
+		c.Addf(path, "invalid regular expression: %v", err)
+	}
+}
+
+// File reports a path that is set but does not exist.
+func (c *Checker) File(path, name string) {
+	if name == "" {
+		return
+	}
+	if _, err := os.Stat(name); err != nil {
+		c.Addf(path, "%v", err)
+	}
+}
+
+// URL reports a value that is set but is not an absolute http(s) URL.
+func (c *Checker) URL(path, v string) {
+	if v == "" {
+		return
+	}
+	u, err := url.Parse(v)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		c.Addf(path, "want an http:// or https:// URL, got %q", v)
+	}
+}
+
+// Components reports an empty list or empty component IDs.
+func (c *Checker) Components(path string, comps []string) {
+	if len(comps) == 0 {
+		c.Addf(path, "at least one component is required")
+	}
+	for i, comp := range comps {
+		if strings.TrimSpace(comp) == "" {
+			c.Addf(fmt.Sprintf("%s[%d]", path, i), "component ID is empty")
+		}
+	}
+}
+
+// OneOf reports a value that is set but not among allowed.
+func (c *Checker) OneOf(path, v string, allowed ...string) {
+	if v == "" {
+		return
+	}
+	for _, a := range allowed {
+		if v == a {
+			return
+		}
+	}
+	c.Addf(path, "must be one of %s, got %q", strings.Join(allowed, ", "), v)
+}
+
+// locate finds the node at path, falling back to its closest existing
+// parent for fields that are missing.
+func locate(f *ast.File, path string) (int, int) {
+	for path != "" {
+		if p, err := yaml.PathString("$." + path); err == nil {
+			if n, fErr := p.FilterFile(f); fErr == nil && n != nil {
+				if tk := start(n).GetToken(); tk != nil {
+					return tk.Position.Line, tk.Position.Column
+				}
+			}
+		}
+		i := strings.LastIndexAny(path, ".[")
+		if i < 0 {
+			break
+		}
+		path = path[:i]
+	}
+	return 0, 0
+}
+
+// start returns the node where n begins: a mapping is reported at its first
+// key rather than at the colon.
+func start(n ast.Node) ast.Node {
+	switch m := n.(type) {
+	case *ast.MappingNode:
+		if len(m.Values) > 0 {
+			return m.Values[0].Key
+		}
+	case *ast.MappingValueNode:
+		return m.Key
+	}
+	return n
+}
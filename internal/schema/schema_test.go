@@ -0,0 +1,57 @@
+package schema
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestDecode_UnknownField(t *testing.T) {
+	var v struct {
+		Name string `yaml:"name"`
+	}
+	err := Decode("a.yaml", []byte("name: x\nnmae: y\n"), &v)
+	var verr *ValidationError
+	if !errors.As(err, &verr) {
+		t.Fatalf("want ValidationError, got %v", err)
+	}
+	if verr.Line != 2 || verr.Column != 1 || !strings.HasPrefix(err.Error(), "a.yaml:2:1: ") {
+		t.Fatalf("unexpected position: %v", err)
+	}
+}
+
+func TestChecker_LocatesFields(t *testing.T) {
+	data := []byte(`
+items:
+  - unit: a
+    timeout: soon
+  - unit: b
+`)
+	chk := NewChecker("r.yaml", data)
+	chk.Duration("items[0].timeout", "soon")
+	chk.Components("items[1].components", nil)
+	chk.OneOf("mode", "fast", "slow", "off")
+	err := chk.Err()
+	if err == nil {
+		t.Fatal("want errors")
+	}
+	got := strings.Split(err.Error(), "\n")
+	want := []string{
+		`r.yaml:4:14: items[0].timeout: invalid duration "soon"`,
+		"r.yaml:5:5: items[1].components: at least one component is required",
+		`r.yaml: mode: must be one of slow, off, got "fast"`,
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("want\n%s\ngot\n%s", strings.Join(want, "\n"), err)
+	}
+}
+
+func TestChecker_NoErrors(t *testing.T) {
+	chk := NewChecker("r.yaml", []byte("a: 1\n"))
+	chk.Duration("a", "1s")
+	chk.URL("u", "https://adcm.example")
+	chk.Regexp("re", `^\d+$`)
+	if err := chk.Err(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,193 @@
+package spool
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	fileName      = "spool.json"
+	corruptSuffix = ".corrupt"
+	dirPerm       = 0o750
+	filePerm      = 0o600
+)
+
+// Entry is the latest undelivered status for one cache key.
+type Entry struct {
+	Key    string    `json:"key"`
+	HostID int       `json:"host_id"`
+	CompID string    `json:"comp_id,omitempty"`
+	IsHost bool      `json:"is_host,omitempty"`
+	Status int       `json:"status"`
+	Seq    uint64    `json:"seq"`
+	Time   time.Time `json:"time"`
+}
+
+// Spool keeps at most one entry per key in memory and mirrors every change
+// to a JSON file in dir, so undelivered statuses survive restarts.
+type Spool struct {
+	path       string
+	maxEntries int
+	maxAge     time.Duration
+
+	mu          sync.Mutex
+	seq         uint64
+	entries     map[string]Entry
+	quarantined string
+}
+
+// Open loads the spool from dir, creating the directory if needed.
+// maxEntries <= 0 and maxAge <= 0 disable the respective limit. A file that
+// can't be decoded is renamed aside and the spool starts empty; Quarantined
+// tells where it went.
+func Open(dir string, maxEntries int, maxAge time.Duration) (*Spool, error) {
+	if err := os.MkdirAll(dir, dirPerm); err != nil {
+		return nil, err
+	}
+	s := &Spool{
+		path:       filepath.Join(dir, fileName),
+		maxEntries: maxEntries,
+		maxAge:     maxAge,
+		entries:    make(map[string]Entry),
+	}
+	data, err := os.ReadFile(s.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var list []Entry
+	if unErr := json.Unmarshal(data, &list); unErr != nil {
+		s.quarantined = s.path + corruptSuffix
+		if mvErr := os.Rename(s.path, s.quarantined); mvErr != nil {
+			return nil, fmt.Errorf("spool %s: %w (quarantine: %w)", s.path, unErr, mvErr)
+		}
+		return s, nil
+	}
+	for _, e := range list {
+		s.entries[e.Key] = e
+		s.seq = max(s.seq, e.Seq)
+	}
+	return s, nil
+}
+
+// Put stores e as the latest undelivered status for e.Key. An entry with the
+// same status is kept as is, so repeated failures don't rewrite the file.
+func (s *Spool) Put(e Entry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if prev, ok := s.entries[e.Key]; ok && prev.Status == e.Status {
+		return nil
+	}
+	s.seq++
+	e.Seq = s.seq
+	s.entries[e.Key] = e
+	for s.maxEntries > 0 && len(s.entries) > s.maxEntries {
+		delete(s.entries, s.oldestLocked().Key)
+	}
+	return s.saveLocked()
+}
+
+// Remove drops the entry for key. If seq is non-zero, the entry is only
+// dropped when it has not been superseded since it was read.
+func (s *Spool) Remove(key string, seq uint64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	prev, ok := s.entries[key]
+	if !ok || (seq != 0 && prev.Seq != seq) {
+		return nil
+	}
+	delete(s.entries, key)
+	return s.saveLocked()
+}
+
+// Pending returns entries in the order they were spooled, discarding those
+// older than the age limit.
+func (s *Spool) Pending(now time.Time) ([]Entry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	expired := false
+	out := make([]Entry, 0, len(s.entries))
+	for k, e := range s.entries {
+		if s.maxAge > 0 && now.Sub(e.Time) > s.maxAge {
+			delete(s.entries, k)
+			expired = true
+			continue
+		}
+		out = append(out, e)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Seq < out[j].Seq })
+	if expired {
+		return out, s.saveLocked()
+	}
+	return out, nil
+}
+
+// SetLimits changes the limits of an open spool. Entries beyond the new
+// maxEntries are dropped oldest first; maxAge applies from the next Pending.
+func (s *Spool) SetLimits(maxEntries int, maxAge time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.maxEntries, s.maxAge = maxEntries, maxAge
+	if s.maxEntries <= 0 || len(s.entries) <= s.maxEntries {
+		return nil
+	}
+	for len(s.entries) > s.maxEntries {
+		delete(s.entries, s.oldestLocked().Key)
+	}
+	return s.saveLocked()
+}
+
+// Quarantined returns where Open moved an undecodable spool file, if it did.
+func (s *Spool) Quarantined() string {
+	return s.quarantined
+}
+
+// Has reports whether an entry for key is waiting.
+func (s *Spool) Has(key string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, ok := s.entries[key]
+	return ok
+}
+
+func (s *Spool) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.entries)
+}
+
+func (s *Spool) oldestLocked() Entry {
+	var oldest Entry
+	for _, e := range s.entries {
+		if oldest.Key == "" || e.Seq < oldest.Seq {
+			oldest = e
+		}
+	}
+	return oldest
+}
+
+func (s *Spool) saveLocked() error {
+	list := make([]Entry, 0, len(s.entries))
+	for _, e := range s.entries {
+		list = append(list, e)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Seq < list[j].Seq })
+	data, err := json.Marshal(list)
+	if err != nil {
+		return err
+	}
+	tmp := s.path + ".tmp"
+	if wErr := os.WriteFile(tmp, data, filePerm); wErr != nil {
+		return wErr
+	}
+	return os.Rename(tmp, s.path)
+}
@@ -0,0 +1,107 @@
+package spool
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestSpool_LatestPerKeyAndPersistence(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Unix(1000, 0)
+
+	s, err := Open(dir, 0, 0)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	_ = s.Put(Entry{Key: "comp:1:501", CompID: "501", Status: 1, Time: now})
+	_ = s.Put(Entry{Key: "host:1", IsHost: true, Status: 0, Time: now})
+	_ = s.Put(Entry{Key: "comp:1:501", CompID: "501", Status: 0, Time: now})
+
+	re, err := Open(dir, 0, 0)
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	got, _ := re.Pending(now)
+	if len(got) != 2 || got[0].Key != "host:1" || got[1].Key != "comp:1:501" || got[1].Status != 0 {
+		t.Fatalf("want host then latest 501=0, got %+v", got)
+	}
+
+	// stale seq must not remove a newer entry
+	_ = re.Put(Entry{Key: "host:1", IsHost: true, Status: 1, Time: now})
+	_ = re.Remove("host:1", got[0].Seq)
+	if re.Len() != 2 {
+		t.Fatalf("superseded entry was removed")
+	}
+	_ = re.Remove("host:1", 0)
+	if re.Len() != 1 {
+		t.Fatalf("want 1 entry after remove, got %d", re.Len())
+	}
+}
+
+func TestSpool_Limits(t *testing.T) {
+	now := time.Unix(1000, 0)
+	s, err := Open(t.TempDir(), 2, time.Minute)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	_ = s.Put(Entry{Key: "a", Status: 1, Time: now.Add(-2 * time.Minute)})
+	_ = s.Put(Entry{Key: "b", Status: 1, Time: now})
+	_ = s.Put(Entry{Key: "c", Status: 1, Time: now})
+	if s.Len() != 2 {
+		t.Fatalf("max entries not enforced: %d", s.Len())
+	}
+
+	_ = s.Put(Entry{Key: "d", Status: 1, Time: now.Add(-2 * time.Minute)})
+	// "b" is evicted as the oldest, "d" is dropped as expired
+	got, _ := s.Pending(now)
+	if len(got) != 1 || got[0].Key != "c" {
+		t.Fatalf("want only c pending, got %+v", got)
+	}
+}
+
+func TestSpool_SetLimits(t *testing.T) {
+	now := time.Unix(1000, 0)
+	s, err := Open(t.TempDir(), 0, 0)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	for _, k := range []string{"a", "b", "c"} {
+		_ = s.Put(Entry{Key: k, Status: 1, Time: now})
+	}
+	if err = s.SetLimits(2, time.Minute); err != nil {
+		t.Fatalf("set limits: %v", err)
+	}
+	got, _ := s.Pending(now.Add(time.Second))
+	if len(got) != 2 || got[0].Key != "b" {
+		t.Fatalf("want the oldest entry evicted, got %+v", got)
+	}
+	if got, _ = s.Pending(now.Add(2 * time.Minute)); len(got) != 0 {
+		t.Fatalf("new max age not applied, got %+v", got)
+	}
+}
+
+func TestSpool_QuarantinesCorruptFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, fileName)
+	if err := os.WriteFile(path, []byte(`[{"key": "a", "sta`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	s, err := Open(dir, 0, 0)
+	if err != nil {
+		t.Fatalf("a corrupt file must not disable the spool: %v", err)
+	}
+	if s.Len() != 0 || s.Quarantined() != path+corruptSuffix {
+		t.Fatalf("want an empty spool and the file quarantined, got %d entries, %q", s.Len(), s.Quarantined())
+	}
+	if _, err = os.Stat(path + corruptSuffix); err != nil {
+		t.Fatalf("quarantined file: %v", err)
+	}
+	if err = s.Put(Entry{Key: "b", Status: 1}); err != nil {
+		t.Fatalf("put after quarantine: %v", err)
+	}
+	if re, _ := Open(dir, 0, 0); re.Len() != 1 || re.Quarantined() != "" {
+		t.Fatalf("want the new spool file readable, got %d entries", re.Len())
+	}
+}
@@ -0,0 +1,90 @@
+// Package tracing sets up OpenTelemetry tracing of scan cycles and ADCM
+// posts, exported over OTLP/HTTP.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strconv"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+
+	"github.com/arenadata/ad-status-sender/internal/config"
+)
+
+const (
+	// Name is the instrumentation scope and default service name.
+	Name = "ad-status-sender"
+
+	defaultPath = "/v1/traces"
+)
+
+// Provider hands out tracers and flushes pending spans on Shutdown.
+type Provider interface {
+	trace.TracerProvider
+	Shutdown(ctx context.Context) error
+}
+
+type noopProvider struct{ noop.TracerProvider }
+
+func (noopProvider) Shutdown(context.Context) error { return nil }
+
+// Noop returns a provider whose spans are never recorded.
+func Noop() Provider { return noopProvider{} }
+
+// New builds a provider exporting to c.Endpoint, or Noop when it is empty.
+// An endpoint without a path gets the standard /v1/traces.
+func New(ctx context.Context, c config.Tracing, hostID int) (Provider, error) {
+	if c.Endpoint == "" {
+		return Noop(), nil
+	}
+	u, err := url.Parse(c.Endpoint)
+	if err != nil || u.Host == "" {
+		return nil, fmt.Errorf("tracing endpoint %q: want http(s)://host:port[/path]", c.Endpoint)
+	}
+	path := u.Path
+	if path == "" || path == "/" {
+		path = defaultPath
+	}
+	opts := []otlptracehttp.Option{otlptracehttp.WithEndpoint(u.Host), otlptracehttp.WithURLPath(path)}
+	if u.Scheme != "https" {
+		opts = append(opts, otlptracehttp.WithInsecure())
+	}
+	if len(c.Headers) > 0 {
+		opts = append(opts, otlptracehttp.WithHeaders(c.Headers))
+	}
+	exp, err := otlptracehttp.New(ctx, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("tracing exporter: %w", err)
+	}
+	service := c.ServiceName
+	if service == "" {
+		service = Name
+	}
+	res := resource.NewSchemaless(
+		attribute.String("service.name", service),
+		attribute.String("adcm.host_id", strconv.Itoa(hostID)),
+	)
+	ratio := c.SampleRatio
+	if ratio <= 0 || ratio > 1 {
+		ratio = 1
+	}
+	return sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exp),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(ratio))),
+	), nil
+}
+
+// Inject writes the W3C traceparent of the span in ctx to an outgoing
+// request's header. Nothing is written without a sampled span.
+func Inject(ctx context.Context, header propagation.HeaderCarrier) {
+	propagation.TraceContext{}.Inject(ctx, header)
+}
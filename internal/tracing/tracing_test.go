@@ -0,0 +1,52 @@
+package tracing
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/arenadata/ad-status-sender/internal/config"
+)
+
+func TestNew_ExportsOverOTLPHTTP(t *testing.T) {
+	var got atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/v1/traces" && r.Header.Get("X-Scope-OrgID") == "ops" {
+			got.Add(1)
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	tp, err := New(context.Background(), config.Tracing{
+		Endpoint: srv.URL,
+		Headers:  map[string]string{"X-Scope-OrgID": "ops"},
+	}, 7)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, span := tp.Tracer(Name).Start(context.Background(), "scan")
+	span.End()
+	if err = tp.Shutdown(t.Context()); err != nil {
+		t.Fatal(err)
+	}
+	if got.Load() == 0 {
+		t.Fatalf("no spans exported to %s", srv.URL)
+	}
+}
+
+func TestNew_DisabledAndInvalid(t *testing.T) {
+	tp, err := New(context.Background(), config.Tracing{}, 7)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, span := tp.Tracer(Name).Start(context.Background(), "scan")
+	if span.SpanContext().IsValid() {
+		t.Fatalf("no-op provider produced a real span")
+	}
+	if _, err = New(context.Background(), config.Tracing{Endpoint: "collector:4318"}, 7); err == nil {
+		t.Fatalf("endpoint without scheme accepted")
+	}
+}